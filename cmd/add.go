@@ -1,9 +1,16 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // addCmd represents the dmsctl add command
@@ -15,7 +22,18 @@ Example:
 	# Add the debug sidecar to a Deployments pods
 	dmsctl add deployment my-deployment
 	# Add the debug sidecar to a DaemonSets pods
-	dmsctl add daemonset my-daemonset`,
+	dmsctl add daemonset my-daemonset
+	# Pick the namespace, kind, workload and container from lists
+	dmsctl add -i
+	# Pick the workload and add the debug sidecar for 4 hours, waiting for the rollout
+	dmsctl add -i --ttl 4h --wait`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !interactive {
+			return cmd.Help()
+		}
+		return addPicked(cmd, dmscmd.Target{Container: containername})
+	},
 }
 
 // addDeploymentCmd represents the dmsctl add deployment command
@@ -26,11 +44,30 @@ var addDeploymentCmd = &cobra.Command{
 After you have added the sidecar, you can port forward to one of the pods with dmsctl port-forward [podname].
 Example:
 	# Add the debug sidecar to a Deployments pods
-	dmsctl add deployment my-deployment`,
-	Args:              cobra.ExactArgs(1),
-	ValidArgsFunction: utils.AutoCompleteDeployments,
-	Run: func(cmd *cobra.Command, args []string) {
-		dmscmd.AddToDeployment(cmd.Context(), kubeconfig, namespace, args[0], containername, debugimage)
+	dmsctl add deployment my-deployment
+	# Add the debug sidecar and let prometheus scrape its metrics
+	dmsctl add deployment my-deployment --prometheus-annotations
+	# Add the debug sidecar to a Deployment in a namespace enforcing the restricted Pod Security Standard
+	dmsctl add deployment my-deployment --security-profile restricted
+	# Add the debug sidecar for 4 hours, after which dmsctl reap removes it
+	dmsctl add deployment my-deployment --ttl 4h
+	# Add the debug sidecar and record why on the Deployment and in its events
+	dmsctl add deployment my-deployment --reason "INC-1234 memory leak"
+	# Show the changes to the pod template without adding the sidecar
+	dmsctl add deployment my-deployment --dry-run=client
+	# Add the debug sidecar and wait for the rollout, removing the sidecar again if it fails
+	dmsctl add deployment my-deployment --wait --timeout 5m
+	# Add the debug sidecar and read the token from the result in a script
+	dmsctl add deployment my-deployment -o json | jq -r .token
+	# Pick the namespace, deployment and container from lists
+	dmsctl add deployment -i`,
+	Args:              argsOrInteractive(1),
+	ValidArgsFunction: utils.AutoCompleteDeployments(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if interactive {
+			return addPicked(cmd, dmscmd.Target{Kind: "deployment", Name: firstArg(args), Container: containername})
+		}
+		return addToWorkload(cmd, "deployment", args[0])
 	},
 }
 
@@ -42,28 +79,119 @@ var addDaemonSetCmd = &cobra.Command{
 After you have added the sidecar, you can port forward to one of the pods with dmsctl port-forward [podname].
 Example:
 	# Add the debug sidecar to a Deployments pods
-	dmsctl add daemonset my-daemonset`,
-	Args:              cobra.ExactArgs(1),
-	ValidArgsFunction: utils.AutoCompleteDaemonSets,
-	Run: func(cmd *cobra.Command, args []string) {
-		dmscmd.AddToDaemonset(cmd.Context(), kubeconfig, namespace, args[0], containername, debugimage)
+	dmsctl add daemonset my-daemonset
+	# Add the debug sidecar and wait for the rollout, keeping the sidecar if it fails
+	dmsctl add daemonset my-daemonset --wait --no-rollback`,
+	Args:              argsOrInteractive(1),
+	ValidArgsFunction: utils.AutoCompleteDaemonSets(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if interactive {
+			return addPicked(cmd, dmscmd.Target{Kind: "daemonset", Name: firstArg(args), Container: containername})
+		}
+		return addToWorkload(cmd, "daemonset", args[0])
 	},
 }
 
 var (
-	containername     string
-	debugimage        string
-	defaultDebugImage string = "mcr.microsoft.com/dotnet/monitor:6.0"
+	containername      string
+	debugimage         string
+	defaultDebugImage  string = "mcr.microsoft.com/dotnet/monitor:8"
+	metrics            bool
+	metricsAnnotations bool
+	podMonitor         bool
+	monitorVersion     int
+	securityProfile    string
+	shareIdentity      bool
+	ttl                time.Duration
+	waitOptions        dmscmd.WaitOptions
 )
 
+// sidecarOptions returns the sidecar options set by the add flags for the workload
+func sidecarOptions(workload string) resources.SidecarOptions {
+	opts := resources.SidecarOptions{
+		Metrics:             metrics || metricsAnnotations || podMonitor,
+		MetricsAnnotations:  metricsAnnotations,
+		MonitorVersion:      monitorVersion,
+		SecurityProfile:     securityProfile,
+		SkipIdentitySharing: !shareIdentity,
+		DryRun:              dryRunStrategy,
+	}
+	audit := auditInfo()
+	opts.Audit = &audit
+	if podMonitor {
+		opts.PodMonitorName = resources.PodMonitorName(workload)
+	}
+	if ttl > 0 {
+		expiresAt := metav1.NewTime(time.Now().Add(ttl).Truncate(time.Second))
+		opts.ExpiresAt = &expiresAt
+	}
+	return opts
+}
+
+func addSecurityProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&securityProfile, "security-profile", resources.SecurityProfileLegacy, "Security profile of the debug sidecar: restricted, baseline or legacy. Only legacy adds SYS_PTRACE")
+	cmd.Flags().BoolVar(&shareIdentity, "share-identity", true, "Run the debug sidecar with the runAsUser and runAsGroup of the container to debug")
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		return resources.ValidateSecurityProfile(securityProfile)
+	}
+}
+
+func addMetricsFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&metrics, "metrics", false, "Expose the dotnet-monitor prometheus metrics endpoint on port 52325")
+	cmd.Flags().BoolVar(&metricsAnnotations, "prometheus-annotations", false, "Add prometheus.io scrape annotations to the pods, implies --metrics")
+	cmd.Flags().BoolVar(&podMonitor, "pod-monitor", false, "Create a prometheus-operator PodMonitor for the metrics endpoint, implies --metrics")
+}
+
+func addTTLFlag(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&ttl, "ttl", 0, "Time to live of the debug sidecar, like 4h. Expired sidecars are removed by dmsctl reap")
+}
+
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&waitOptions.Wait, "wait", false, "Wait for the rollout of the debug sidecar, removing it again if the rollout fails or times out")
+	cmd.Flags().DurationVar(&waitOptions.Timeout, "timeout", 5*time.Minute, "Time to wait for the rollout with --wait, zero waits until it is done or fails")
+	cmd.Flags().BoolVar(&waitOptions.NoRollback, "no-rollback", false, "Keep the debug sidecar when the rollout fails or times out with --wait")
+}
+
+// addSidecarFlags registers the flags of the debug sidecar on the add commands, on add itself for the workloads picked with --interactive
+func addSidecarFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&containername, "container", "c", "", "Supply container name if deployment contains multiple pods")
+	cmd.Flags().StringVar(&debugimage, "debugimage", defaultDebugImage, "image to add as a debug sidecar")
+	cmd.Flags().IntVar(&monitorVersion, "monitor-version", 0, "dotnet-monitor major version of the debug image. Parsed from the debugimage tag if not set")
+	addMetricsFlags(cmd)
+	addSecurityProfileFlag(cmd)
+	addTTLFlag(cmd)
+	addReasonFlag(cmd)
+	addDryRunFlag(cmd, podTemplateDryRunUsage)
+	addWaitFlags(cmd)
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("debugimage", utils.AutoCompleteDebugImages))
+}
+
+// addToWorkload adds the debug sidecar to the deployment or daemonset with the add flags
+func addToWorkload(cmd *cobra.Command, kind, name string) error {
+	var err error
+	if kind == "daemonset" {
+		err = dmscmd.AddToDaemonset(cmd.Context(), factory, name, containername, debugimage, sidecarOptions(name), waitOptions, output)
+	} else {
+		err = dmscmd.AddToDeployment(cmd.Context(), factory, name, containername, debugimage, sidecarOptions(name), waitOptions, output)
+	}
+	if errors.Is(err, dmserrors.ErrMultipleContainers) {
+		return fmt.Errorf("%w, use --container or --interactive", err)
+	}
+	return err
+}
+
 func init() {
 	rootCmd.AddCommand(addCmd)
+	addSidecarFlags(addCmd)
+	addInteractiveFlag(addCmd, "namespace, kind, workload and container")
 
 	addCmd.AddCommand(addDeploymentCmd)
-	addDeploymentCmd.Flags().StringVarP(&containername, "container", "c", "", "Supply container name if deployment contains multiple pods")
-	addDeploymentCmd.Flags().StringVar(&debugimage, "debugimage", defaultDebugImage, "image to add as a debug sidecar")
+	addSidecarFlags(addDeploymentCmd)
+	addInteractiveFlag(addDeploymentCmd, "namespace, deployment and container")
+	cobra.CheckErr(addDeploymentCmd.RegisterFlagCompletionFunc("container", utils.AutoCompleteContainers(factory, "deployment")))
 
 	addCmd.AddCommand(addDaemonSetCmd)
-	addDaemonSetCmd.Flags().StringVarP(&containername, "container", "c", "", "Supply container name if deployment contains multiple pods")
-	addDaemonSetCmd.Flags().StringVar(&debugimage, "debugimage", defaultDebugImage, "image to add as a debug sidecar")
+	addSidecarFlags(addDaemonSetCmd)
+	addInteractiveFlag(addDaemonSetCmd, "namespace, daemonset and container")
+	cobra.CheckErr(addDaemonSetCmd.RegisterFlagCompletionFunc("container", utils.AutoCompleteContainers(factory, "daemonset")))
 }
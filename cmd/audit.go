@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// requireReasonKey is the config key making --reason required, set as require-reason in the config file or REQUIRE_REASON in the environment
+const requireReasonKey = "require-reason"
+
+var reason string
+
+// auditInfo returns the audit info of the change made by this invocation. The user is resolved against the cluster later
+func auditInfo() resources.AuditInfo {
+	return resources.AuditInfo{
+		Time:       metav1.Now(),
+		CLIVersion: versionString,
+		Reason:     reason,
+	}
+}
+
+func addReasonFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&reason, "reason", "", "Why the change is made. Recorded on the workload and in its events. Required if require-reason is set in the config")
+	preRunE := cmd.PreRunE
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if reason == "" && viper.GetBool(requireReasonKey) {
+			return fmt.Errorf("--reason is required by policy")
+		}
+		if preRunE != nil {
+			return preRunE(cmd, args)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// authCmd represents the dmsctl auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect your permissions to debug workloads",
+	Long: `Inspect if you are allowed to add and remove the debug sidecar of workloads.
+Example:
+	# Check if you are allowed to add the debug sidecar to a Deployment
+	dmsctl auth can-i my-deployment`,
+}
+
+// authCanICmd represents the dmsctl auth can-i command
+var authCanICmd = &cobra.Command{
+	Use:   "can-i [kind/]name",
+	Short: "Check if you are allowed to add and remove the debug sidecar of a workload",
+	Long: `Check if you are allowed to add and remove the debug sidecar of a workload and port-forward to its pods, with a SelfSubjectAccessReview for each permission.
+These are the permissions add checks before changing anything with the default options, --pod-monitor and --wait need more.
+The workload kind defaults to deployment.
+Exits with code 4 if any permission is missing.
+Example:
+	# Check the permissions for a Deployment
+	dmsctl auth can-i my-deployment
+	# Check the permissions for a DaemonSet in another namespace
+	dmsctl auth can-i daemonset/my-daemonset -n monitoring`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: utils.AutoCompleteDeployments(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.CanI(cmd.Context(), factory, args[0], output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authCanICmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var doctorToken string
+
+// doctorCmd represents the dmsctl doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [pod|kind/name]",
+	Short: "Find out why the debug sidecar of a pod or workload does not work",
+	Long: `Check the debug sidecar of a pod, or of a running pod of a deployment or daemonset, and print what to do about the problems found.
+It checks that the debug container is not crash-looping, that it shares /tmp and the user of the container to debug, that its secret exists,
+and that dotnet-monitor finds the .NET processes through a port-forward.
+Example:
+	# Check the debug sidecar of a pod
+	dmsctl doctor my-pod
+	# Check the debug sidecar of a pod of a deployment, using the token to list the .NET processes
+	dmsctl doctor deployment/my-deployment --token $TOKEN`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: utils.AutoCompletePodsWithDebugContainer(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.Doctor(cmd.Context(), factory, args[0], doctorToken, output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().StringVar(&doctorToken, "token", "", "Bearer token printed when the debug sidecar was added, to list the .NET processes")
+}
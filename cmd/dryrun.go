@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/spf13/cobra"
+)
+
+var dryRunStrategy string
+
+// podTemplateDryRunUsage is the usage of --dry-run for the commands changing the pod template
+const podTemplateDryRunUsage = `With client only print the diff of the pod template, with server also send the change to the api server without persisting it. No secret is created`
+
+// addDryRunFlag adds the --dry-run strategy flag to the command, the usage describing what each strategy does for it
+func addDryRunFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVar(&dryRunStrategy, "dry-run", resources.DryRunNone, `Must be "none", "client", or "server". `+usage)
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = resources.DryRunClient
+	preRunE := cmd.PreRunE
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		err := resources.ValidateDryRun(dryRunStrategy)
+		if err != nil {
+			return err
+		}
+		if preRunE != nil {
+			return preRunE(cmd, args)
+		}
+		return nil
+	}
+}
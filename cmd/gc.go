@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/spf13/cobra"
+)
+
+// gcCmd represents the dmsctl gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete orphaned debug sidecar secrets and collection rules configmaps",
+	Long: `Delete the api key secrets and collection rules configmaps created by dmsctl whose workload no longer exists or no longer references them.
+They are left behind if adding the sidecar fails halfway or the workload is deleted.
+Those created by newer versions of dmsctl are owned by their workload and deleted by kubernetes together with it.
+Example:
+	# List orphaned secrets and configmaps in the current namespace without deleting them
+	dmsctl gc --dry-run
+	# Delete orphaned secrets and configmaps in all namespaces
+	dmsctl gc -A`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.CollectGarbage(cmd.Context(), factory, allNamespaces, dryRunStrategy)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+	gcCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Collect orphaned resources across all namespaces")
+	addDryRunFlag(gcCmd, "With client only list the orphaned resources, with server also send the deletes to the api server without persisting them")
+}
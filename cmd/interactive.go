@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var interactive bool
+
+func addInteractiveFlag(cmd *cobra.Command, what string) {
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, fmt.Sprintf("Pick the %s not supplied from lists with search in the terminal. Skipped when not running in a terminal", what))
+}
+
+// argsOrInteractive accepts n arguments, or less with --interactive where the missing ones are picked
+func argsOrInteractive(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if interactive {
+			return cobra.MaximumNArgs(n)(cmd, args)
+		}
+		return cobra.ExactArgs(n)(cmd, args)
+	}
+}
+
+// terminalPicker returns the picker of --interactive, or nil if it is not set or dmsctl is not running in a terminal.
+// missing is what has to be supplied without the picker, returned as an error if the picker is skipped
+func terminalPicker(missing string) (*utils.Picker, error) {
+	if !interactive {
+		return nil, nil
+	}
+	p, err := utils.NewTerminalPicker()
+	if err != nil {
+		if missing != "" {
+			return nil, fmt.Errorf("%s is required when %v", missing, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: skipping the interactive pickers, %v\n", err)
+		return nil, nil
+	}
+	return p, nil
+}
+
+// addPicked picks what is not supplied of the workload and container to debug, and adds the debug sidecar to the workload
+func addPicked(cmd *cobra.Command, target dmscmd.Target) error {
+	missing := ""
+	if target.Name == "" {
+		missing = "the name of the workload"
+	}
+	p, err := terminalPicker(missing)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		target, err = dmscmd.PickWorkload(cmd.Context(), factory, p, target)
+		if err != nil {
+			return err
+		}
+		*configFlags.Namespace = target.Namespace
+		containername = target.Container
+		printEquivalent("add", target)
+	}
+	return addToWorkload(cmd, target.Kind, target.Name)
+}
+
+// portForwardPicked picks the namespace and pod with the debug sidecar if not supplied, and forwards the port to it
+func portForwardPicked(cmd *cobra.Command, target dmscmd.Target) error {
+	missing := ""
+	if target.Name == "" {
+		missing = "the name of the pod"
+	}
+	p, err := terminalPicker(missing)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		target, err = dmscmd.PickPod(cmd.Context(), factory, p, target)
+		if err != nil {
+			return err
+		}
+		*configFlags.Namespace = target.Namespace
+		printEquivalent("port-forward", target)
+	}
+	return dmscmd.ForwardPort(cmd.Context(), factory, target.Name, output)
+}
+
+// firstArg returns the first argument, or empty if none are supplied
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// printEquivalent prints the command without --interactive doing the same as the one with the picks, to learn the names
+func printEquivalent(command string, target dmscmd.Target) {
+	args := []string{"dmsctl", command}
+	if target.Kind != "" {
+		args = append(args, target.Kind)
+	}
+	args = append(args, target.Name, "-n", target.Namespace)
+	if target.Container != "" {
+		args = append(args, "-c", target.Container)
+	}
+	fmt.Fprintf(os.Stderr, "Running %s\n", strings.Join(args, " "))
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the dmsctl list command
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"status"},
+	Short:   "List workloads with the debug sidecar attached",
+	Long: `List the Deployments and DaemonSets with the debug sidecar attached, to find the ones left behind after debugging.
+Example:
+	# List workloads with the debug sidecar in the current namespace
+	dmsctl list
+	# List workloads with the debug sidecar in all namespaces, including image and secret
+	dmsctl list -A -o wide
+	# List workloads with the debug sidecar as json
+	dmsctl list -o json`,
+	Args: cobra.NoArgs,
+	// The global output flag also supports wide for list
+	Annotations: map[string]string{outputWideAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.ListDebugWorkloads(cmd.Context(), factory, allNamespaces, output)
+	},
+}
+
+var allNamespaces bool
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+	listCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "List workloads across all namespaces")
+}
@@ -14,14 +14,22 @@ var portforwardCmd = &cobra.Command{
 This command will forward port 52323 from your local machine to port 52323 in a pod.
 Example:
 	# Forward port 52323 from your local machine to port 52323 in the pod my-pod
-	dmsctl port-forward my-pod`,
-	Args:              cobra.ExactArgs(1),
-	ValidArgsFunction: utils.AutoCompletePodsWithDebugContainer,
-	Run: func(cmd *cobra.Command, args []string) {
-		dmscmd.ForwardPort(cmd.Context(), kubeconfig, namespace, args[0])
+	dmsctl port-forward my-pod
+	# Forward the port and print the local url of the dotnet-monitor API as json once forwarding
+	dmsctl port-forward my-pod -o json
+	# Pick the namespace and pod from lists
+	dmsctl port-forward -i`,
+	Args:              argsOrInteractive(1),
+	ValidArgsFunction: utils.AutoCompletePodsWithDebugContainer(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if interactive {
+			return portForwardPicked(cmd, dmscmd.Target{Name: firstArg(args)})
+		}
+		return dmscmd.ForwardPort(cmd.Context(), factory, args[0], output)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(portforwardCmd)
+	addInteractiveFlag(portforwardCmd, "namespace and pod")
 }
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"time"
+
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/spf13/cobra"
+)
+
+// reapCmd represents the dmsctl reap command
+var reapCmd = &cobra.Command{
+	Use:   "reap",
+	Short: "Remove expired debug sidecars",
+	Long: `Remove the debug sidecars added with --ttl whose time to live has passed, together with their secrets.
+Without a kubeconfig the in-cluster config is used, so reap can run as a CronJob or a Deployment in the cluster.
+Example:
+	# Remove expired sidecars in the current namespace
+	dmsctl reap
+	# Remove expired sidecars in all namespaces every 5 minutes until stopped
+	dmsctl reap -A --watch --interval 5m`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.Reap(cmd.Context(), factory, allNamespaces, watchReap, reapInterval, auditInfo())
+	},
+}
+
+var (
+	watchReap    bool
+	reapInterval time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(reapCmd)
+	reapCmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Remove expired sidecars across all namespaces")
+	reapCmd.Flags().BoolVarP(&watchReap, "watch", "w", false, "Keep removing expired sidecars every interval until stopped")
+	reapCmd.Flags().DurationVar(&reapInterval, "interval", time.Minute, "How often to look for expired sidecars with --watch")
+}
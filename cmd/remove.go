@@ -2,6 +2,7 @@ package cmd
 
 import (
 	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -25,11 +26,13 @@ var removeDeploymentCmd = &cobra.Command{
 	Long: `After you are done debugging, you can remove the debug sidecar from your pods.
 Example:
 	# Remove the debug sidecar from a Deployments pods
-	dmsctl remove deployment my-deployment`,
+	dmsctl remove deployment my-deployment
+	# Restore the pod template exactly as it was before the sidecar was added
+	dmsctl remove deployment my-deployment --restore-exact`,
 	Args:              cobra.ExactArgs(1),
-	ValidArgsFunction: utils.AutoCompleteDeployments,
-	Run: func(cmd *cobra.Command, args []string) {
-		dmscmd.RemoveFromDeployment(cmd.Context(), kubeconfig, namespace, args[0])
+	ValidArgsFunction: utils.AutoCompleteDeployments(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.RemoveFromDeployment(cmd.Context(), factory, args[0], auditInfo(), removeOptions(), output)
 	},
 }
 
@@ -42,16 +45,36 @@ Example:
 	# Remove the debug sidecar from a DaemonSets pods
 	dmsctl remove daemonset my-daemonset`,
 	Args:              cobra.ExactArgs(1),
-	ValidArgsFunction: utils.AutoCompleteDaemonSets,
-	Run: func(cmd *cobra.Command, args []string) {
-		dmscmd.RemoveFromDaemonset(cmd.Context(), kubeconfig, namespace, args[0])
+	ValidArgsFunction: utils.AutoCompleteDaemonSets(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.RemoveFromDaemonset(cmd.Context(), factory, args[0], auditInfo(), removeOptions(), output)
 	},
 }
 
+var restoreExact bool
+
+// removeOptions returns the remove options set by the remove flags
+func removeOptions() resources.RemoveOptions {
+	return resources.RemoveOptions{
+		RestoreExact: restoreExact,
+		DryRun:       dryRunStrategy,
+	}
+}
+
+func addRestoreExactFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&restoreExact, "restore-exact", false, "Restore the pod template snapshot taken when the sidecar was added. Falls back to removing the sidecar and prints the difference if the pod template changed since")
+}
+
 func init() {
 	rootCmd.AddCommand(removeCmd)
 
 	removeCmd.AddCommand(removeDeploymentCmd)
+	addReasonFlag(removeDeploymentCmd)
+	addRestoreExactFlag(removeDeploymentCmd)
+	addDryRunFlag(removeDeploymentCmd, podTemplateDryRunUsage)
 
 	removeCmd.AddCommand(removeDaemonSetCmd)
+	addReasonFlag(removeDaemonSetCmd)
+	addRestoreExactFlag(removeDaemonSetCmd)
+	addDryRunFlag(removeDaemonSetCmd, podTemplateDryRunUsage)
 }
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/spf13/cobra"
+)
+
+// renderCmd represents the dmsctl render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render the manifests adding a debug sidecar, for GitOps",
+	Long: `When the cluster is managed by GitOps, changes made with dmsctl add are reverted.
+Render adds the debug sidecar to a local workload manifest instead, and prints the manifests to commit.
+Example:
+	# Print the Deployment in deploy.yaml with the debug sidecar added, and the Secret it mounts
+	dmsctl render deployment my-deployment -f deploy.yaml
+	# Print a strategic merge patch adding the debug sidecar to a DaemonSet
+	dmsctl render daemonset my-daemonset -f daemonset.yaml -o patch`,
+}
+
+// renderDeploymentCmd represents the dmsctl render deployment command
+var renderDeploymentCmd = &cobra.Command{
+	Use:   "deployment [name]",
+	Short: "Render the manifests adding the debug sidecar to a deployment",
+	Long: `Add the debug sidecar to a Deployment in a local manifest file without changing the cluster, and print the manifests to commit.
+The Secret with the public key of the printed token must be committed with the Deployment.
+Output formats:
+	yaml       the Deployment with the debug sidecar added, followed by the Secret
+	patch      a strategic merge patch adding the debug sidecar, followed by the Secret
+	kustomize  a kustomize component with a JSON6902 patch and the Secret, written to --output-dir
+Example:
+	# Print the Deployment in deploy.yaml with the debug sidecar added, and the Secret it mounts
+	dmsctl render deployment my-deployment -f deploy.yaml
+	# Print a strategic merge patch and a Secret in plain text, and encrypt it with sops
+	dmsctl render deployment my-deployment -f deploy.yaml -o patch --plaintext-secret > debug.yaml
+	sops --encrypt --encrypted-regex '^(data|stringData)$' --in-place debug.yaml
+	# Write a kustomize component to overlays/debug, and add it to the components of the kustomization
+	dmsctl render deployment my-deployment -f base/deploy.yaml -o kustomize --output-dir overlays/debug`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.RenderSidecar(factory, "Deployment", args[0], containername, debugimage, sidecarOptions(args[0]), renderOptions)
+	},
+}
+
+// renderDaemonSetCmd represents the dmsctl render daemonset command
+var renderDaemonSetCmd = &cobra.Command{
+	Use:   "daemonset [name]",
+	Short: "Render the manifests adding the debug sidecar to a daemonset",
+	Long: `Add the debug sidecar to a DaemonSet in a local manifest file without changing the cluster, and print the manifests to commit.
+The Secret with the public key of the printed token must be committed with the DaemonSet.
+Example:
+	# Print the DaemonSet in daemonset.yaml with the debug sidecar added, and the Secret it mounts
+	dmsctl render daemonset my-daemonset -f daemonset.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.RenderSidecar(factory, "DaemonSet", args[0], containername, debugimage, sidecarOptions(args[0]), renderOptions)
+	},
+}
+
+var renderOptions dmscmd.RenderOptions
+
+func addRenderFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&renderOptions.Filename, "filename", "f", "", "File containing the workload manifest, - reads from stdin")
+	cobra.CheckErr(cmd.MarkFlagRequired("filename"))
+	cmd.Flags().StringVarP(&renderOptions.Format, "output", "o", resources.RenderYAML, "Output format. One of: yaml, patch, kustomize")
+	cmd.Flags().StringVar(&renderOptions.OutputDir, "output-dir", "dmsctl-debug", "Directory the kustomize component is written to")
+	cmd.Flags().BoolVar(&renderOptions.PlaintextSecret, "plaintext-secret", false, "Write the Secret with stringData in plain text, ready to be encrypted with sops")
+	cmd.Flags().StringVarP(&containername, "container", "c", "", "Supply container name if the workload contains multiple containers")
+	cmd.Flags().StringVar(&debugimage, "debugimage", defaultDebugImage, "image to add as a debug sidecar")
+	cmd.Flags().IntVar(&monitorVersion, "monitor-version", 0, "dotnet-monitor major version of the debug image. Parsed from the debugimage tag if not set")
+	addMetricsFlags(cmd)
+	addSecurityProfileFlag(cmd)
+	addTTLFlag(cmd)
+	addReasonFlag(cmd)
+	preRunE := cmd.PreRunE
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		err := resources.ValidateRenderFormat(renderOptions.Format)
+		if err != nil {
+			return err
+		}
+		return preRunE(cmd, args)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+	renderCmd.AddCommand(renderDeploymentCmd)
+	addRenderFlags(renderDeploymentCmd)
+	renderCmd.AddCommand(renderDaemonSetCmd)
+	addRenderFlags(renderDaemonSetCmd)
+}
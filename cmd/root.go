@@ -2,21 +2,37 @@ package cmd
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/klog/v2"
 )
 
 var (
-	cfgFile    string
-	namespace  string
-	kubeconfig string
+	cfgFile string
+	output  string
+	// configFlags are the kubectl compatible global flags, like --kubeconfig, --context, --namespace and --as
+	configFlags = genericclioptions.NewConfigFlags(true)
+	// factory creates the clients of all commands and the autocompletion from configFlags
+	factory = dmskube.NewFactory(configFlags)
 )
 
+// outputWideAnnotation marks the commands supporting the wide output format
+const outputWideAnnotation = "dmsctl/output-wide"
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "dmsctl",
@@ -32,18 +48,45 @@ Examples:
 	# Setup a port forward from your machine to the dotnet-monitor sidecar container in the pod
 	dmsctl port-forward my-pod-13fa7
 
+	# Apply collection rules to the dotnet-monitor sidecars of a deployment
+	dmsctl rules apply my-deployment -f rules.yaml
+
 	# Remove sidecars from the pods assosiated with a deployment in kubernetes
 	dmsctl remove deployment my-deployment
 
 	# Remove sidecars from the pods assosiated with a daemonset in kubernetes
 	dmsctl remove daemonset my-daemonset 
+
+	# Show the requests made to the api server, like kubectl
+	dmsctl list -v=6
+
+Exit codes:
+	0  the command succeeded
+	1  the command failed, like on invalid flags or arguments
+	2  the workload, pod, container, secret or debug sidecar was not found
+	3  the debug sidecar is already added to the workload
+	4  the api server rejected the credentials or forbade the request, or permissions checked before it are missing
+	5  the command timed out, like waiting for a rollout with --wait
 `,
+	// Failures are printed to stderr by cobra, without the usage which hides the error
+	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if output == "wide" && cmd.Annotations[outputWideAnnotation] == "true" {
+			return nil
+		}
+		return dmscmd.ValidateOutput(output)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// It exits with the exit code of the error the command failed with, see errors.ExitCode
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	err := rootCmd.Execute()
+	klog.Flush()
+	if err != nil {
+		os.Exit(dmserrors.ExitCode(err))
+	}
 }
 
 func init() {
@@ -54,10 +97,28 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.dmsconfig.yaml)")
-	rootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request. Otherwise, the current namespace is used.")
-	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Override path to the kubeconfig file to use for CLI requests.")
+	configFlags.AddFlags(rootCmd.PersistentFlags())
+	cobra.CheckErr(rootCmd.RegisterFlagCompletionFunc("namespace", utils.AutoCompleteNamespaces(factory)))
+	cobra.CheckErr(rootCmd.RegisterFlagCompletionFunc("context", utils.AutoCompleteContexts(factory)))
+	addLogFlags(rootCmd.PersistentFlags())
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", dmscmd.OutputText, "Output format of add, remove, list, port-forward, auth can-i and doctor. One of: json, yaml. list also supports wide. Text by default")
+	rootCmd.PersistentFlags().String(keyPrefixKey, resources.LegacyKeyPrefix, "Domain prefix of the annotations and labels added by dmsctl, like monitor.altinn.no/. Sidecars added with the legacy prefix are still detected. Can also be set as key-prefix in the config file")
+	cobra.CheckErr(viper.BindPFlag(keyPrefixKey, rootCmd.PersistentFlags().Lookup(keyPrefixKey)))
+}
+
+// addLogFlags adds the -v and --vmodule flags of klog, logging the http requests to the api server with -v=6 like kubectl
+func addLogFlags(flags *pflag.FlagSet) {
+	klogFlags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(klogFlags)
+	v := klogFlags.Lookup("v")
+	v.Usage = "Log level verbosity. 4 logs the resources changed, 5 the patches and port-forward connections and 6 the http requests"
+	flags.AddGoFlag(v)
+	flags.AddGoFlag(klogFlags.Lookup("vmodule"))
 }
 
+// keyPrefixKey is the config key and flag setting the domain prefix of the annotations and labels added by dmsctl
+const keyPrefixKey = "key-prefix"
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
@@ -73,12 +134,14 @@ func initConfig() {
 		viper.SetConfigName(".dmsconfig")
 	}
 
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+	cobra.CheckErr(resources.SetKeyPrefix(viper.GetString(keyPrefixKey)))
 }
 
 // NewDmsctlCommand returns rootCmd. Used to generate docs
@@ -0,0 +1,560 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// executeCommand runs dmsctl with args against a fake clientset, returning what it printed to stderr and the error it failed with
+func executeCommand(t *testing.T, c *testclient.Clientset, args ...string) (string, error) {
+	_, stderr, err := executeCommandOutput(t, c, args...)
+	return stderr, err
+}
+
+// executeCommandOutput runs dmsctl with args against a fake clientset, returning what it printed to stdout and stderr and the error it failed with
+func executeCommandOutput(t *testing.T, c *testclient.Clientset, args ...string) (string, string, error) {
+	t.Helper()
+	f := factory
+	t.Cleanup(func() {
+		factory = f
+	})
+	factory = &fakeFactory{
+		helper: dmskube.Helper{
+			Client: c,
+			Dynamic: fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				resources.PodMonitorResource: "PodMonitorList",
+			}),
+		},
+	}
+	resetFlags(rootCmd)
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetArgs(args)
+
+	// The commands print their results with fmt, so stdout is replaced with a pipe
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	read := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		read <- string(b)
+	}()
+	err = rootCmd.ExecuteContext(context.Background())
+	os.Stdout = stdout
+	w.Close()
+	return <-read, stderr.String(), err
+}
+
+// fakeFactory is a factory returning a helper with fake clients, in the namespace flag or test
+type fakeFactory struct {
+	helper dmskube.Helper
+}
+
+func (f *fakeFactory) Helper() (dmskube.Helper, error) {
+	return f.helper, nil
+}
+
+func (f *fakeFactory) ToRESTConfig() (*rest.Config, error) {
+	return &rest.Config{}, nil
+}
+
+func (f *fakeFactory) Namespace() (string, bool, error) {
+	if *configFlags.Namespace != "" {
+		return *configFlags.Namespace, true, nil
+	}
+	return "test", false, nil
+}
+
+func (f *fakeFactory) User() (string, error) {
+	return "test-user", nil
+}
+
+func (f *fakeFactory) Contexts() ([]string, error) {
+	return []string{"test"}, nil
+}
+
+// resetFlags sets the flags of a command and its subcommands back to their defaults, as they are shared by all executions
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		_ = f.Value.Set(f.DefValue)
+		f.Changed = false
+	})
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+// newTestClientset returns a fake clientset with the objects, allowing all SelfSubjectAccessReviews
+func newTestClientset(objects ...runtime.Object) *testclient.Clientset {
+	c := testclient.NewSimpleClientset(objects...)
+	c.PrependReactor("create", "selfsubjectaccessreviews", denyAccess(""))
+	return c
+}
+
+// denyAccess returns a reactor denying the SelfSubjectAccessReviews of the verb on the resource, like "create secrets"
+func denyAccess(verbResource string) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = attributes.Verb+" "+attributes.Resource != verbResource
+		return true, review, nil
+	}
+}
+
+func testDeployment(annotations map[string]string, containers ...string) *appsv1.Deployment {
+	replicas := int32(1)
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "test-uid"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}, Annotations: annotations},
+			},
+		},
+	}
+	for _, c := range containers {
+		d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, corev1.Container{Name: c, Image: "test:latest"})
+	}
+	return d
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		objects []runtime.Object
+		// reactor reacts to the reactVerb requests on reactResource, before the fake clientset
+		reactVerb     string
+		reactResource string
+		reactor       clienttesting.ReactionFunc
+		wantCode      int
+		wantError     string
+		check         func(t *testing.T, c *testclient.Clientset)
+	}{
+		{
+			name:     "Adds sidecar to deployment",
+			args:     []string{"add", "deployment", "test"},
+			objects:  []runtime.Object{testDeployment(nil, "app")},
+			wantCode: dmserrors.ExitOK,
+			check: func(t *testing.T, c *testclient.Clientset) {
+				d, _ := c.AppsV1().Deployments("test").Get(context.Background(), "test", metav1.GetOptions{})
+				if !resources.HasDebugSidecar(d.Spec.Template.Annotations) {
+					t.Errorf("deployment has no debug sidecar after add")
+				}
+			},
+		},
+		{
+			name:      "Adding sidecar to missing deployment is not found",
+			args:      []string{"add", "deployment", "missing"},
+			wantCode:  dmserrors.ExitNotFound,
+			wantError: `deployments.apps "missing" not found`,
+		},
+		{
+			name:      "Adding sidecar twice is already present",
+			args:      []string{"add", "deployment", "test"},
+			objects:   []runtime.Object{testDeployment(map[string]string{resources.LegacyKeyPrefix + "dd-added": "true"}, "app")},
+			wantCode:  dmserrors.ExitAlreadyPresent,
+			wantError: "deployment test: debug sidecar already present",
+			check: func(t *testing.T, c *testclient.Clientset) {
+				secrets, _ := c.CoreV1().Secrets("test").List(context.Background(), metav1.ListOptions{})
+				if len(secrets.Items) != 0 {
+					t.Errorf("add left %d secrets after failing", len(secrets.Items))
+				}
+			},
+		},
+		{
+			name:      "Adding sidecar without container to a pod with multiple containers fails",
+			args:      []string{"add", "deployment", "test"},
+			objects:   []runtime.Object{testDeployment(nil, "app", "proxy")},
+			wantCode:  dmserrors.ExitError,
+			wantError: "containers are app, proxy, use --container or --interactive",
+		},
+		{
+			name:      "Interactive add without name fails when not in a terminal",
+			args:      []string{"add", "deployment", "-i"},
+			objects:   []runtime.Object{testDeployment(nil, "app")},
+			wantCode:  dmserrors.ExitError,
+			wantError: "the name of the workload is required when not running in a terminal",
+		},
+		{
+			name:      "Interactive add accepts the sidecar flags",
+			args:      []string{"add", "-i", "--debugimage", "mcr.microsoft.com/dotnet/monitor:9", "--ttl", "4h", "--wait", "--dry-run"},
+			wantCode:  dmserrors.ExitError,
+			wantError: "the name of the workload is required when not running in a terminal",
+		},
+		{
+			name:     "Interactive add skips the pickers when not in a terminal",
+			args:     []string{"add", "deployment", "test", "-i"},
+			objects:  []runtime.Object{testDeployment(nil, "app")},
+			wantCode: dmserrors.ExitOK,
+			check: func(t *testing.T, c *testclient.Clientset) {
+				d, _ := c.AppsV1().Deployments("test").Get(context.Background(), "test", metav1.GetOptions{})
+				if !resources.HasDebugSidecar(d.Spec.Template.Annotations) {
+					t.Errorf("deployment has no debug sidecar after add")
+				}
+			},
+		},
+		{
+			name:      "Interactive port-forward without pod fails when not in a terminal",
+			args:      []string{"port-forward", "-i"},
+			wantCode:  dmserrors.ExitError,
+			wantError: "the name of the pod is required when not running in a terminal",
+		},
+		{
+			name:      "Adding sidecar to missing container is not found",
+			args:      []string{"add", "deployment", "test", "-c", "missing"},
+			objects:   []runtime.Object{testDeployment(nil, "app", "proxy")},
+			wantCode:  dmserrors.ExitNotFound,
+			wantError: "container not found: missing",
+		},
+		{
+			name:      "Rollout timing out rolls back the sidecar",
+			args:      []string{"add", "deployment", "test", "--wait", "--timeout", "100ms"},
+			objects:   []runtime.Object{testDeployment(nil, "app")},
+			wantCode:  dmserrors.ExitTimeout,
+			wantError: "rollout of deployment test failed: timed out",
+			check: func(t *testing.T, c *testclient.Clientset) {
+				d, _ := c.AppsV1().Deployments("test").Get(context.Background(), "test", metav1.GetOptions{})
+				if resources.HasDebugSidecar(d.Spec.Template.Annotations) {
+					t.Errorf("deployment has debug sidecar after rollback")
+				}
+				secrets, _ := c.CoreV1().Secrets("test").List(context.Background(), metav1.ListOptions{})
+				if len(secrets.Items) != 0 {
+					t.Errorf("rollback left %d secrets", len(secrets.Items))
+				}
+			},
+		},
+		{
+			name:      "Removing sidecar not added is not found",
+			args:      []string{"remove", "deployment", "test"},
+			objects:   []runtime.Object{testDeployment(nil, "app")},
+			wantCode:  dmserrors.ExitNotFound,
+			wantError: "deployment test: debug sidecar not present",
+		},
+		{
+			name:          "Removing sidecar without access is forbidden",
+			args:          []string{"remove", "daemonset", "test"},
+			reactVerb:     "get",
+			reactResource: "daemonsets",
+			reactor: func(action clienttesting.Action) (bool, runtime.Object, error) {
+				return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, "test", nil)
+			},
+			wantCode:  dmserrors.ExitForbidden,
+			wantError: "forbidden",
+		},
+		{
+			name:          "Adding sidecar without permission to create secrets is forbidden before changing anything",
+			args:          []string{"add", "deployment", "test"},
+			objects:       []runtime.Object{testDeployment(nil, "app")},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("create secrets"),
+			wantCode:      dmserrors.ExitForbidden,
+			wantError:     "missing permissions to add the debug sidecar to deployment test",
+			check: func(t *testing.T, c *testclient.Clientset) {
+				d, _ := c.AppsV1().Deployments("test").Get(context.Background(), "test", metav1.GetOptions{})
+				if resources.HasDebugSidecar(d.Spec.Template.Annotations) {
+					t.Errorf("deployment has debug sidecar after failing preflight")
+				}
+			},
+		},
+		{
+			name:          "Adding sidecar with PodMonitor without permission to create PodMonitors is forbidden",
+			args:          []string{"add", "deployment", "test", "--pod-monitor"},
+			objects:       []runtime.Object{testDeployment(nil, "app")},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("create podmonitors"),
+			wantCode:      dmserrors.ExitForbidden,
+			wantError:     "missing permissions to add the debug sidecar to deployment test",
+		},
+		{
+			name:          "Adding sidecar without waiting does not need to watch the rollout",
+			args:          []string{"add", "deployment", "test"},
+			objects:       []runtime.Object{testDeployment(nil, "app")},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("watch deployments"),
+			wantCode:      dmserrors.ExitOK,
+		},
+		{
+			name:          "Removing sidecar with collection rules without permission to delete configmaps is forbidden",
+			args:          []string{"remove", "deployment", "test"},
+			objects:       []runtime.Object{testDeployment(map[string]string{"dev.local/dd-added": "true", "dev.local/dd-apply": `{"containerToDebug":"app","debugContainerName":"debug","secretMount":"dd-monitor-apikey-abcde","rulesConfigMap":"test-dd-rules"}`}, "app", "debug")},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("delete configmaps"),
+			wantCode:      dmserrors.ExitForbidden,
+			wantError:     "missing permissions to remove the debug sidecar from deployment test",
+		},
+		{
+			name:          "Adding sidecar with client dry run skips permission check",
+			args:          []string{"add", "deployment", "test", "--dry-run=client"},
+			objects:       []runtime.Object{testDeployment(nil, "app")},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("patch deployments"),
+			wantCode:      dmserrors.ExitOK,
+		},
+		{
+			name: "Collecting garbage with client dry run keeps orphaned configmaps",
+			args: []string{"gc", "--dry-run"},
+			objects: []runtime.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "dd-monitor-rules-abcde", Namespace: "test", Labels: map[string]string{resources.RulesLabel(): "deleted"}},
+			}},
+			wantCode: dmserrors.ExitOK,
+			check: func(t *testing.T, c *testclient.Clientset) {
+				if _, err := c.CoreV1().ConfigMaps("test").Get(context.Background(), "dd-monitor-rules-abcde", metav1.GetOptions{}); err != nil {
+					t.Errorf("gc --dry-run deleted the orphaned configmap: %v", err)
+				}
+			},
+		},
+		{
+			name: "Collecting garbage deletes orphaned configmaps",
+			args: []string{"gc"},
+			objects: []runtime.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "dd-monitor-rules-abcde", Namespace: "test", Labels: map[string]string{resources.RulesLabel(): "deleted"}},
+			}},
+			wantCode: dmserrors.ExitOK,
+			check: func(t *testing.T, c *testclient.Clientset) {
+				if _, err := c.CoreV1().ConfigMaps("test").Get(context.Background(), "dd-monitor-rules-abcde", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+					t.Errorf("gc did not delete the orphaned configmap: %v", err)
+				}
+			},
+		},
+		{
+			name:      "Collecting garbage with invalid dry run strategy fails",
+			args:      []string{"gc", "--dry-run=all"},
+			wantCode:  dmserrors.ExitError,
+			wantError: "invalid dry run strategy all",
+		},
+		{
+			name:          "Removing sidecar without permission to patch is forbidden",
+			args:          []string{"remove", "deployment", "test"},
+			objects:       []runtime.Object{testDeployment(map[string]string{resources.LegacyKeyPrefix + "dd-added": "true"}, "app")},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("patch deployments"),
+			wantCode:      dmserrors.ExitForbidden,
+			wantError:     "missing permissions to remove the debug sidecar from deployment test",
+		},
+		{
+			name:     "Auth can-i with all permissions succeeds",
+			args:     []string{"auth", "can-i", "daemonset/test"},
+			wantCode: dmserrors.ExitOK,
+		},
+		{
+			name:          "Auth can-i without permission to port-forward is forbidden",
+			args:          []string{"auth", "can-i", "test"},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("create pods"),
+			wantCode:      dmserrors.ExitForbidden,
+			wantError:     "missing permissions to add the debug sidecar to deployment test in namespace test",
+		},
+		{
+			name:      "Doctor on workload without debug sidecar is not found",
+			args:      []string{"doctor", "deployment/test"},
+			objects:   []runtime.Object{testDeployment(nil, "app")},
+			wantCode:  dmserrors.ExitNotFound,
+			wantError: "deployment test: debug sidecar not present",
+		},
+		{
+			name:      "Doctor on workload without pods finds problems",
+			args:      []string{"doctor", "deployment/test"},
+			objects:   []runtime.Object{testDeployment(map[string]string{resources.LegacyKeyPrefix + "dd-added": "true"}, "app")},
+			wantCode:  dmserrors.ExitError,
+			wantError: "found 1 problems with the debug sidecar of deployment/test",
+		},
+		{
+			name:      "Port forward to missing pod is not found",
+			args:      []string{"port-forward", "missing"},
+			wantCode:  dmserrors.ExitNotFound,
+			wantError: `pods "missing" not found`,
+		},
+		{
+			name:      "Listing with unknown output format fails",
+			args:      []string{"list", "-o", "table"},
+			wantCode:  dmserrors.ExitError,
+			wantError: "unknown output format table",
+		},
+		{
+			name:      "Issuing token for workload without sidecar is not found",
+			args:      []string{"token", "deployment/test"},
+			objects:   []runtime.Object{testDeployment(nil, "app")},
+			wantCode:  dmserrors.ExitNotFound,
+			wantError: "debug sidecar not present",
+		},
+		{
+			name:          "Issuing token without permission to patch secrets is forbidden",
+			args:          []string{"token", "test"},
+			objects:       []runtime.Object{testDeployment(nil, "app")},
+			reactVerb:     "create",
+			reactResource: "selfsubjectaccessreviews",
+			reactor:       denyAccess("patch secrets"),
+			wantCode:      dmserrors.ExitForbidden,
+		},
+		{
+			name:      "Reaping with an interval of zero fails before reaping",
+			args:      []string{"reap", "--watch", "--interval", "0s"},
+			wantCode:  dmserrors.ExitError,
+			wantError: "invalid --interval 0s, must be greater than zero",
+		},
+		{
+			name:      "Reaping with a negative interval fails before reaping",
+			args:      []string{"reap", "--watch", "--interval=-1m"},
+			wantCode:  dmserrors.ExitError,
+			wantError: "invalid --interval -1m0s, must be greater than zero",
+		},
+		{
+			name:     "Reaping once ignores the interval",
+			args:     []string{"reap", "--interval", "0s"},
+			wantCode: dmserrors.ExitOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClientset(tt.objects...)
+			if tt.reactor != nil {
+				c.PrependReactor(tt.reactVerb, tt.reactResource, tt.reactor)
+			}
+			stderr, err := executeCommand(t, c, tt.args...)
+			if got := dmserrors.ExitCode(err); got != tt.wantCode {
+				t.Errorf("dmsctl %s exit code = %d, want %d, error = %v", strings.Join(tt.args, " "), got, tt.wantCode, err)
+			}
+			if tt.wantError != "" && !strings.Contains(stderr, tt.wantError) {
+				t.Errorf("dmsctl %s stderr = %q, want error containing %q", strings.Join(tt.args, " "), stderr, tt.wantError)
+			}
+			if tt.check != nil {
+				tt.check(t, c)
+			}
+		})
+	}
+}
+
+func TestCommandStructuredOutput_SecretReused(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dd-monitor-apikey-reused",
+			Namespace: "test",
+			Labels:    map[string]string{resources.SecretLabel(): "test"},
+		},
+	}
+	c := newTestClientset(testDeployment(nil, "app"), secret)
+
+	stdout, _, err := executeCommandOutput(t, c, "add", "deployment", "test", "-o", "json")
+	if err != nil {
+		t.Fatalf("dmsctl add error = %v", err)
+	}
+	var added map[string]interface{}
+	err = json.Unmarshal([]byte(stdout), &added)
+	if err != nil {
+		t.Fatalf("dmsctl add -o json printed %q, not json: %v", stdout, err)
+	}
+	if _, ok := added["token"]; ok || added["secretReused"] != true || added["secretName"] != secret.Name {
+		t.Errorf("dmsctl add -o json = %v, want secret %s reused without token", added, secret.Name)
+	}
+
+	c = newTestClientset(testDeployment(nil, "app"), secret)
+	stdout, _, err = executeCommandOutput(t, c, "add", "deployment", "test")
+	if err != nil {
+		t.Fatalf("dmsctl add error = %v", err)
+	}
+	if strings.Contains(stdout, "Bearer") || !strings.Contains(stdout, "dmsctl token deployment/test") {
+		t.Errorf("dmsctl add printed %q, want how to issue a token instead of the auth header", stdout)
+	}
+}
+
+func TestCommandStructuredOutput(t *testing.T) {
+	c := newTestClientset(testDeployment(nil, "app"))
+
+	stdout, _, err := executeCommandOutput(t, c, "add", "deployment", "test", "-o", "json")
+	if err != nil {
+		t.Fatalf("dmsctl add error = %v", err)
+	}
+	var added dmscmd.SidecarResult
+	err = json.Unmarshal([]byte(stdout), &added)
+	if err != nil {
+		t.Fatalf("dmsctl add -o json printed %q, not a SidecarResult: %v", stdout, err)
+	}
+	if added.Kind != "Deployment" || added.Name != "test" || added.UID != "test-uid" || added.Token == "" {
+		t.Errorf("dmsctl add -o json = %+v, want deployment test with uid and token", added)
+	}
+	if added.SecretName == "" || added.SecretName != added.Config.SecretName {
+		t.Errorf("dmsctl add -o json secret name = %q, want the secret of the DDConfig %q", added.SecretName, added.Config.SecretName)
+	}
+
+	stdout, _, err = executeCommandOutput(t, c, "list", "-o", "json")
+	if err != nil {
+		t.Fatalf("dmsctl list error = %v", err)
+	}
+	var listed []dmskube.DebugWorkload
+	err = json.Unmarshal([]byte(stdout), &listed)
+	if err != nil || len(listed) != 1 || listed[0].Config.SecretName != added.SecretName {
+		t.Errorf("dmsctl list -o json = %q, %v, want the deployment the sidecar was added to", stdout, err)
+	}
+
+	stdout, _, err = executeCommandOutput(t, c, "remove", "deployment", "test", "-o", "yaml")
+	if err != nil {
+		t.Fatalf("dmsctl remove error = %v", err)
+	}
+	var removed dmscmd.SidecarResult
+	err = yaml.Unmarshal([]byte(stdout), &removed)
+	if err != nil {
+		t.Fatalf("dmsctl remove -o yaml printed %q, not a SidecarResult: %v", stdout, err)
+	}
+	if removed.SecretName != added.SecretName || removed.Token != "" {
+		t.Errorf("dmsctl remove -o yaml = %+v, want the removed secret %s and no token", removed, added.SecretName)
+	}
+
+	stdout, _, err = executeCommandOutput(t, c, "add", "deployment", "test", "-o", "json")
+	if err != nil {
+		t.Fatalf("dmsctl add error = %v", err)
+	}
+	stdout, _, err = executeCommandOutput(t, c, "token", "deployment/test", "-o", "json")
+	if err != nil {
+		t.Fatalf("dmsctl token error = %v", err)
+	}
+	var issued dmscmd.TokenResult
+	err = json.Unmarshal([]byte(stdout), &issued)
+	if err != nil {
+		t.Fatalf("dmsctl token -o json printed %q, not a TokenResult: %v", stdout, err)
+	}
+	if issued.Kind != "Deployment" || issued.Name != "test" || issued.SecretName == "" || issued.Token == "" {
+		t.Errorf("dmsctl token -o json = %+v, want deployment test with secret and token", issued)
+	}
+
+	_, stderr, err := executeCommandOutput(t, c, "add", "deployment", "test", "-o", "xml")
+	if dmserrors.ExitCode(err) != dmserrors.ExitError || !strings.Contains(stderr, "unknown output format xml") {
+		t.Errorf("dmsctl add -o xml error = %v, stderr = %q, want unknown output format", err, stderr)
+	}
+}
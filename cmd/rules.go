@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// rulesCmd represents the dmsctl rules command
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage dotnet-monitor collection rules",
+	Long: `Collection rules lets dotnet-monitor collect artifacts, like dumps and traces, when a trigger fires.
+Example:
+	# Apply collection rules to the debug sidecar of a Deployment
+	dmsctl rules apply my-deployment -f rules.yaml
+	# List the collection rules and their state in a pod
+	dmsctl rules list my-pod --token $TOKEN`,
+}
+
+// rulesApplyCmd represents the dmsctl rules apply command
+var rulesApplyCmd = &cobra.Command{
+	Use:   "apply [kind/]name",
+	Short: "Validate and apply collection rules to a workload with a debug sidecar",
+	Long: `Validate the collection rules in a file and store them in a ConfigMap mounted into the debug sidecar under /etc/dotnet-monitor.
+The workload is a Deployment unless prefixed with daemonset/.
+The file uses the dotnet-monitor settings format, with the rules under CollectionRules and the egress providers the actions reference under Egress.
+Example:
+	# Apply collection rules to the debug sidecar of a Deployment
+	dmsctl rules apply my-deployment -f rules.yaml
+	# Apply collection rules to the debug sidecar of a DaemonSet
+	dmsctl rules apply daemonset/my-daemonset -f rules.yaml
+
+Example rules.yaml collecting a dump when cpu usage is above 80% for one minute:
+	CollectionRules:
+	  HighCpu:
+	    Trigger:
+	      Type: EventCounter
+	      Settings:
+	        ProviderName: System.Runtime
+	        CounterName: cpu-usage
+	        GreaterThan: 80
+	        SlidingWindowDuration: "00:01:00"
+	    Actions:
+	    - Type: CollectDump
+	      Settings:
+	        Egress: tmp
+	    Limits:
+	      ActionCount: 1
+	Egress:
+	  FileSystem:
+	    tmp:
+	      DirectoryPath: /tmp/dumps`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.ApplyCollectionRules(cmd.Context(), factory, args[0], rulesfile)
+	},
+}
+
+// rulesListCmd represents the dmsctl rules list command
+var rulesListCmd = &cobra.Command{
+	Use:   "list [podname]",
+	Short: "List the collection rules in a pod and their state",
+	Long: `List the collection rules dotnet-monitor has loaded in a pod and their state, queried from the /collectionrules endpoint.
+Example:
+	# List the collection rules in the pod my-pod
+	dmsctl rules list my-pod --token $TOKEN`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: utils.AutoCompletePodsWithDebugContainer(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.ListCollectionRules(cmd.Context(), factory, args[0], token)
+	},
+}
+
+// rulesStatusCmd represents the dmsctl rules status command
+var rulesStatusCmd = &cobra.Command{
+	Use:   "status [podname] [rule]",
+	Short: "Show the trigger state of the collection rules in a pod",
+	Long: `Show the trigger state of one or all collection rules in a pod, queried from the /collectionrules endpoint.
+Example:
+	# Show the trigger state of all collection rules in the pod my-pod
+	dmsctl rules status my-pod --token $TOKEN
+	# Show the trigger state of the collection rule HighCpu in the pod my-pod
+	dmsctl rules status my-pod HighCpu --token $TOKEN`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: utils.AutoCompletePodsWithDebugContainer(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rulename := ""
+		if len(args) > 1 {
+			rulename = args[1]
+		}
+		return dmscmd.CollectionRulesStatus(cmd.Context(), factory, args[0], token, rulename)
+	},
+}
+
+var (
+	rulesfile string
+	token     string
+)
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+
+	rulesCmd.AddCommand(rulesApplyCmd)
+	rulesApplyCmd.Flags().StringVarP(&rulesfile, "filename", "f", "", "File containing the collection rules")
+	rulesApplyCmd.MarkFlagRequired("filename")
+
+	rulesCmd.AddCommand(rulesListCmd)
+	rulesListCmd.Flags().StringVar(&token, "token", "", "Bearer token printed when the debug sidecar was added")
+
+	rulesCmd.AddCommand(rulesStatusCmd)
+	rulesStatusCmd.Flags().StringVar(&token, "token", "", "Bearer token printed when the debug sidecar was added")
+}
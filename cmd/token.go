@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	dmscmd "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/cmd"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// tokenCmd represents the dmsctl token command
+var tokenCmd = &cobra.Command{
+	Use:   "token [kind/]name",
+	Short: "Issue a new token for the debug sidecar of a workload",
+	Long: `Issue a new bearer token for the dotnet-monitor API of the debug sidecar of a workload, replacing the key in its secret.
+Only the public key is stored, so the token of a sidecar is not known after it is printed by add, or when add reuses an existing secret.
+The sidecar reads the key from the secret mounted in its pod, so the pods are not restarted. The tokens issued before are rejected
+once the kubelet updates the mounted secret, which can take a minute or two. The workload kind defaults to deployment.
+Example:
+	# Issue a new token for the debug sidecar of a Deployment
+	dmsctl token my-deployment
+	# Issue a new token for the debug sidecar of a DaemonSet and read it in a script
+	dmsctl token daemonset/my-daemonset -o json | jq -r .token`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: utils.AutoCompleteDeployments(factory),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return dmscmd.IssueToken(cmd.Context(), factory, args[0], output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -12,6 +12,10 @@ func main() {
 	ctx := context.Background()
 	dmsctl := cmd.NewDmsctlCommand(ctx)
 	dmsctl.DisableAutoGenTag = true
+	// The default cache dir is in the home directory of whoever generates the docs
+	if f := dmsctl.PersistentFlags().Lookup("cache-dir"); f != nil {
+		f.DefValue = "$HOME/.kube/cache"
+	}
 	err := doc.GenMarkdownTree(dmsctl, "./docs")
 	if err != nil {
 		log.Fatal(err)
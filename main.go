@@ -17,7 +17,8 @@ package main
 
 import (
 	"github.com/altinn/dotnet-monitor-sidecar-cli/cmd"
-	_ "k8s.io/client-go/plugin/pkg/client/auth/azure"
+	// Exec credential plugins like kubelogin are built in, the auth providers of old kubeconfigs are registered here
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
 )
 
 func main() {
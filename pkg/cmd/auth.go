@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+)
+
+// CanI prints if the permissions to add and remove the debug sidecar of a workload on the form [kind/]name, and port-forward
+// to its pods, are allowed. Returns an error if any of them are missing
+func CanI(ctx context.Context, f dmskube.Factory, workload string, output string) error {
+	kind, name, err := parseWorkload(workload)
+	if err != nil {
+		return err
+	}
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	// Adding with the default options needs the permissions removing the sidecar added with them does
+	reviews, err := h.ReviewAccess(ctx, namespace, dmskube.AddSidecarPermissions(kind, name, resources.SidecarOptions{Audit: &resources.AuditInfo{}}, false))
+	if err != nil {
+		return fmt.Errorf("failed to review permissions: %w", err)
+	}
+	if output != OutputText {
+		err = printResult(output, reviews)
+		if err != nil {
+			return err
+		}
+	} else {
+		printAccessReviews(os.Stdout, reviews)
+	}
+	if len(dmskube.MissingPermissions(reviews)) > 0 {
+		return fmt.Errorf("%w to add the debug sidecar to %s %s in namespace %s", errors.ErrMissingPermissions, kind, name, namespace)
+	}
+	return nil
+}
+
+// removePermissions returns the permissions needed to remove the debug sidecar of a workload, including those to delete the resources
+// in its DDConfig. Failing to read the DDConfig is left to the removal to report, with the permissions needed without it
+func removePermissions(ctx context.Context, h dmskube.Helper, namespace, kind, name, dryRun string) []dmskube.Permission {
+	ddConfig, _ := h.GetWorkloadDDConfig(ctx, namespace, kind, name)
+	return dmskube.RemoveSidecarPermissions(kind, name, ddConfig, dryRun)
+}
+
+// preflight reviews the permissions a change needs before making it, so a forbidden request does not leave it halfway done.
+// The missing permissions are printed as a table. The change is made anyway if the permissions could not be reviewed
+func preflight(ctx context.Context, h dmskube.Helper, namespace string, permissions []dmskube.Permission, dryRun, action string) error {
+	if dryRun == resources.DryRunClient {
+		return nil
+	}
+	reviews, err := h.ReviewAccess(ctx, namespace, permissions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to review permissions to %s: %v\n", action, err)
+		return nil
+	}
+	missing := dmskube.MissingPermissions(reviews)
+	if len(missing) == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Missing permissions in namespace %s:\n", namespace)
+	printAccessReviews(os.Stderr, missing)
+	return fmt.Errorf("%w to %s", errors.ErrMissingPermissions, action)
+}
+
+// printAccessReviews prints the access reviews as a table like kubectl auth can-i --list
+func printAccessReviews(out io.Writer, reviews []dmskube.AccessReview) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "VERB\tRESOURCE\tNAME\tALLOWED\tREASON")
+	for _, r := range reviews {
+		allowed := "no"
+		if r.Allowed {
+			allowed = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Verb, r.ResourceString(), r.Name, allowed, r.Reason)
+	}
+	w.Flush()
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+)
+
+// ApplyCollectionRules validates the collection rules in rulesfile and applies them to the debug sidecar of a workload
+func ApplyCollectionRules(ctx context.Context, f dmskube.Factory, workload, rulesfile string) error {
+	kind, name, err := parseWorkload(workload)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(rulesfile)
+	if err != nil {
+		return fmt.Errorf("failed to read collection rules file: %w", err)
+	}
+	rules, err := resources.ParseCollectionRules(data)
+	if err != nil {
+		return fmt.Errorf("invalid collection rules: %w", err)
+	}
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	var cm string
+	switch kind {
+	case kindDaemonSet:
+		_, cm, err = h.ApplyCollectionRulesDaemonSet(ctx, namespace, name, rules)
+	default:
+		_, cm, err = h.ApplyCollectionRulesDeployment(ctx, namespace, name, rules)
+	}
+	if err != nil {
+		if errors.IsNotPresent(err) {
+			return fmt.Errorf("%w, add it with dmsctl add %s %s", err, kind, name)
+		}
+		return fmt.Errorf("failed to apply collection rules to %s %s: %w", kind, name, err)
+	}
+	fmt.Printf("Applied %d collection rules to %s %s using configmap %s\n", len(rules.CollectionRules), kind, name, cm)
+	return nil
+}
+
+// ListCollectionRules prints the state of the collection rules in the debug sidecar of a pod
+func ListCollectionRules(ctx context.Context, f dmskube.Factory, podname, token string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	rules, err := h.ListCollectionRules(ctx, namespace, podname, token)
+	if err != nil {
+		return fmt.Errorf("failed to list collection rules in pod %s: %w", podname, err)
+	}
+	names := make([]string, 0, len(rules))
+	for n := range rules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tREASON")
+	for _, n := range names {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", n, rules[n].State, rules[n].StateReason)
+	}
+	return w.Flush()
+}
+
+// CollectionRulesStatus prints the detailed trigger state of one or all collection rules in the debug sidecar of a pod
+func CollectionRulesStatus(ctx context.Context, f dmskube.Factory, podname, token, rulename string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	var rulenames []string
+	if rulename != "" {
+		rulenames = append(rulenames, rulename)
+	}
+	details, err := h.GetCollectionRuleDetails(ctx, namespace, podname, token, rulenames...)
+	if err != nil {
+		return fmt.Errorf("failed to get status of collection rules in pod %s: %w", podname, err)
+	}
+	names := make([]string, 0, len(details))
+	for n := range details {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATE\tOCCURRENCES\tWINDOW OCCURRENCES\tACTION LIMIT\tWINDOW COUNTDOWN\tFINISHED COUNTDOWN")
+	for _, n := range names {
+		d := details[n]
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", n, d.State, d.LifetimeOccurrences, d.SlidingWindowOccurrences, d.ActionCountLimit, d.SlidingWindowDurationCountdown, d.RuleFinishedCountdown)
+	}
+	return w.Flush()
+}
@@ -3,84 +3,103 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
-	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
-
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // AddToDaemonset setup debug sidecar to a Daemonset and configures it
-func AddToDaemonset(ctx context.Context, kubeconfig string, namespace string, deploymentname, containername, debugimage string) {
-	if home := homedir.HomeDir(); home != "" && kubeconfig == "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
+func AddToDaemonset(ctx context.Context, f dmskube.Factory, daemonsetname, containername, debugimage string, opts resources.SidecarOptions, wait WaitOptions, output string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
 	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	warnPodSecurity(ctx, h, namespace, opts)
+	auditUser(ctx, h, f, opts.Audit)
+	err = preflight(ctx, h, namespace, dmskube.AddSidecarPermissions("DaemonSet", daemonsetname, opts, wait.Wait), opts.DryRun, fmt.Sprintf("add the debug sidecar to daemonset %s", daemonsetname))
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	if namespace == "" {
-		namespace, err = utils.GetNamespaceFromCurrentContext()
+	var before *appsv1.DaemonSet
+	if resources.IsDryRun(opts.DryRun) {
+		before, err = h.Client.AppsV1().DaemonSets(namespace).Get(ctx, daemonsetname, metav1.GetOptions{})
 		if err != nil {
-			fmt.Printf("Error getting namespace: %v", err)
-			return
+			return fmt.Errorf("failed to get daemonset %s: %w", daemonsetname, err)
 		}
 	}
-	clientset, err := kubernetes.NewForConfig(config)
+	d, token, err := h.AddDebugSidecarDaemonSet(ctx, namespace, daemonsetname, containername, debugimage, opts)
 	if err != nil {
-		panic(err.Error())
+		if errors.IsAlreadyPresent(err) {
+			return err
+		}
+		return fmt.Errorf("failed to attach sidecar to daemonset %s: %w", daemonsetname, err)
 	}
-	h := dmskube.Helper{
-		Client: clientset,
+	ddConfig, err := resources.DDConfigFromPodTemplate(d.Spec.Template)
+	if err != nil {
+		return err
 	}
-	d, token, err := h.AddDebugSidecarDaemonSet(ctx, namespace, deploymentname, containername, debugimage)
-
+	result := sidecarResult("DaemonSet", namespace, d, ddConfig, token)
+	if before != nil {
+		return printDryRunResult(output, result, before.Spec.Template, d.Spec.Template, opts.DryRun)
+	}
+	result.SecretReused = token == ""
+	fmt.Fprintf(infoWriter(output), "Added sidecar to daemonset %s with uid %s\n", d.Name, d.UID)
+	if opts.Audit != nil {
+		recordEvent(ctx, h, "DaemonSet", d, dmskube.EventReasonSidecarAdded, dmskube.AuditMessage("Debug sidecar added", *opts.Audit))
+	}
+	err = waitForRollout(ctx, h, infoWriter(output), "DaemonSet", namespace, daemonsetname, wait, opts.Audit, func() (metav1.Object, error) {
+		d, _, err := h.RemoveDebugSidecarDaemonSet(ctx, namespace, daemonsetname, resources.RemoveOptions{RestoreExact: true})
+		return d, err
+	})
 	if err != nil {
-		if errors.IsAlreadyPresent(err) {
-			fmt.Printf("Debug sidecar already attached to deployment %s\n", d.Name)
-			return
-		}
-		panic(err.Error())
+		return err
+	}
+	if output != OutputText {
+		return printResult(output, result)
 	}
-	fmt.Printf("Added sidecar to daemonset %s with uid %s\n", d.Name, d.UID)
-	fmt.Printf("Portforward to one of the pods with dmsctl port-forward [podname].\nQuery the API with this auth header:\nAuthorization: Bearer %s\n", token)
+	printMetricsInfo(opts)
+	printExpiryInfo(opts)
+	printTokenInfo(result)
+	return nil
 }
 
 // RemoveFromDaemonset removes the debug sidecar and configuration from a daemonset
-func RemoveFromDaemonset(ctx context.Context, kubeconfig string, namespace string, daemonsetname string) {
-	if home := homedir.HomeDir(); home != "" && kubeconfig == "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
+func RemoveFromDaemonset(ctx context.Context, f dmskube.Factory, daemonsetname string, audit resources.AuditInfo, opts resources.RemoveOptions, output string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
 	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	auditUser(ctx, h, f, &audit)
+	err = preflight(ctx, h, namespace, removePermissions(ctx, h, namespace, "DaemonSet", daemonsetname, opts.DryRun), opts.DryRun, fmt.Sprintf("remove the debug sidecar from daemonset %s", daemonsetname))
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	if namespace == "" {
-		namespace, err = utils.GetNamespaceFromCurrentContext()
+	var before *appsv1.DaemonSet
+	if resources.IsDryRun(opts.DryRun) {
+		before, err = h.Client.AppsV1().DaemonSets(namespace).Get(ctx, daemonsetname, metav1.GetOptions{})
 		if err != nil {
-			fmt.Printf("Error getting namespace: %v", err)
-			return
+			return fmt.Errorf("failed to get daemonset %s: %w", daemonsetname, err)
 		}
 	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
-	h := dmskube.Helper{
-		Client: clientset,
-	}
-	d, err := h.RemoveDebugSidecarDaemonSet(ctx, namespace, daemonsetname)
-
+	d, restored, err := h.RemoveDebugSidecarDaemonSet(ctx, namespace, daemonsetname, opts)
 	if err != nil {
 		if errors.IsNotPresent(err) {
-			fmt.Printf("Debug sidecar not attached to daemonset %s\n", d.Name)
-			return
+			return err
 		}
-		panic(err.Error())
+		return fmt.Errorf("failed to remove sidecar from daemonset %s: %w", daemonsetname, err)
+	}
+	printRestoreInfo(infoWriter(output), restored, opts)
+	result := sidecarResult("DaemonSet", namespace, d, restored.Config, "")
+	if before != nil {
+		return printDryRunResult(output, result, before.Spec.Template, d.Spec.Template, opts.DryRun)
+	}
+	recordEvent(ctx, h, "DaemonSet", d, dmskube.EventReasonSidecarRemoved, dmskube.AuditMessage("Debug sidecar removed", audit))
+	fmt.Fprintf(infoWriter(output), "Removed sidecar from daemonset %s with uid %s\n", d.Name, d.UID)
+	if output != OutputText {
+		return printResult(output, result)
 	}
-	fmt.Printf("Removed sidecar from daemonset %s with uid %s\n", d.Name, d.UID)
+	return nil
 }
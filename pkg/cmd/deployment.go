@@ -3,85 +3,103 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"path/filepath"
 
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
-	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
-
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // AddToDeployment adds a debug sidecar to a deployment and configures it
-func AddToDeployment(ctx context.Context, kubeconfig string, namespace string, deploymentname, containername, debugimage string) {
-	if home := homedir.HomeDir(); home != "" && kubeconfig == "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
+func AddToDeployment(ctx context.Context, f dmskube.Factory, deploymentname, containername, debugimage string, opts resources.SidecarOptions, wait WaitOptions, output string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
 	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	warnPodSecurity(ctx, h, namespace, opts)
+	auditUser(ctx, h, f, opts.Audit)
+	err = preflight(ctx, h, namespace, dmskube.AddSidecarPermissions("Deployment", deploymentname, opts, wait.Wait), opts.DryRun, fmt.Sprintf("add the debug sidecar to deployment %s", deploymentname))
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	if namespace == "" {
-		namespace, err = utils.GetNamespaceFromCurrentContext()
+	var before *appsv1.Deployment
+	if resources.IsDryRun(opts.DryRun) {
+		before, err = h.Client.AppsV1().Deployments(namespace).Get(ctx, deploymentname, metav1.GetOptions{})
 		if err != nil {
-			fmt.Printf("Error getting namespace: %v", err)
-			return
+			return fmt.Errorf("failed to get deployment %s: %w", deploymentname, err)
 		}
 	}
-	clientset, err := kubernetes.NewForConfig(config)
+	d, token, err := h.AddDebugSidecarDeployment(ctx, namespace, deploymentname, containername, debugimage, opts)
 	if err != nil {
-		panic(err.Error())
+		if errors.IsAlreadyPresent(err) {
+			return err
+		}
+		return fmt.Errorf("failed to attach sidecar to deployment %s: %w", deploymentname, err)
 	}
-	h := dmskube.Helper{
-		Client: clientset,
+	ddConfig, err := resources.DDConfigFromPodTemplate(d.Spec.Template)
+	if err != nil {
+		return err
 	}
-	d, token, err := h.AddDebugSidecarDeployment(ctx, namespace, deploymentname, containername, debugimage)
-
+	result := sidecarResult("Deployment", namespace, d, ddConfig, token)
+	if before != nil {
+		return printDryRunResult(output, result, before.Spec.Template, d.Spec.Template, opts.DryRun)
+	}
+	result.SecretReused = token == ""
+	fmt.Fprintf(infoWriter(output), "Added sidecar to deployment %s with uid %s\n", d.Name, d.UID)
+	if opts.Audit != nil {
+		recordEvent(ctx, h, "Deployment", d, dmskube.EventReasonSidecarAdded, dmskube.AuditMessage("Debug sidecar added", *opts.Audit))
+	}
+	err = waitForRollout(ctx, h, infoWriter(output), "Deployment", namespace, deploymentname, wait, opts.Audit, func() (metav1.Object, error) {
+		d, _, err := h.RemoveDebugSidecarDeployment(ctx, namespace, deploymentname, resources.RemoveOptions{RestoreExact: true})
+		return d, err
+	})
 	if err != nil {
-		if errors.IsAlreadyPresent(err) {
-			fmt.Printf("Debug sidecar already attached to deployment %s\n", d.Name)
-			return
-		}
-		fmt.Printf("Failed to attach sidecar to deployment %s: %v\n", deploymentname, err)
-		return
+		return err
+	}
+	if output != OutputText {
+		return printResult(output, result)
 	}
-	fmt.Printf("Added sidecar to deployment %s with uid %s\n", d.Name, d.UID)
-	fmt.Printf("Portforward to one of the pods with dmsctl port-forward [podname].\nQuery the API with this auth header:\nAuthorization: Bearer %s\n", token)
+	printMetricsInfo(opts)
+	printExpiryInfo(opts)
+	printTokenInfo(result)
+	return nil
 }
 
 // RemoveFromDeployment removes the debug sidecar and configuration from a deployment
-func RemoveFromDeployment(ctx context.Context, kubeconfig string, namespace string, deploymentname string) {
-	if home := homedir.HomeDir(); home != "" && kubeconfig == "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
+func RemoveFromDeployment(ctx context.Context, f dmskube.Factory, deploymentname string, audit resources.AuditInfo, opts resources.RemoveOptions, output string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
 	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	auditUser(ctx, h, f, &audit)
+	err = preflight(ctx, h, namespace, removePermissions(ctx, h, namespace, "Deployment", deploymentname, opts.DryRun), opts.DryRun, fmt.Sprintf("remove the debug sidecar from deployment %s", deploymentname))
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	if namespace == "" {
-		namespace, err = utils.GetNamespaceFromCurrentContext()
+	var before *appsv1.Deployment
+	if resources.IsDryRun(opts.DryRun) {
+		before, err = h.Client.AppsV1().Deployments(namespace).Get(ctx, deploymentname, metav1.GetOptions{})
 		if err != nil {
-			fmt.Printf("Error getting namespace: %v", err)
-			return
+			return fmt.Errorf("failed to get deployment %s: %w", deploymentname, err)
 		}
 	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
-	}
-	h := dmskube.Helper{
-		Client: clientset,
-	}
-	d, err := h.RemoveDebugSidecarDeployment(ctx, namespace, deploymentname)
-
+	d, restored, err := h.RemoveDebugSidecarDeployment(ctx, namespace, deploymentname, opts)
 	if err != nil {
 		if errors.IsNotPresent(err) {
-			fmt.Printf("Debug sidecar not attached to deployment %s\n", d.Name)
-			return
+			return err
 		}
-		panic(err.Error())
+		return fmt.Errorf("failed to remove sidecar from deployment %s: %w", deploymentname, err)
+	}
+	printRestoreInfo(infoWriter(output), restored, opts)
+	result := sidecarResult("Deployment", namespace, d, restored.Config, "")
+	if before != nil {
+		return printDryRunResult(output, result, before.Spec.Template, d.Spec.Template, opts.DryRun)
+	}
+	recordEvent(ctx, h, "Deployment", d, dmskube.EventReasonSidecarRemoved, dmskube.AuditMessage("Debug sidecar removed", audit))
+	fmt.Fprintf(infoWriter(output), "Removed sidecar from deployment %s with uid %s\n", d.Name, d.UID)
+	if output != OutputText {
+		return printResult(output, result)
 	}
-	fmt.Printf("Removed sidecar from deployment %s with uid %s\n", d.Name, d.UID)
+	return nil
 }
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+)
+
+// Doctor checks the debug sidecar of a pod, or of a pod of a workload on the form kind/name, and prints the findings
+// with how to fix them. Returns an error if any check failed
+func Doctor(ctx context.Context, f dmskube.Factory, target, token, output string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	var diagnosis dmskube.Diagnosis
+	kind, name, found := strings.Cut(target, "/")
+	switch strings.ToLower(kind) {
+	case "pod", "pods", "po":
+		diagnosis, err = h.DiagnosePod(ctx, namespace, name, token)
+	default:
+		if !found {
+			diagnosis, err = h.DiagnosePod(ctx, namespace, target, token)
+			break
+		}
+		kind, name, err = parseWorkload(target)
+		if err != nil {
+			return err
+		}
+		diagnosis, err = h.DiagnoseWorkload(ctx, namespace, kind, name, token)
+	}
+	if err != nil {
+		if errors.IsNotPresent(err) {
+			return err
+		}
+		return fmt.Errorf("failed to diagnose %s: %w", target, err)
+	}
+	if output != OutputText {
+		err = printResult(output, diagnosis)
+		if err != nil {
+			return err
+		}
+	} else {
+		printDiagnosis(diagnosis)
+	}
+	problems := 0
+	for _, finding := range diagnosis.Findings {
+		if finding.Severity == resources.FindingError {
+			problems++
+		}
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %d problems with the debug sidecar of %s", problems, target)
+	}
+	return nil
+}
+
+// printDiagnosis prints the findings of a diagnosis, with how to fix the warnings and errors
+func printDiagnosis(diagnosis dmskube.Diagnosis) {
+	switch {
+	case diagnosis.Pod == "":
+		fmt.Printf("Diagnosed %s in namespace %s\n", diagnosis.Workload, diagnosis.Namespace)
+	case diagnosis.Workload != "":
+		fmt.Printf("Diagnosed pod %s of %s in namespace %s\n", diagnosis.Pod, diagnosis.Workload, diagnosis.Namespace)
+	default:
+		fmt.Printf("Diagnosed pod %s in namespace %s\n", diagnosis.Pod, diagnosis.Namespace)
+	}
+	for _, finding := range diagnosis.Findings {
+		fmt.Printf("%-10s%s: %s\n", "["+finding.Severity+"]", finding.Check, finding.Message)
+		if finding.Fix != "" {
+			fmt.Printf("%-10sfix: %s\n", "", finding.Fix)
+		}
+	}
+}
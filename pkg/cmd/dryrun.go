@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// printDryRun prints the unified diff of the pod template of a workload changed in dry run, colored on a terminal
+func printDryRun(kind, name string, before, after corev1.PodTemplateSpec, dryRun string) error {
+	diff, err := dryRunDiff(kind, name, before, after, dryRun)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Printf("No changes to the pod template of %s %s (dry run %s)\n", kind, name, dryRun)
+		return nil
+	}
+	fmt.Print(colorDiff(diff))
+	return nil
+}
+
+// printDryRunResult prints the diff of the pod template of a workload changed in dry run, or the result with the diff as json or yaml
+func printDryRunResult(output string, result SidecarResult, before, after corev1.PodTemplateSpec, dryRun string) error {
+	kind := strings.ToLower(result.Kind)
+	if output == OutputText {
+		return printDryRun(kind, result.Name, before, after, dryRun)
+	}
+	diff, err := dryRunDiff(kind, result.Name, before, after, dryRun)
+	if err != nil {
+		return err
+	}
+	result.DryRun, result.Diff = dryRun, diff
+	return printResult(output, result)
+}
+
+func dryRunDiff(kind, name string, before, after corev1.PodTemplateSpec, dryRun string) (string, error) {
+	diff, err := resources.PodTemplateDiff(fmt.Sprintf("%s/%s", kind, name), fmt.Sprintf("%s/%s (dry run %s)", kind, name, dryRun), before, after)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff the pod template of %s %s: %w", kind, name, err)
+	}
+	return diff, nil
+}
+
+// colorDiff colors a unified diff if stdout is a terminal and NO_COLOR is not set
+func colorDiff(diff string) string {
+	if os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return diff
+	}
+	return resources.ColorDiff(diff)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+)
+
+// CollectGarbage deletes the secrets and collection rules configmaps created by the cli that are no longer used by their workload
+func CollectGarbage(ctx context.Context, f dmskube.Factory, allNamespaces bool, dryRun string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	if allNamespaces {
+		namespace = ""
+	}
+	orphans, warnings, err := h.CollectOrphanedResources(ctx, namespace, dryRun)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	if len(orphans) > 0 {
+		action := "deleted"
+		if resources.IsDryRun(dryRun) {
+			action = "would be deleted"
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tKIND\tNAME\tOWNER\tREASON\tACTION")
+		for _, o := range orphans {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", o.Namespace, o.Kind, o.Name, o.Owner, o.Reason, action)
+		}
+		w.Flush()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to collect orphaned resources: %w", err)
+	}
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned resources found")
+	}
+	return nil
+}
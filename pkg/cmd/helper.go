@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	kindDeployment = "deployment"
+	kindDaemonSet  = "daemonset"
+)
+
+// newHelper returns a kubernetes helper and the namespace of the request from the factory
+func newHelper(f dmskube.Factory) (dmskube.Helper, string, error) {
+	h, err := f.Helper()
+	if err != nil {
+		return dmskube.Helper{}, "", err
+	}
+	namespace, _, err := f.Namespace()
+	if err != nil {
+		return dmskube.Helper{}, "", err
+	}
+	return h, namespace, nil
+}
+
+// parseWorkload splits a workload reference on the form [kind/]name. Kind defaults to deployment
+func parseWorkload(workload string) (kind, name string, err error) {
+	kind, name, found := strings.Cut(workload, "/")
+	if !found {
+		return kindDeployment, workload, nil
+	}
+	switch strings.ToLower(kind) {
+	case "deployment", "deployments", "deploy":
+		return kindDeployment, name, nil
+	case "daemonset", "daemonsets", "ds":
+		return kindDaemonSet, name, nil
+	}
+	return "", "", fmt.Errorf("unsupported workload kind %s, supported kinds are deployment and daemonset", kind)
+}
+
+// printMetricsInfo prints how the prometheus metrics endpoint was exposed
+func printMetricsInfo(opts resources.SidecarOptions) {
+	if !opts.Metrics {
+		return
+	}
+	fmt.Printf("Prometheus metrics are served on port %d (%s)\n", resources.MetricsPort, resources.MetricsPortName)
+	if opts.MetricsAnnotations {
+		fmt.Println("Added prometheus.io scrape annotations to the pod template")
+	}
+	if opts.PodMonitorName != "" {
+		fmt.Printf("Created PodMonitor %s\n", opts.PodMonitorName)
+	}
+}
+
+// printExpiryInfo prints when the sidecar expires
+func printExpiryInfo(opts resources.SidecarOptions) {
+	if opts.ExpiresAt == nil {
+		return
+	}
+	fmt.Printf("Sidecar expires at %s and is removed by dmsctl reap after that\n", opts.ExpiresAt.Format(time.RFC3339))
+}
+
+// printTokenInfo prints how to query the API of the sidecar added, with the token or how to issue one if the secret was reused
+func printTokenInfo(result SidecarResult) {
+	fmt.Println("Portforward to one of the pods with dmsctl port-forward [podname].")
+	if result.SecretReused {
+		fmt.Printf("Reused secret %s, its token is not available. Issue a new token with dmsctl token %s/%s\n", result.SecretName, strings.ToLower(result.Kind), result.Name)
+		return
+	}
+	fmt.Printf("Query the API with this auth header:\nAuthorization: Bearer %s\n", result.Token)
+}
+
+// printRestoreInfo prints if the pod template snapshot was restored, or why not and how the result differs from it
+func printRestoreInfo(w io.Writer, restored resources.RestoredPodTemplate, opts resources.RemoveOptions) {
+	if !opts.RestoreExact {
+		return
+	}
+	if restored.Exact {
+		fmt.Fprintln(w, "Restored the pod template from before the sidecar was added")
+		return
+	}
+	fmt.Fprintf(w, "Unable to restore the exact pod template, %s. Removed the sidecar surgically instead\n", restored.Reason)
+	if restored.Diff != "" {
+		fmt.Fprintf(w, "Difference from the pod template before the sidecar was added:\n%s", colorDiff(restored.Diff))
+	}
+}
+
+// auditUser sets the user of the audit info to who the cluster authenticates us as, or the user of the kubeconfig context
+func auditUser(ctx context.Context, h dmskube.Helper, f dmskube.Factory, audit *resources.AuditInfo) {
+	if audit == nil {
+		return
+	}
+	fallback, err := f.User()
+	if err != nil {
+		fallback = "unknown"
+	}
+	audit.User = h.WhoAmI(ctx, fallback)
+}
+
+// recordEvent records an event on the workload, printing a warning if it fails as the change itself succeeded
+func recordEvent(ctx context.Context, h dmskube.Helper, kind string, workload metav1.Object, reason, message string) {
+	err := h.RecordWorkloadEvent(ctx, kind, workload, reason, message)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to record event on %s %s: %v\n", strings.ToLower(kind), workload.GetName(), err)
+	}
+}
+
+// warnPodSecurity prints a warning for each Pod Security admission level of the namespace the sidecar would violate
+func warnPodSecurity(ctx context.Context, h dmskube.Helper, namespace string, opts resources.SidecarOptions) {
+	warnings, err := h.PodSecurityWarnings(ctx, namespace, opts.SecurityProfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to check Pod Security labels of namespace %s: %v\n", namespace, err)
+		return
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s. Consider --security-profile %s\n", w, resources.SecurityProfileRestricted)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Target is what a command acts on. The fields not supplied as arguments or flags are picked interactively
+type Target struct {
+	Namespace string
+	// Kind is deployment or daemonset for workloads
+	Kind string
+	// Name of the workload or pod
+	Name string
+	// Container to debug in the pods of a workload
+	Container string
+}
+
+// PickWorkload picks the namespace, kind, name and container to debug of a workload to add the debug sidecar to,
+// for the ones not set in target. Workloads which already have the debug sidecar are not listed
+func PickWorkload(ctx context.Context, f dmskube.Factory, p *utils.Picker, target Target) (Target, error) {
+	h, namespace, err := pickNamespace(ctx, f, p, target)
+	if err != nil {
+		return target, err
+	}
+	target.Namespace = namespace
+	if target.Kind == "" {
+		target.Kind, err = p.Pick("kind", []string{kindDeployment, kindDaemonSet})
+		if err != nil {
+			return target, err
+		}
+	}
+	templates := map[string]corev1.PodTemplateSpec{}
+	var names []string
+	switch target.Kind {
+	case kindDaemonSet:
+		daemonsets, err := h.ListDaemonsetsInNamespace(ctx, target.Namespace)
+		if err != nil {
+			return target, err
+		}
+		for _, d := range daemonsets.Items {
+			templates[d.Name] = d.Spec.Template
+		}
+	default:
+		deployments, err := h.ListDeploymentsInNamespace(ctx, target.Namespace)
+		if err != nil {
+			return target, err
+		}
+		for _, d := range deployments.Items {
+			templates[d.Name] = d.Spec.Template
+		}
+	}
+	for name, template := range templates {
+		if !resources.HasDebugSidecar(template.Annotations) {
+			names = append(names, name)
+		}
+	}
+	if target.Name == "" {
+		if len(names) == 0 {
+			return target, fmt.Errorf("no %s without the debug sidecar in namespace %s", target.Kind, target.Namespace)
+		}
+		slices.Sort(names)
+		target.Name, err = p.Pick(target.Kind, names)
+		if err != nil {
+			return target, err
+		}
+	}
+	template, found := templates[target.Name]
+	if target.Container != "" || !found || len(template.Spec.Containers) < 2 {
+		return target, nil
+	}
+	var containers []string
+	for _, c := range template.Spec.Containers {
+		containers = append(containers, c.Name)
+	}
+	target.Container, err = p.Pick("container", containers)
+	return target, err
+}
+
+// PickPod picks the namespace and name of a running pod with the debug sidecar, for the ones not set in target
+func PickPod(ctx context.Context, f dmskube.Factory, p *utils.Picker, target Target) (Target, error) {
+	h, namespace, err := pickNamespace(ctx, f, p, target)
+	if err != nil {
+		return target, err
+	}
+	target.Namespace = namespace
+	if target.Name != "" {
+		return target, nil
+	}
+	pods, err := h.ListPodsInNamespace(ctx, target.Namespace)
+	if err != nil {
+		return target, err
+	}
+	var names []string
+	for _, pod := range pods.Items {
+		if resources.HasDebugSidecar(pod.Annotations) && pod.Status.Phase == corev1.PodRunning {
+			names = append(names, pod.Name)
+		}
+	}
+	if len(names) == 0 {
+		return target, fmt.Errorf("no running pods with the debug sidecar in namespace %s", target.Namespace)
+	}
+	slices.Sort(names)
+	target.Name, err = p.Pick("pod", names)
+	return target, err
+}
+
+// pickNamespace returns a helper and the namespace of target, the namespace flag, or the one picked.
+// The namespace of the kubeconfig context is listed first, and used if listing the namespaces is forbidden
+func pickNamespace(ctx context.Context, f dmskube.Factory, p *utils.Picker, target Target) (dmskube.Helper, string, error) {
+	h, err := f.Helper()
+	if err != nil {
+		return h, "", err
+	}
+	if target.Namespace != "" {
+		return h, target.Namespace, nil
+	}
+	current, explicit, err := f.Namespace()
+	if err != nil || explicit {
+		return h, current, err
+	}
+	namespaces, err := h.ListNamespaces(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unable to list namespaces, using namespace %s: %v\n", current, err)
+		return h, current, nil
+	}
+	names := []string{current}
+	for _, ns := range namespaces.Items {
+		if ns.Name != current {
+			names = append(names, ns.Name)
+		}
+	}
+	slices.Sort(names[1:])
+	namespace, err := p.Pick("namespace", names)
+	return h, namespace, err
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// ListDebugWorkloads prints the workloads with the debug sidecar attached in a namespace, or all namespaces
+func ListDebugWorkloads(ctx context.Context, f dmskube.Factory, allNamespaces bool, output string) error {
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	if allNamespaces {
+		namespace = ""
+	}
+	workloads, err := h.ListDebugWorkloads(ctx, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list workloads with debug sidecar: %w", err)
+	}
+	switch output {
+	case OutputJSON, OutputYAML:
+		return printResult(output, workloads)
+	case OutputText, "wide":
+		printDebugWorkloads(workloads, allNamespaces, output == "wide")
+	default:
+		return fmt.Errorf("unknown output format %s, supported formats are json, yaml and wide", output)
+	}
+	return nil
+}
+
+// unparseable is shown in the columns from the DDConfig of workloads where it could not be parsed
+const unparseable = "<unparseable>"
+
+func printDebugWorkloads(workloads []dmskube.DebugWorkload, allNamespaces, wide bool) {
+	if len(workloads) == 0 {
+		fmt.Println("No workloads with debug sidecar found")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprint(w, "KIND\tNAME\tCONTAINER\tDEBUG CONTAINER\tREADY\tAGE")
+	if wide {
+		fmt.Fprint(w, "\tIMAGE\tSECRET\tEXPIRES")
+	}
+	fmt.Fprintln(w)
+	for _, d := range workloads {
+		if allNamespaces {
+			fmt.Fprintf(w, "%s\t", d.Namespace)
+		}
+		container, debugContainer := d.Config.ContainerToDebug, d.Config.DebugContainerName
+		if d.Unparseable() {
+			container, debugContainer = unparseable, unparseable
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d\t%s", d.Kind, d.Name, container, debugContainer, d.ReadyPods, d.DesiredPods, age(d.AddedAt()))
+		if wide {
+			secret, expiry := d.Config.SecretName, expires(d.Config.ExpiresAt)
+			if d.Unparseable() {
+				secret, expiry = unparseable, unparseable
+			}
+			fmt.Fprintf(w, "\t%s\t%s\t%s", d.Image, secret, expiry)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+	for _, d := range workloads {
+		if d.Unparseable() {
+			fmt.Fprintf(os.Stderr, "Warning: the debug sidecar config of %s %s/%s is unparseable: %s\n", d.Kind, d.Namespace, d.Name, d.Error)
+		}
+	}
+}
+
+// age returns the human readable time since t, like kubectl
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t))
+}
+
+// expires returns the human readable time until t, or <never> if the sidecar does not expire
+func expires(t *metav1.Time) string {
+	if t == nil {
+		return "<never>"
+	}
+	if t.Time.Before(time.Now()) {
+		return "expired"
+	}
+	return duration.HumanDuration(time.Until(t.Time))
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Output formats of the results of the commands. The default is human readable text
+const (
+	OutputText = ""
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// ValidateOutput returns an error if output is not a supported output format
+func ValidateOutput(output string) error {
+	switch output {
+	case OutputText, OutputJSON, OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %s, supported formats are json and yaml", output)
+}
+
+// SidecarResult is the result of adding or removing the debug sidecar of a workload
+type SidecarResult struct {
+	Kind      string    `json:"kind"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	UID       types.UID `json:"uid,omitempty"`
+	// SecretName is the name of the secret with the public key of the token
+	SecretName string `json:"secretName,omitempty"`
+	// Token is the bearer token of the dotnet-monitor API, only known when the sidecar is added with a new secret
+	Token string `json:"token,omitempty"`
+	// SecretReused is true if the existing secret of the workload is mounted, whose token is not known. Issue a new one with dmsctl token
+	SecretReused bool               `json:"secretReused,omitempty"`
+	Config       resources.DDConfig `json:"ddConfig"`
+	// DryRun is the dry run strategy, and Diff the unified diff of the pod template in dry run
+	DryRun string `json:"dryRun,omitempty"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+// TokenResult is the result of issuing a new token for the debug sidecar of a workload
+type TokenResult struct {
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	SecretName string `json:"secretName"`
+	// Token is the bearer token of the dotnet-monitor API
+	Token string `json:"token"`
+}
+
+// PortForwardResult is the result of forwarding a local port to dotnet-monitor in a pod
+type PortForwardResult struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	// URL is the local url of the dotnet-monitor API in the pod
+	URL string `json:"url"`
+}
+
+// infoWriter returns where to print human readable information, stderr if stdout is reserved for the result as json or yaml
+func infoWriter(output string) io.Writer {
+	if output == OutputText {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// printResult prints the result of a command as json or yaml
+func printResult(output string, result interface{}) error {
+	switch output {
+	case OutputJSON:
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case OutputYAML:
+		b, err := yaml.Marshal(result)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+	default:
+		return ValidateOutput(output)
+	}
+	return nil
+}
+
+// sidecarResult returns the result of adding the debug sidecar of a workload, or removing it with the DDConfig it was added with
+func sidecarResult(kind, namespace string, meta metav1.Object, config resources.DDConfig, token string) SidecarResult {
+	return SidecarResult{
+		Kind:       kind,
+		Namespace:  namespace,
+		Name:       meta.GetName(),
+		UID:        meta.GetUID(),
+		SecretName: config.SecretName,
+		Token:      token,
+		Config:     config,
+	}
+}
@@ -3,37 +3,31 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"path/filepath"
+	"os"
 
 	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
-	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 )
 
-// ForwardPort forwards port 52323 from host to a pod
-func ForwardPort(ctx context.Context, kubeconfig string, namespace string, podname string) {
-	if home := homedir.HomeDir(); home != "" && kubeconfig == "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+// ForwardPort forwards port 52323 from host to a pod. The local url is printed once the port is forwarded,
+// as a PortForwardResult with output set to json or yaml. The connections handled are logged with -v=5
+func ForwardPort(ctx context.Context, f dmskube.Factory, podname string, output string) error {
+	h, namespace, err := newHelper(f)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
-	if namespace == "" {
-		namespace, err = utils.GetNamespaceFromCurrentContext()
-		if err != nil {
-			fmt.Printf("Error getting namespace: %v", err)
+	ready := func(localURL string) {
+		if output == OutputText {
+			fmt.Printf("Forwarding %s to dotnet-monitor in pod %s, press Ctrl+C to stop\n", localURL, podname)
 			return
 		}
+		err := printResult(output, PortForwardResult{Namespace: namespace, Pod: podname, URL: localURL})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to print port forward result: %v\n", err)
+		}
 	}
-	clientset, err := kubernetes.NewForConfig(config)
+	err = h.PortForward(ctx, namespace, podname, ready)
 	if err != nil {
-		panic(err.Error())
-	}
-	h := dmskube.Helper{
-		Client: clientset,
+		return fmt.Errorf("failed to forward port to pod %s: %w", podname, err)
 	}
-	h.PortForward(ctx, namespace, podname)
+	return nil
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+)
+
+// Reap removes the expired debug sidecars in a namespace, or all namespaces.
+// With watch set it keeps reaping every interval until the context is cancelled, printing failures instead of returning them
+func Reap(ctx context.Context, f dmskube.Factory, allNamespaces, watch bool, interval time.Duration, audit resources.AuditInfo) error {
+	if watch && interval <= 0 {
+		return fmt.Errorf("invalid --interval %s, must be greater than zero", interval)
+	}
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	if allNamespaces {
+		namespace = ""
+	}
+	auditUser(ctx, h, f, &audit)
+	err = reap(ctx, h, namespace, watch, audit)
+	if !watch {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err = reap(ctx, h, namespace, watch, audit)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+func reap(ctx context.Context, h dmskube.Helper, namespace string, watch bool, audit resources.AuditInfo) error {
+	reaped, warnings, err := h.ReapExpiredSidecars(ctx, namespace, time.Now(), audit)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	for _, w := range reaped {
+		fmt.Printf("Removed expired sidecar from %s %s/%s, expired at %s\n", w.Kind, w.Namespace, w.Name, w.Config.ExpiresAt.Format(time.RFC3339))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reap expired sidecars: %w", err)
+	}
+	if len(reaped) == 0 && !watch {
+		fmt.Println("No expired sidecars found")
+	}
+	return nil
+}
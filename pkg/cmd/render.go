@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils/jwx"
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// RenderOptions represents how the manifests adding the debug sidecar are rendered
+type RenderOptions struct {
+	// Filename is the file with the workload manifest, - reads from stdin
+	Filename string
+	// Format is the output format, see resources.RenderYAML, resources.RenderPatch and resources.RenderKustomize
+	Format string
+	// OutputDir is the directory the kustomize component is written to
+	OutputDir string
+	// PlaintextSecret writes the secret with stringData, ready to be encrypted with sops
+	PlaintextSecret bool
+}
+
+// RenderSidecar adds the debug sidecar to a workload manifest without changing the cluster, and prints the manifests to commit for GitOps
+func RenderSidecar(f dmskube.Factory, kind, name, containername, debugimage string, opts resources.SidecarOptions, renderOpts RenderOptions) error {
+	err := renderSidecar(f, kind, name, containername, debugimage, opts, renderOpts)
+	if err != nil {
+		return fmt.Errorf("failed to render sidecar for %s %s: %w", kind, name, err)
+	}
+	return nil
+}
+
+func renderSidecar(f dmskube.Factory, kind, name, containername, debugimage string, opts resources.SidecarOptions, renderOpts RenderOptions) error {
+	var r io.Reader = os.Stdin
+	if renderOpts.Filename != "-" {
+		file, err := os.Open(renderOpts.Filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	workload, err := resources.ReadWorkloadManifest(r, kind, name)
+	if err != nil {
+		return err
+	}
+	// Without a namespace in the manifest or the namespace flag, the namespace is left to the GitOps tool
+	namespace := workload.GetNamespace()
+	if namespace == "" {
+		flagNamespace, explicit, err := f.Namespace()
+		if err != nil {
+			return err
+		}
+		if explicit {
+			namespace = flagNamespace
+		}
+	}
+	if opts.Audit != nil {
+		opts.Audit.User, err = f.User()
+		if err != nil {
+			opts.Audit.User = "unknown"
+		}
+	}
+
+	token, subject, key, err := jwx.CreateJWTKey()
+	if err != nil {
+		return err
+	}
+	secret := resources.GenerateSecret(namespace, subject, key, name)
+	secret.APIVersion, secret.Kind = "v1", "Secret"
+	if renderOpts.PlaintextSecret {
+		secret.StringData = make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			secret.StringData[k] = string(v)
+		}
+		secret.Data = nil
+	}
+	rendered, err := resources.RenderDebugSidecar(workload, namespace, containername, debugimage, secret.Name, opts)
+	if err != nil {
+		return err
+	}
+
+	switch renderOpts.Format {
+	case resources.RenderKustomize:
+		err = writeKustomizeComponent(renderOpts.OutputDir, workload.GetAPIVersion(), kind, name, rendered, secret)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote kustomize component to %s. Add it to the components of the kustomization of %s %s\n", renderOpts.OutputDir, kind, name)
+		fmt.Printf("Query the API with this auth header when the sidecar is deployed:\nAuthorization: Bearer %s\n", token)
+		return nil
+	}
+	manifests := []interface{}{rendered.Workload.Object, secret}
+	if renderOpts.Format == resources.RenderPatch {
+		manifests[0] = rendered.StrategicMergePatch
+	}
+	if rendered.PodMonitor != nil {
+		manifests = append(manifests, rendered.PodMonitor.Object)
+	}
+	err = printManifests(manifests)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Query the API with this auth header when the sidecar is deployed:\nAuthorization: Bearer %s\n", token)
+	return nil
+}
+
+// printManifests prints the manifests as a multi-document YAML stream
+func printManifests(manifests []interface{}) error {
+	for i, m := range manifests {
+		data, err := yaml.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println("---")
+		}
+		fmt.Print(string(data))
+	}
+	return nil
+}
+
+// writeKustomizeComponent writes a kustomize component with the secret, the PodMonitor and a JSON6902 patch adding the debug sidecar to the workload
+func writeKustomizeComponent(dir, apiVersion, kind, name string, rendered resources.RenderedSidecar, secret corev1.Secret) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	group, version, _ := strings.Cut(apiVersion, "/")
+	files := map[string]interface{}{
+		"patch.yaml":  rendered.JSONPatch,
+		"secret.yaml": secret,
+	}
+	componentResources := []string{"secret.yaml"}
+	if rendered.PodMonitor != nil {
+		files["podmonitor.yaml"] = rendered.PodMonitor.Object
+		componentResources = append(componentResources, "podmonitor.yaml")
+	}
+	files["kustomization.yaml"] = map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1alpha1",
+		"kind":       "Component",
+		"resources":  componentResources,
+		"patches": []interface{}{
+			map[string]interface{}{
+				"path": "patch.yaml",
+				"target": map[string]interface{}{
+					"group":   group,
+					"version": version,
+					"kind":    kind,
+					"name":    name,
+				},
+			},
+		},
+	}
+	for filename, manifest := range files {
+		data, err := yaml.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filepath.Join(dir, filename), data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
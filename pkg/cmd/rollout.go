@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WaitOptions represents how to wait for the rollout of a workload after the debug sidecar is added
+type WaitOptions struct {
+	// Wait for the rollout to finish
+	Wait bool
+	// Timeout of the rollout, zero waits until it is done or fails
+	Timeout time.Duration
+	// NoRollback keeps the debug sidecar when the rollout fails or times out
+	NoRollback bool
+}
+
+// waitForRollout waits for the rollout of a workload the debug sidecar was added to, printing its progress to w.
+// If the rollout fails the sidecar is removed with rollback, unless NoRollback is set, and the rollout error is returned
+func waitForRollout(ctx context.Context, h dmskube.Helper, w io.Writer, kind, namespace, name string, opts WaitOptions, audit *resources.AuditInfo, rollback func() (metav1.Object, error)) error {
+	if !opts.Wait {
+		return nil
+	}
+	err := h.WaitForRollout(ctx, kind, namespace, name, opts.Timeout, func(status string) {
+		fmt.Fprintln(w, status)
+	})
+	if err == nil {
+		return nil
+	}
+	kindName := fmt.Sprintf("%s %s", strings.ToLower(kind), name)
+	err = fmt.Errorf("rollout of %s failed: %w", kindName, err)
+	if opts.NoRollback {
+		fmt.Fprintf(os.Stderr, "Keeping the debug sidecar as --no-rollback is set. Remove it with dmsctl remove %s\n", kindName)
+		return err
+	}
+	workload, rollbackErr := rollback()
+	if rollbackErr != nil {
+		return fmt.Errorf("%w, and rolling back the debug sidecar failed: %v", err, rollbackErr)
+	}
+	fmt.Fprintf(os.Stderr, "Rolled back the debug sidecar of %s and deleted its secret\n", kindName)
+	if audit != nil {
+		recordEvent(ctx, h, kind, workload, dmskube.EventReasonSidecarRemoved, dmskube.AuditMessage("Debug sidecar rolled back after failed rollout", *audit))
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+)
+
+// IssueToken issues a new token for the debug sidecar of a workload on the form [kind/]name, replacing the key in its secret, and prints it
+func IssueToken(ctx context.Context, f dmskube.Factory, workload, output string) error {
+	kind, name, err := parseWorkload(workload)
+	if err != nil {
+		return err
+	}
+	h, namespace, err := newHelper(f)
+	if err != nil {
+		return err
+	}
+	err = preflight(ctx, h, namespace, dmskube.IssueTokenPermissions(kind, name), resources.DryRunNone, fmt.Sprintf("issue a token for the debug sidecar of %s %s", kind, name))
+	if err != nil {
+		return err
+	}
+	secretname, token, err := h.IssueJWKToken(ctx, namespace, kind, name)
+	if err != nil {
+		if errors.IsNotPresent(err) {
+			return err
+		}
+		return fmt.Errorf("failed to issue token for %s %s: %w", kind, name, err)
+	}
+	if output != OutputText {
+		result := TokenResult{Kind: "Deployment", Namespace: namespace, Name: name, SecretName: secretname, Token: token}
+		if kind == kindDaemonSet {
+			result.Kind = "DaemonSet"
+		}
+		return printResult(output, result)
+	}
+	fmt.Printf("Replaced the key in secret %s, tokens issued before are rejected once the pods see the change.\n", secretname)
+	fmt.Printf("Query the API with this auth header:\nAuthorization: Bearer %s\n", token)
+	return nil
+}
@@ -1,16 +1,77 @@
 package errors
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrAlreadyPresent is returned when adding the debug sidecar to a workload it is already added to
+	ErrAlreadyPresent = errors.New("debug sidecar already present")
+	// ErrNotPresent is returned when the debug sidecar is not added to a workload or pod
+	ErrNotPresent = errors.New("debug sidecar not present")
+	// ErrSecretNotFound is returned when the secret of the debug sidecar of a workload is not found
+	ErrSecretNotFound = errors.New("debug sidecar secret not found")
+	// ErrMultipleContainers is returned when a pod has multiple containers and the container to debug is not supplied
+	ErrMultipleContainers = errors.New("multiple containers present, please supply the one you want to debug")
+	// ErrContainerNotFound is returned when the container to debug is not in the pod
+	ErrContainerNotFound = errors.New("container not found")
+	// ErrMissingPermissions is returned when the access reviews before a change deny permissions it needs
+	ErrMissingPermissions = errors.New("missing permissions")
+)
+
+// WorkloadError is an error about the debug sidecar of a workload or pod
+type WorkloadError struct {
+	// Kind of the workload, like Deployment, DaemonSet or Pod. Empty if not known
+	Kind string
+	// Name of the workload
+	Name string
+	Err  error
+}
+
+func (e *WorkloadError) Error() string {
+	if e.Kind == "" {
+		return fmt.Sprintf("%s: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", strings.ToLower(e.Kind), e.Name, e.Err)
+}
+
+func (e *WorkloadError) Unwrap() error {
+	return e.Err
+}
+
+// ContainerError is an error about the container to debug in a pod
+type ContainerError struct {
+	// Container to debug, empty if not supplied
+	Container string
+	// Containers in the pod
+	Containers []string
+	Err        error
+}
+
+func (e *ContainerError) Error() string {
+	if e.Container == "" {
+		return fmt.Sprintf("%v, containers are %s", e.Err, strings.Join(e.Containers, ", "))
+	}
+	return fmt.Sprintf("%v: %s, containers are %s", e.Err, e.Container, strings.Join(e.Containers, ", "))
+}
+
+func (e *ContainerError) Unwrap() error {
+	return e.Err
+}
+
 // IsAlreadyPresent returns true if the error is due to the debug sidecar already being present
 func IsAlreadyPresent(err error) bool {
-	return err.Error() == "debug sidecar already present"
+	return errors.Is(err, ErrAlreadyPresent)
 }
 
 // IsNotPresent returns true if the error is due to the debug sidecar not being present
 func IsNotPresent(err error) bool {
-	return err.Error() == "debug sidecar not present"
+	return errors.Is(err, ErrNotPresent)
 }
 
-// IsNotFound returns true if the error is due to the resource could not be found
+// IsNotFound returns true if the error is due to the secret of the debug sidecar not being found
 func IsNotFound(err error) bool {
-	return err.Error() == "resource not found"
+	return errors.Is(err, ErrSecretNotFound)
 }
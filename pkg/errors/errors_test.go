@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -12,13 +13,28 @@ func TestIsAlreadyPresent(t *testing.T) {
 		want bool
 	}{
 		{
-			name: "Returns true if the error has value 'debug sidecar already present'",
-			err:  fmt.Errorf("debug sidecar already present"),
+			name: "Returns true if the error is ErrAlreadyPresent",
+			err:  ErrAlreadyPresent,
+			want: true,
+		},
+		{
+			name: "Returns true if the error wraps ErrAlreadyPresent",
+			err:  fmt.Errorf("failed to add: %w", &WorkloadError{Kind: "Deployment", Name: "test", Err: ErrAlreadyPresent}),
 			want: true,
 		},
 		{
-			name: "Returns false if the error does not have value 'debug sidecar already present'",
-			err:  fmt.Errorf("debug sidecar not present"),
+			name: "Returns false if the error only has the message of ErrAlreadyPresent",
+			err:  fmt.Errorf("debug sidecar already present"),
+			want: false,
+		},
+		{
+			name: "Returns false if the error is ErrNotPresent",
+			err:  ErrNotPresent,
+			want: false,
+		},
+		{
+			name: "Returns false if the error is nil",
+			err:  nil,
 			want: false,
 		},
 	}
@@ -38,20 +54,30 @@ func TestIsNotPresent(t *testing.T) {
 		want bool
 	}{
 		{
-			name: "Returns true if the error has value 'debug sidecar not present'",
-			err:  fmt.Errorf("debug sidecar not present"),
+			name: "Returns true if the error is ErrNotPresent",
+			err:  ErrNotPresent,
 			want: true,
 		},
 		{
-			name: "Returns false if the error does not have value 'debug sidecar not present'",
-			err:  fmt.Errorf("debug sidecar already present"),
+			name: "Returns true if the error wraps ErrNotPresent",
+			err:  &WorkloadError{Kind: "Pod", Name: "test", Err: ErrNotPresent},
+			want: true,
+		},
+		{
+			name: "Returns false if the error is ErrAlreadyPresent",
+			err:  ErrAlreadyPresent,
+			want: false,
+		},
+		{
+			name: "Returns false if the error is nil",
+			err:  nil,
 			want: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if got := IsNotPresent(tt.err); got != tt.want {
-				t.Errorf("IsAlreadyPresent() = %v, want %v", got, tt.want)
+				t.Errorf("IsNotPresent() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -64,15 +90,20 @@ func TestIsNotFound(t *testing.T) {
 		want bool
 	}{
 		{
-			name: "Returns true if the error has value 'resource not found'",
-			err:  fmt.Errorf("resource not found"),
+			name: "Returns true if the error wraps ErrSecretNotFound",
+			err:  &WorkloadError{Name: "test", Err: ErrSecretNotFound},
 			want: true,
 		},
 		{
-			name: "Returns false if the error does not have value 'resource not found'",
+			name: "Returns false if the error is another error",
 			err:  fmt.Errorf("resource found"),
 			want: false,
 		},
+		{
+			name: "Returns false if the error is nil",
+			err:  nil,
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -82,3 +113,62 @@ func TestIsNotFound(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkloadError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *WorkloadError
+		want string
+	}{
+		{
+			name: "Adds kind and name to the message",
+			err:  &WorkloadError{Kind: "DaemonSet", Name: "test", Err: ErrNotPresent},
+			want: "daemonset test: debug sidecar not present",
+		},
+		{
+			name: "Adds name to the message if kind is not known",
+			err:  &WorkloadError{Name: "test", Err: ErrSecretNotFound},
+			want: "test: debug sidecar secret not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("WorkloadError.Error() = %q, want %q", got, tt.want)
+			}
+			var workloadErr *WorkloadError
+			if !errors.As(fmt.Errorf("wrapped: %w", tt.err), &workloadErr) || workloadErr.Name != tt.err.Name {
+				t.Errorf("errors.As() did not find the WorkloadError in a wrapped error")
+			}
+		})
+	}
+}
+
+func TestContainerError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *ContainerError
+		want string
+	}{
+		{
+			name: "Lists the containers when the container to debug is not supplied",
+			err:  &ContainerError{Containers: []string{"app", "proxy"}, Err: ErrMultipleContainers},
+			want: "multiple containers present, please supply the one you want to debug, containers are app, proxy",
+		},
+		{
+			name: "Adds the container to debug when it is not found",
+			err:  &ContainerError{Container: "missing", Containers: []string{"app", "proxy"}, Err: ErrContainerNotFound},
+			want: "container not found: missing, containers are app, proxy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("ContainerError.Error() = %q, want %q", got, tt.want)
+			}
+			if !errors.Is(tt.err, tt.err.Err) {
+				t.Errorf("errors.Is() = false, want ContainerError to wrap %v", tt.err.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package errors
+
+import (
+	"context"
+	"errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Exit codes of dmsctl, so scripts can tell failures apart
+const (
+	// ExitOK is the exit code when the command succeeds
+	ExitOK = 0
+	// ExitError is the exit code of failures without a more specific exit code, like invalid flags or arguments
+	ExitError = 1
+	// ExitNotFound is the exit code when the workload, pod, container, secret or debug sidecar is not found
+	ExitNotFound = 2
+	// ExitAlreadyPresent is the exit code when the debug sidecar is already added to the workload
+	ExitAlreadyPresent = 3
+	// ExitForbidden is the exit code when the api server rejects the credentials or forbids the request, or would forbid it
+	ExitForbidden = 4
+	// ExitTimeout is the exit code when waiting for the api server or a rollout times out
+	ExitTimeout = 5
+)
+
+// ErrTimeout is returned when waiting for a workload times out
+var ErrTimeout = errors.New("timed out")
+
+// ExitCode returns the exit code of dmsctl failing with err
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case IsAlreadyPresent(err):
+		return ExitAlreadyPresent
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded), wait.Interrupted(err), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return ExitTimeout
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err), errors.Is(err, ErrMissingPermissions):
+		return ExitForbidden
+	case apierrors.IsNotFound(err), IsNotPresent(err), IsNotFound(err), errors.Is(err, ErrContainerNotFound):
+		return ExitNotFound
+	}
+	return ExitError
+}
@@ -0,0 +1,82 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestExitCode(t *testing.T) {
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "Succeeds without error",
+			err:  nil,
+			want: ExitOK,
+		},
+		{
+			name: "Not found for missing workload",
+			err:  fmt.Errorf("failed to get deployment test: %w", apierrors.NewNotFound(deployments, "test")),
+			want: ExitNotFound,
+		},
+		{
+			name: "Not found for missing debug sidecar",
+			err:  &WorkloadError{Kind: "Deployment", Name: "test", Err: ErrNotPresent},
+			want: ExitNotFound,
+		},
+		{
+			name: "Not found for missing container",
+			err:  &ContainerError{Container: "test", Err: ErrContainerNotFound},
+			want: ExitNotFound,
+		},
+		{
+			name: "Already present for added debug sidecar",
+			err:  &WorkloadError{Kind: "Deployment", Name: "test", Err: ErrAlreadyPresent},
+			want: ExitAlreadyPresent,
+		},
+		{
+			name: "Forbidden for forbidden request",
+			err:  fmt.Errorf("failed to remove sidecar: %w", apierrors.NewForbidden(deployments, "test", fmt.Errorf("denied"))),
+			want: ExitForbidden,
+		},
+		{
+			name: "Forbidden for rejected credentials",
+			err:  apierrors.NewUnauthorized("expired token"),
+			want: ExitForbidden,
+		},
+		{
+			name: "Forbidden for missing permissions",
+			err:  fmt.Errorf("%w to add the debug sidecar to deployment test", ErrMissingPermissions),
+			want: ExitForbidden,
+		},
+		{
+			name: "Timeout for rollout timing out",
+			err:  fmt.Errorf("rollout of deployment test failed: %w after 5m0s", ErrTimeout),
+			want: ExitTimeout,
+		},
+		{
+			name: "Timeout for context deadline",
+			err:  context.DeadlineExceeded,
+			want: ExitTimeout,
+		},
+		{
+			name: "Error for other failures",
+			err:  &ContainerError{Err: ErrMultipleContainers},
+			want: ExitError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
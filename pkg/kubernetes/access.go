@@ -0,0 +1,149 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Permission is a verb on a resource in a namespace dmsctl needs to be allowed
+type Permission struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	// Name of the resource, empty for all resources like when creating or listing
+	Name string `json:"name,omitempty"`
+}
+
+// ResourceString returns the resource like kubectl auth can-i, as resource.group/subresource
+func (p Permission) ResourceString() string {
+	var b strings.Builder
+	b.WriteString(p.Resource)
+	if p.Group != "" {
+		b.WriteString("." + p.Group)
+	}
+	if p.Subresource != "" {
+		b.WriteString("/" + p.Subresource)
+	}
+	return b.String()
+}
+
+// AccessReview is the result of reviewing if the client is allowed a permission
+type AccessReview struct {
+	Permission `json:",inline"`
+	Allowed    bool   `json:"allowed"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// workloadResource returns the resource of a workload kind
+func workloadResource(kind string) string {
+	if strings.EqualFold(kind, "DaemonSet") {
+		return "daemonsets"
+	}
+	return "deployments"
+}
+
+// workloadPermissions returns the permissions to read and patch the pod template of a workload
+func workloadPermissions(kind, name string) []Permission {
+	return []Permission{
+		{Verb: "get", Group: "apps", Resource: workloadResource(kind), Name: name},
+		{Verb: "patch", Group: "apps", Resource: workloadResource(kind), Name: name},
+	}
+}
+
+// podMonitorPermission returns the permission to verb the prometheus-operator PodMonitors, or the one named
+func podMonitorPermission(verb, name string) Permission {
+	return Permission{Verb: verb, Group: resources.PodMonitorResource.Group, Resource: resources.PodMonitorResource.Resource, Name: name}
+}
+
+// AddSidecarPermissions returns the permissions needed to add the debug sidecar with opts to a workload, clean up if that fails
+// and port-forward to its pods. The workload is patched, so patch is needed instead of update. In dry run nothing else is changed.
+// wait adds the permissions to watch the rollout, rolling back the sidecar if it fails needs no others
+func AddSidecarPermissions(kind, name string, opts resources.SidecarOptions, wait bool) []Permission {
+	permissions := workloadPermissions(kind, name)
+	if resources.IsDryRun(opts.DryRun) {
+		return permissions
+	}
+	permissions = append(permissions,
+		Permission{Verb: "list", Resource: "secrets"},
+		Permission{Verb: "create", Resource: "secrets"},
+		Permission{Verb: "delete", Resource: "secrets"},
+	)
+	if opts.PodMonitorName != "" {
+		permissions = append(permissions, podMonitorPermission("create", ""), podMonitorPermission("delete", opts.PodMonitorName))
+	}
+	if opts.Audit != nil {
+		permissions = append(permissions, Permission{Verb: "create", Resource: "events"})
+	}
+	if wait {
+		permissions = append(permissions,
+			Permission{Verb: "list", Group: "apps", Resource: workloadResource(kind)},
+			Permission{Verb: "watch", Group: "apps", Resource: workloadResource(kind)},
+		)
+	}
+	return append(permissions, Permission{Verb: "create", Resource: "pods", Subresource: "portforward"})
+}
+
+// RemoveSidecarPermissions returns the permissions needed to remove the debug sidecar added with ddConfig from a workload,
+// delete its secret, collection rules configmap and PodMonitor, and record an event. In dry run nothing else is changed
+func RemoveSidecarPermissions(kind, name string, ddConfig resources.DDConfig, dryRun string) []Permission {
+	permissions := workloadPermissions(kind, name)
+	if resources.IsDryRun(dryRun) {
+		return permissions
+	}
+	permissions = append(permissions, Permission{Verb: "delete", Resource: "secrets", Name: ddConfig.SecretName})
+	if ddConfig.RulesConfigMap != "" {
+		permissions = append(permissions, Permission{Verb: "delete", Resource: "configmaps", Name: ddConfig.RulesConfigMap})
+	}
+	if ddConfig.PodMonitor != "" {
+		permissions = append(permissions, podMonitorPermission("delete", ddConfig.PodMonitor))
+	}
+	return append(permissions, Permission{Verb: "create", Resource: "events"})
+}
+
+// IssueTokenPermissions returns the permissions needed to issue a new token for the debug sidecar of a workload
+func IssueTokenPermissions(kind, name string) []Permission {
+	return []Permission{
+		{Verb: "get", Group: "apps", Resource: workloadResource(kind), Name: name},
+		{Verb: "patch", Resource: "secrets"},
+	}
+}
+
+// ReviewAccess reviews if the client is allowed the permissions in a namespace with a SelfSubjectAccessReview each
+func (h *Helper) ReviewAccess(ctx context.Context, namespace string, permissions []Permission) ([]AccessReview, error) {
+	reviews := make([]AccessReview, 0, len(permissions))
+	for _, p := range permissions {
+		review, err := h.Client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   namespace,
+					Verb:        p.Verb,
+					Group:       p.Group,
+					Resource:    p.Resource,
+					Subresource: p.Subresource,
+					Name:        p.Name,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, AccessReview{Permission: p, Allowed: review.Status.Allowed, Reason: review.Status.Reason})
+	}
+	return reviews, nil
+}
+
+// MissingPermissions returns the reviews of the permissions that are not allowed
+func MissingPermissions(reviews []AccessReview) []AccessReview {
+	var missing []AccessReview
+	for _, r := range reviews {
+		if !r.Allowed {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
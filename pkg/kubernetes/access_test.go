@@ -0,0 +1,221 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestHelper_ReviewAccess(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		denied      string
+		reviewErr   error
+		wantMissing []Permission
+		wantErr     bool
+	}{
+		{
+			name: "All permissions allowed",
+			kind: "Deployment",
+		},
+		{
+			name:   "Patching daemonset denied",
+			kind:   "DaemonSet",
+			denied: "patch daemonsets",
+			wantMissing: []Permission{
+				{Verb: "patch", Group: "apps", Resource: "daemonsets", Name: "test"},
+			},
+		},
+		{
+			name:   "Port forward denied",
+			kind:   "Deployment",
+			denied: "create pods/portforward",
+			wantMissing: []Permission{
+				{Verb: "create", Resource: "pods", Subresource: "portforward"},
+			},
+		},
+		{
+			name:      "Access review failing",
+			kind:      "Deployment",
+			reviewErr: fmt.Errorf("connection refused"),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testclient.NewSimpleClientset()
+			var namespaces []string
+			c.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if tt.reviewErr != nil {
+					return true, nil, tt.reviewErr
+				}
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				attributes := review.Spec.ResourceAttributes
+				namespaces = append(namespaces, attributes.Namespace)
+				resource := attributes.Resource
+				if attributes.Subresource != "" {
+					resource += "/" + attributes.Subresource
+				}
+				review.Status.Allowed = attributes.Verb+" "+resource != tt.denied
+				if !review.Status.Allowed {
+					review.Status.Reason = "denied by test"
+				}
+				return true, review, nil
+			})
+			h := &Helper{Client: c}
+			permissions := AddSidecarPermissions(tt.kind, "test", resources.SidecarOptions{}, false)
+			reviews, err := h.ReviewAccess(context.Background(), "default", permissions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Helper.ReviewAccess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(reviews) != len(permissions) {
+				t.Errorf("Helper.ReviewAccess() returned %d reviews, want %d", len(reviews), len(permissions))
+			}
+			for _, ns := range namespaces {
+				if ns != "default" {
+					t.Errorf("Helper.ReviewAccess() reviewed access in namespace %s, want default", ns)
+				}
+			}
+			var missing []Permission
+			for _, r := range MissingPermissions(reviews) {
+				if r.Reason != "denied by test" {
+					t.Errorf("Missing permission %v has reason %q, want the reason of the review", r.Permission, r.Reason)
+				}
+				missing = append(missing, r.Permission)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("MissingPermissions() = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestPermission_ResourceString(t *testing.T) {
+	tests := []struct {
+		permission Permission
+		want       string
+	}{
+		{Permission{Verb: "list", Resource: "secrets"}, "secrets"},
+		{Permission{Verb: "patch", Group: "apps", Resource: "deployments"}, "deployments.apps"},
+		{Permission{Verb: "create", Resource: "pods", Subresource: "portforward"}, "pods/portforward"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.permission.ResourceString(); got != tt.want {
+				t.Errorf("Permission.ResourceString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// permissionStrings returns the permissions as "verb resource name", to compare them in tests
+func permissionStrings(permissions []Permission) []string {
+	var s []string
+	for _, p := range permissions {
+		s = append(s, strings.TrimSpace(p.Verb+" "+p.ResourceString()+" "+p.Name))
+	}
+	return s
+}
+
+func TestAddSidecarPermissions(t *testing.T) {
+	tests := []struct {
+		name string
+		kind string
+		opts resources.SidecarOptions
+		wait bool
+		want []string
+	}{
+		{
+			name: "Default options",
+			kind: "Deployment",
+			want: []string{
+				"get deployments.apps test", "patch deployments.apps test",
+				"list secrets", "create secrets", "delete secrets",
+				"create pods/portforward",
+			},
+		},
+		{
+			name: "PodMonitor, audit and wait",
+			kind: "DaemonSet",
+			opts: resources.SidecarOptions{PodMonitorName: "test-dd-monitor", Audit: &resources.AuditInfo{}},
+			wait: true,
+			want: []string{
+				"get daemonsets.apps test", "patch daemonsets.apps test",
+				"list secrets", "create secrets", "delete secrets",
+				"create podmonitors.monitoring.coreos.com", "delete podmonitors.monitoring.coreos.com test-dd-monitor",
+				"create events",
+				"list daemonsets.apps", "watch daemonsets.apps",
+				"create pods/portforward",
+			},
+		},
+		{
+			name: "Server dry run only patches the workload",
+			kind: "Deployment",
+			opts: resources.SidecarOptions{DryRun: resources.DryRunServer, PodMonitorName: "test-dd-monitor", Audit: &resources.AuditInfo{}},
+			wait: true,
+			want: []string{"get deployments.apps test", "patch deployments.apps test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := permissionStrings(AddSidecarPermissions(tt.kind, "test", tt.opts, tt.wait)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AddSidecarPermissions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveSidecarPermissions(t *testing.T) {
+	tests := []struct {
+		name     string
+		ddConfig resources.DDConfig
+		dryRun   string
+		want     []string
+	}{
+		{
+			name:     "Sidecar with secret only",
+			ddConfig: resources.DDConfig{SecretName: "dd-monitor-apikey-abcde"},
+			want: []string{
+				"get deployments.apps test", "patch deployments.apps test",
+				"delete secrets dd-monitor-apikey-abcde",
+				"create events",
+			},
+		},
+		{
+			name:     "Sidecar with collection rules and PodMonitor",
+			ddConfig: resources.DDConfig{SecretName: "dd-monitor-apikey-abcde", RulesConfigMap: "test-dd-rules", PodMonitor: "test-dd-monitor"},
+			want: []string{
+				"get deployments.apps test", "patch deployments.apps test",
+				"delete secrets dd-monitor-apikey-abcde",
+				"delete configmaps test-dd-rules",
+				"delete podmonitors.monitoring.coreos.com test-dd-monitor",
+				"create events",
+			},
+		},
+		{
+			name:     "Server dry run only patches the workload",
+			ddConfig: resources.DDConfig{SecretName: "dd-monitor-apikey-abcde", RulesConfigMap: "test-dd-rules"},
+			dryRun:   resources.DryRunServer,
+			want:     []string{"get deployments.apps test", "patch deployments.apps test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := permissionStrings(RemoveSidecarPermissions("Deployment", "test", tt.ddConfig, tt.dryRun)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RemoveSidecarPermissions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ApplyCollectionRulesDeployment stores collection rules in a configmap and mounts it into the debug sidecar of a Deployment
+func (h *Helper) ApplyCollectionRulesDeployment(ctx context.Context, namespace, deploymentname string, rules resources.CollectionRulesFile) (*appsv1.Deployment, string, error) {
+	d, err := h.Client.AppsV1().Deployments(namespace).Get(ctx, deploymentname, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	cm, created, err := h.applyRulesConfigMap(ctx, namespace, deploymentname, d.Spec.Template, rules, resources.WorkloadOwnerReference("Deployment", d))
+	if err != nil {
+		return nil, "", workloadError("Deployment", deploymentname, err)
+	}
+	dep, err := h.patchDeployment(ctx, namespace, deploymentname, resources.DryRunNone, func(d *appsv1.Deployment) error {
+		template, err := resources.AddCollectionRulesPodTemplate(d.Spec.Template, cm)
+		if err != nil {
+			return err
+		}
+		d.Spec.Template = template
+		return nil
+	})
+	if err != nil {
+		if created {
+			h.RemoveRulesConfigMap(ctx, namespace, cm)
+		}
+		return nil, "", err
+	}
+	return dep, cm, nil
+}
+
+// ApplyCollectionRulesDaemonSet stores collection rules in a configmap and mounts it into the debug sidecar of a DaemonSet
+func (h *Helper) ApplyCollectionRulesDaemonSet(ctx context.Context, namespace, daemonsetname string, rules resources.CollectionRulesFile) (*appsv1.DaemonSet, string, error) {
+	d, err := h.Client.AppsV1().DaemonSets(namespace).Get(ctx, daemonsetname, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	cm, created, err := h.applyRulesConfigMap(ctx, namespace, daemonsetname, d.Spec.Template, rules, resources.WorkloadOwnerReference("DaemonSet", d))
+	if err != nil {
+		return nil, "", workloadError("DaemonSet", daemonsetname, err)
+	}
+	ds, err := h.patchDaemonSet(ctx, namespace, daemonsetname, resources.DryRunNone, func(d *appsv1.DaemonSet) error {
+		template, err := resources.AddCollectionRulesPodTemplate(d.Spec.Template, cm)
+		if err != nil {
+			return err
+		}
+		d.Spec.Template = template
+		return nil
+	})
+	if err != nil {
+		if created {
+			h.RemoveRulesConfigMap(ctx, namespace, cm)
+		}
+		return nil, "", err
+	}
+	return ds, cm, nil
+}
+
+// applyRulesConfigMap creates or patches the collection rules configmap of the debug sidecar in a pod template, returning its name and if it was created.
+// The configmap is owned by the workload, also when patched, so configmaps created without the owner reference get it
+func (h *Helper) applyRulesConfigMap(ctx context.Context, namespace, owner string, template corev1.PodTemplateSpec, rules resources.CollectionRulesFile, ownerRef metav1.OwnerReference) (string, bool, error) {
+	ddConfig, err := resources.DDConfigFromPodTemplate(template)
+	if err != nil {
+		return "", false, err
+	}
+	cm, err := resources.GenerateRulesConfigMap(namespace, ddConfig.RulesConfigMap, owner, rules, ownerRef)
+	if err != nil {
+		return "", false, err
+	}
+	if ddConfig.RulesConfigMap != "" {
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"ownerReferences": cm.OwnerReferences},
+			"data":     cm.Data,
+		})
+		if err != nil {
+			return "", false, err
+		}
+		_, err = h.Client.CoreV1().ConfigMaps(namespace).Patch(ctx, cm.Name, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+		return cm.Name, false, err
+	}
+	_, err = h.Client.CoreV1().ConfigMaps(namespace).Create(ctx, &cm, metav1.CreateOptions{FieldManager: FieldManager})
+	if err != nil {
+		return "", false, err
+	}
+	return cm.Name, true, nil
+}
+
+// RemoveRulesConfigMap removes the collection rules configmap
+func (h *Helper) RemoveRulesConfigMap(ctx context.Context, namespace, configmapname string) error {
+	if configmapname == "" {
+		return nil
+	}
+	return h.Client.CoreV1().ConfigMaps(namespace).Delete(ctx, configmapname, metav1.DeleteOptions{})
+}
+
+// ListCollectionRules returns the state of all collection rules from dotnet-monitor in a pod
+func (h *Helper) ListCollectionRules(ctx context.Context, namespace, podname, token string) (map[string]resources.CollectionRuleState, error) {
+	rules := map[string]resources.CollectionRuleState{}
+	err := h.WithTunnel(ctx, namespace, podname, func(baseURL string) error {
+		return monitorGet(ctx, baseURL, token, "/collectionrules", &rules)
+	})
+	return rules, err
+}
+
+// GetCollectionRuleDetails returns the detailed state of the named collection rules from dotnet-monitor in a pod.
+// All rules are returned if no names are given.
+func (h *Helper) GetCollectionRuleDetails(ctx context.Context, namespace, podname, token string, rulenames ...string) (map[string]resources.CollectionRuleDetails, error) {
+	details := map[string]resources.CollectionRuleDetails{}
+	err := h.WithTunnel(ctx, namespace, podname, func(baseURL string) error {
+		if len(rulenames) == 0 {
+			rules := map[string]resources.CollectionRuleState{}
+			err := monitorGet(ctx, baseURL, token, "/collectionrules", &rules)
+			if err != nil {
+				return err
+			}
+			for name := range rules {
+				rulenames = append(rulenames, name)
+			}
+		}
+		for _, name := range rulenames {
+			d := resources.CollectionRuleDetails{}
+			err := monitorGet(ctx, baseURL, token, fmt.Sprintf("/collectionrules/%s", url.PathEscape(name)), &d)
+			if err != nil {
+				return err
+			}
+			details[name] = d
+		}
+		return nil
+	})
+	return details, err
+}
@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHelper_ApplyCollectionRulesDeployment(t *testing.T) {
+	rules := resources.CollectionRulesFile{
+		CollectionRules: map[string]resources.CollectionRule{
+			"Startup": {
+				Trigger: resources.CollectionRuleTrigger{Type: "Startup"},
+				Actions: []resources.CollectionRuleAction{{Type: "Execute"}},
+			},
+		},
+	}
+	tests := []struct {
+		name           string
+		deploymentname string
+		initfile       string
+		wantErr        bool
+	}{
+		{
+			name:           "Applies rules to deployment with debug sidecar",
+			deploymentname: "test",
+			initfile:       "testdata/deployment/remove.yaml",
+			wantErr:        false,
+		},
+		{
+			name:           "Debug sidecar not present",
+			deploymentname: "test",
+			initfile:       "testdata/deployment/add.yaml",
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var deployment appsv1.Deployment
+			err := getObjectFromFile(tt.initfile, &deployment)
+			if err != nil {
+				t.Errorf("getObjectFromFile() error = %v", err)
+				return
+			}
+			c := testclient.NewSimpleClientset(&deployment)
+			h := &Helper{
+				Client: c,
+			}
+			actual, cmName, err := h.ApplyCollectionRulesDeployment(ctx, "test", tt.deploymentname, rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Helper.ApplyCollectionRulesDeployment() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			cm, err := c.CoreV1().ConfigMaps("test").Get(ctx, cmName, metav1.GetOptions{})
+			if err != nil {
+				t.Errorf("Expected configmap %s to be created: %v", cmName, err)
+				return
+			}
+			if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].Kind != "Deployment" || cm.OwnerReferences[0].Name != tt.deploymentname {
+				t.Errorf("ConfigMap owner references = %v, want the deployment", cm.OwnerReferences)
+			}
+			ddConfig, err := resources.DDConfigFromPodTemplate(actual.Spec.Template)
+			if err != nil {
+				t.Errorf("DDConfigFromPodTemplate() error = %v", err)
+				return
+			}
+			if ddConfig.RulesConfigMap != cmName {
+				t.Errorf("DDConfig.RulesConfigMap = %s, want %s", ddConfig.RulesConfigMap, cmName)
+			}
+			// Applying again updates the existing configmap
+			_, again, err := h.ApplyCollectionRulesDeployment(ctx, "test", tt.deploymentname, rules)
+			if err != nil || again != cmName {
+				t.Errorf("Expected second apply to reuse configmap %s, got %s error = %v", cmName, again, err)
+			}
+		})
+	}
+}
@@ -3,54 +3,85 @@ package kubernetes
 import (
 	"context"
 
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
 )
 
 // AddDebugSidecarDaemonSet adds a debug sidecar to a daemonset
-func (h *Helper) AddDebugSidecarDaemonSet(ctx context.Context, namespace, daemonsetname, containerToDebug, debugimage string) (*appsv1.DaemonSet, string, error) {
+func (h *Helper) AddDebugSidecarDaemonSet(ctx context.Context, namespace, daemonsetname, containerToDebug, debugimage string, opts resources.SidecarOptions) (*appsv1.DaemonSet, string, error) {
 	d, err := h.Client.AppsV1().DaemonSets(namespace).Get(ctx, daemonsetname, metav1.GetOptions{})
 	if err != nil {
 		return nil, "", err
 	}
-	sn, token, err := h.CreateJWKSecret(ctx, namespace, daemonsetname)
-	if err != nil {
-		return nil, "", err
+	// Checked before creating the sidecar resources, as the secret of the sidecar already added is reused
+	if resources.HasDebugSidecar(d.Spec.Template.Annotations) {
+		return nil, "", workloadError("DaemonSet", daemonsetname, dmserrors.ErrAlreadyPresent)
 	}
-	template, err := resources.AddDebugContainerPodTemplate(d.Spec.Template, namespace, containerToDebug, debugimage, sn)
+	created, err := h.createSidecarResources(ctx, namespace, daemonsetname, resources.WorkloadOwnerReference("DaemonSet", d), d.Spec.Selector, opts)
 	if err != nil {
-		h.RemoveJWKSecret(ctx, namespace, sn)
 		return nil, "", err
 	}
-	d.Spec.Template = template
-	ds, err := h.Client.AppsV1().DaemonSets(namespace).Update(ctx, d, metav1.UpdateOptions{})
+	ds, err := h.patchDaemonSet(ctx, namespace, daemonsetname, opts.DryRun, func(d *appsv1.DaemonSet) error {
+		template, err := resources.AddDebugContainerPodTemplate(d.Spec.Template, namespace, containerToDebug, debugimage, created.secretName, opts)
+		if err != nil {
+			return workloadError("DaemonSet", daemonsetname, err)
+		}
+		err = resources.SnapshotPodTemplate(&d.ObjectMeta, d.Spec.Template)
+		if err != nil {
+			return err
+		}
+		d.Spec.Template = template
+		return nil
+	})
 	if err != nil {
-		h.RemoveJWKSecret(ctx, namespace, sn)
+		h.cleanupSidecarResources(ctx, namespace, created)
 		return nil, "", err
 	}
-	return ds, token, nil
+	if !resources.IsDryRun(opts.DryRun) {
+		// The template returned is the one written by the api server, which remove --restore-exact compares with
+		_, err = h.patchDaemonSet(ctx, namespace, daemonsetname, opts.DryRun, func(d *appsv1.DaemonSet) error {
+			return resources.RecordAddedPodTemplate(&d.ObjectMeta, ds.Spec.Template)
+		})
+		if err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to record the pod template with the sidecar added", "kind", "DaemonSet", "namespace", namespace, "name", daemonsetname)
+		}
+	}
+	return ds, created.token, nil
 }
 
 // RemoveDebugSidecarDaemonSet removes the debug sidecar from a daemonset
-func (h *Helper) RemoveDebugSidecarDaemonSet(ctx context.Context, namespace, deploymentname string) (*appsv1.DaemonSet, error) {
-	d, err := h.Client.AppsV1().DaemonSets(namespace).Get(ctx, deploymentname, metav1.GetOptions{})
-	if err != nil {
-		return nil, err
-	}
-	ddConfig, err := resources.DDConfigFromPodTemplate(d.Spec.Template)
-	if err != nil {
-		return nil, err
-	}
-	err = h.RemoveJWKSecret(ctx, namespace, ddConfig.SecretName)
+func (h *Helper) RemoveDebugSidecarDaemonSet(ctx context.Context, namespace, daemonsetname string, opts resources.RemoveOptions) (*appsv1.DaemonSet, resources.RestoredPodTemplate, error) {
+	var (
+		ddConfig resources.DDConfig
+		restored resources.RestoredPodTemplate
+	)
+	ds, err := h.patchDaemonSet(ctx, namespace, daemonsetname, opts.DryRun, func(d *appsv1.DaemonSet) error {
+		var err error
+		ddConfig, err = resources.DDConfigFromPodTemplate(d.Spec.Template)
+		if err != nil {
+			return workloadError("DaemonSet", daemonsetname, err)
+		}
+		restored, err = resources.RestorePodTemplate(&d.ObjectMeta, d.Spec.Template, namespace, ddConfig.ContainerToDebug, opts.RestoreExact)
+		if err != nil {
+			return err
+		}
+		restored.Config = ddConfig
+		d.Spec.Template = restored.Template
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, resources.RestoredPodTemplate{}, err
 	}
-	d.Spec.Template, err = resources.RemoveDebugContainerPodTemplate(d.Spec.Template, namespace, ddConfig.ContainerToDebug)
-	if err != nil {
-		return nil, err
+	if !resources.IsDryRun(opts.DryRun) {
+		err = h.removeSidecarResources(ctx, namespace, ddConfig)
+		if err != nil {
+			return nil, resources.RestoredPodTemplate{}, err
+		}
 	}
-	return h.Client.AppsV1().DaemonSets(namespace).Update(ctx, d, metav1.UpdateOptions{})
+	return ds, restored, nil
 }
 
 // ListDaemonsetsInNamespace returns list of deployments in a namespace
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -83,7 +84,7 @@ func TestHelper_AddDebugSidecarDaemonSet(t *testing.T) {
 				Client: c,
 			}
 			var expected *appsv1.DaemonSet
-			actual, gotToken, err := h.AddDebugSidecarDaemonSet(tt.args.ctx, tt.args.namespace, tt.args.daemonsetname, tt.args.containerToDebug, tt.args.debugimage)
+			actual, gotToken, err := h.AddDebugSidecarDaemonSet(ctx, tt.args.namespace, tt.args.daemonsetname, tt.args.containerToDebug, tt.args.debugimage, resources.SidecarOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Helper.AddDebugSidecarDaemonSet() error = %v, wantErr %v", err, tt.wantErr)
 				return
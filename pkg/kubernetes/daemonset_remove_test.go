@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -74,7 +75,7 @@ func TestHelper_RemoveDebugSidecarDaemonSet(t *testing.T) {
 				Client: c,
 			}
 			var expected *appsv1.DaemonSet
-			actual, err := h.RemoveDebugSidecarDaemonSet(tt.args.ctx, tt.args.namespace, tt.args.deploymentname)
+			actual, _, err := h.RemoveDebugSidecarDaemonSet(ctx, tt.args.namespace, tt.args.deploymentname, resources.RemoveOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Helper.RemoveDebugSidecarDaemonSet() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -2,64 +2,108 @@ package kubernetes
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"strings"
 
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
 )
 
 // Helper struct for kubernetes helper methods for managing debug sidecars
 type Helper struct {
 	Client kubernetes.Interface
+	// Dynamic is used for resources without a typed client, like PodMonitors
+	Dynamic dynamic.Interface
+	// Config is the REST config the clients were created with, used to port-forward to pods
+	Config *rest.Config
+}
+
+// workloadError adds the kind and name of a workload to errors about the debug sidecar being present or not
+func workloadError(kind, name string, err error) error {
+	if dmserrors.IsAlreadyPresent(err) || dmserrors.IsNotPresent(err) {
+		return &dmserrors.WorkloadError{Kind: kind, Name: name, Err: err}
+	}
+	return err
 }
 
 // AddDebugSidecarDeployment adds debug sidecar to a Deployment
-func (h *Helper) AddDebugSidecarDeployment(ctx context.Context, namespace, deploymentname, containerToDebug, debugimage string) (*appsv1.Deployment, string, error) {
+func (h *Helper) AddDebugSidecarDeployment(ctx context.Context, namespace, deploymentname, containerToDebug, debugimage string, opts resources.SidecarOptions) (*appsv1.Deployment, string, error) {
 	d, err := h.Client.AppsV1().Deployments(namespace).Get(ctx, deploymentname, metav1.GetOptions{})
 	if err != nil {
 		return nil, "", err
 	}
-	sn, token, err := h.CreateJWKSecret(ctx, namespace, deploymentname)
-	if err != nil {
-		return nil, "", err
+	// Checked before creating the sidecar resources, as the secret of the sidecar already added is reused
+	if resources.HasDebugSidecar(d.Spec.Template.Annotations) {
+		return nil, "", workloadError("Deployment", deploymentname, dmserrors.ErrAlreadyPresent)
 	}
-	template, err := resources.AddDebugContainerPodTemplate(d.Spec.Template, namespace, containerToDebug, debugimage, sn)
+	created, err := h.createSidecarResources(ctx, namespace, deploymentname, resources.WorkloadOwnerReference("Deployment", d), d.Spec.Selector, opts)
 	if err != nil {
-		h.RemoveJWKSecret(ctx, namespace, sn)
 		return nil, "", err
 	}
-	d.Spec.Template = template
-	dep, err := h.Client.AppsV1().Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{})
+	dep, err := h.patchDeployment(ctx, namespace, deploymentname, opts.DryRun, func(d *appsv1.Deployment) error {
+		template, err := resources.AddDebugContainerPodTemplate(d.Spec.Template, namespace, containerToDebug, debugimage, created.secretName, opts)
+		if err != nil {
+			return workloadError("Deployment", deploymentname, err)
+		}
+		err = resources.SnapshotPodTemplate(&d.ObjectMeta, d.Spec.Template)
+		if err != nil {
+			return err
+		}
+		d.Spec.Template = template
+		return nil
+	})
 	if err != nil {
-		h.RemoveJWKSecret(ctx, namespace, sn)
+		h.cleanupSidecarResources(ctx, namespace, created)
 		return nil, "", err
 	}
-	return dep, token, nil
+	if !resources.IsDryRun(opts.DryRun) {
+		// The template returned is the one written by the api server, which remove --restore-exact compares with
+		_, err = h.patchDeployment(ctx, namespace, deploymentname, opts.DryRun, func(d *appsv1.Deployment) error {
+			return resources.RecordAddedPodTemplate(&d.ObjectMeta, dep.Spec.Template)
+		})
+		if err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to record the pod template with the sidecar added", "kind", "Deployment", "namespace", namespace, "name", deploymentname)
+		}
+	}
+	return dep, created.token, nil
 }
 
 // RemoveDebugSidecarDeployment removes debug sidecar from a Deployment
-func (h *Helper) RemoveDebugSidecarDeployment(ctx context.Context, namespace, deploymentname string) (*appsv1.Deployment, error) {
-	d, err := h.Client.AppsV1().Deployments(namespace).Get(ctx, deploymentname, metav1.GetOptions{})
+func (h *Helper) RemoveDebugSidecarDeployment(ctx context.Context, namespace, deploymentname string, opts resources.RemoveOptions) (*appsv1.Deployment, resources.RestoredPodTemplate, error) {
+	var (
+		ddConfig resources.DDConfig
+		restored resources.RestoredPodTemplate
+	)
+	dep, err := h.patchDeployment(ctx, namespace, deploymentname, opts.DryRun, func(d *appsv1.Deployment) error {
+		var err error
+		ddConfig, err = resources.DDConfigFromPodTemplate(d.Spec.Template)
+		if err != nil {
+			return workloadError("Deployment", deploymentname, err)
+		}
+		restored, err = resources.RestorePodTemplate(&d.ObjectMeta, d.Spec.Template, namespace, ddConfig.ContainerToDebug, opts.RestoreExact)
+		if err != nil {
+			return err
+		}
+		restored.Config = ddConfig
+		d.Spec.Template = restored.Template
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, resources.RestoredPodTemplate{}, err
 	}
-	ddConfig, err := resources.DDConfigFromPodTemplate(d.Spec.Template)
-	if err != nil {
-		return nil, err
-	}
-	d.Spec.Template, err = resources.RemoveDebugContainerPodTemplate(d.Spec.Template, namespace, ddConfig.ContainerToDebug)
-	if err != nil {
-		return nil, err
+	if !resources.IsDryRun(opts.DryRun) {
+		err = h.removeSidecarResources(ctx, namespace, ddConfig)
+		if err != nil {
+			return nil, resources.RestoredPodTemplate{}, err
+		}
 	}
-	err = h.RemoveJWKSecret(ctx, namespace, ddConfig.SecretName)
-	if err != nil {
-		return nil, err
-	}
-	return h.Client.AppsV1().Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{})
+	return dep, restored, nil
 }
 
 // GetDDApplyInfo returns the debug sidecar apply info for a deployment from kubernetes
@@ -68,7 +112,29 @@ func (h *Helper) GetDDApplyInfo(ctx context.Context, namespace, deploymentname s
 	if err != nil {
 		return resources.DDConfig{}, err
 	}
-	return resources.DDConfigFromPodTemplate(d.Spec.Template)
+	ddConfig, err := resources.DDConfigFromPodTemplate(d.Spec.Template)
+	return ddConfig, workloadError("Deployment", deploymentname, err)
+}
+
+// GetWorkloadDDConfig returns the debug sidecar apply info for a Deployment or DaemonSet from kubernetes
+func (h *Helper) GetWorkloadDDConfig(ctx context.Context, namespace, kind, name string) (resources.DDConfig, error) {
+	var template corev1.PodTemplateSpec
+	switch strings.ToLower(kind) {
+	case "daemonset":
+		d, err := h.Client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return resources.DDConfig{}, err
+		}
+		kind, template = "DaemonSet", d.Spec.Template
+	default:
+		d, err := h.Client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return resources.DDConfig{}, err
+		}
+		kind, template = "Deployment", d.Spec.Template
+	}
+	ddConfig, err := resources.DDConfigFromPodTemplate(template)
+	return ddConfig, workloadError(kind, name, err)
 }
 
 // GetDDPodApplyInfo returns the debug sidecar apply info for a pod from kubernetes
@@ -77,15 +143,8 @@ func (h *Helper) GetDDPodApplyInfo(ctx context.Context, namespace, podname strin
 	if err != nil {
 		return resources.DDConfig{}, err
 	}
-	if p.ObjectMeta.Annotations["dev.local/dd-added"] != "true" {
-		return resources.DDConfig{}, fmt.Errorf("debug sidecar not present")
-	}
-	appliedConfig := resources.DDConfig{}
-	err = json.Unmarshal([]byte(p.ObjectMeta.Annotations["dev.local/dd-apply"]), &appliedConfig)
-	if err != nil {
-		return resources.DDConfig{}, err
-	}
-	return appliedConfig, nil
+	ddConfig, err := resources.DDConfigFromAnnotations(p.ObjectMeta.Annotations)
+	return ddConfig, workloadError("Pod", podname, err)
 }
 
 // ListPodsInNamespace returns list of pods in a namespace
@@ -9,13 +9,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	"github.com/ghodss/yaml"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/sergi/go-diff/diffmatchpatch"
 	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/informers"
 	testclient "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
@@ -37,6 +41,7 @@ func TestHelper_AddDebugSidecarDeployment(t *testing.T) {
 		deploymentname   string
 		containerToDebug string
 		debugimage       string
+		opts             resources.SidecarOptions
 	}
 	tests := []struct {
 		name       string
@@ -57,6 +62,23 @@ func TestHelper_AddDebugSidecarDeployment(t *testing.T) {
 			goldenfile: "testdata/deployment/add.golden",
 			wantErr:    false,
 		},
+		{
+			name: "Returns deployment with metrics exposed",
+			args: args{
+				namespace:        "test",
+				deploymentname:   "test",
+				containerToDebug: "test",
+				debugimage:       "test",
+				opts: resources.SidecarOptions{
+					Metrics:            true,
+					MetricsAnnotations: true,
+					PodMonitorName:     resources.PodMonitorName("test"),
+				},
+			},
+			initfile:   "testdata/deployment/add.yaml",
+			goldenfile: "testdata/deployment/add-metrics.golden",
+			wantErr:    false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -83,10 +105,11 @@ func TestHelper_AddDebugSidecarDeployment(t *testing.T) {
 				existing,
 			)
 			h := &Helper{
-				Client: c,
+				Client:  c,
+				Dynamic: newFakeDynamicClient(),
 			}
 			var expected appsv1.Deployment
-			actual, token, err := h.AddDebugSidecarDeployment(ctx, tt.args.namespace, tt.args.deploymentname, tt.args.containerToDebug, tt.args.debugimage)
+			actual, token, err := h.AddDebugSidecarDeployment(ctx, tt.args.namespace, tt.args.deploymentname, tt.args.containerToDebug, tt.args.debugimage, tt.args.opts)
 			err = readUpdateGoldenFile(tt.goldenfile, *update, &expected, actual)
 			if err != nil {
 				t.Errorf("readUpdateGoldenFile() error = %v", err)
@@ -103,6 +126,13 @@ func TestHelper_AddDebugSidecarDeployment(t *testing.T) {
 				t.Errorf("unexpected number of volumes, got %v, want %v", len(actual.Spec.Template.Spec.Volumes), len(expected.Spec.Template.Spec.Volumes))
 			}
 
+			if tt.args.opts.PodMonitorName != "" {
+				_, err = h.Dynamic.Resource(resources.PodMonitorResource).Namespace(tt.args.namespace).Get(ctx, tt.args.opts.PodMonitorName, metav1.GetOptions{})
+				if err != nil {
+					t.Errorf("Expected PodMonitor %s to be created: %v", tt.args.opts.PodMonitorName, err)
+				}
+			}
+
 			// Simple token validation
 			jt, err := jwt.Parse([]byte(token), jwt.WithVerify(false))
 			if err != nil {
@@ -195,3 +225,13 @@ func createFakeClientWithDeploymentWatcher(ctx context.Context, handlers *cache.
 	<-watcherStarted
 	return c
 }
+
+func newFakeDynamicClient(objs ...runtime.Object) *fakedynamic.FakeDynamicClient {
+	return fakedynamic.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			resources.PodMonitorResource: "PodMonitorList",
+		},
+		objs...,
+	)
+}
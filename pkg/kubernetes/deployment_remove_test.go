@@ -5,10 +5,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+	testclient "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -72,7 +75,7 @@ func TestHelper_RemoveDebugSidecarDeployment(t *testing.T) {
 				Client: c,
 			}
 			var expected appsv1.Deployment
-			actual, err := h.RemoveDebugSidecarDeployment(ctx, tt.args.namespace, tt.args.deploymentname)
+			actual, _, err := h.RemoveDebugSidecarDeployment(ctx, tt.args.namespace, tt.args.deploymentname, resources.RemoveOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("RemoveDebugSidecarDeployment() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -100,3 +103,42 @@ func TestHelper_RemoveDebugSidecarDeployment(t *testing.T) {
 		})
 	}
 }
+
+func TestHelper_RemoveDebugSidecarDeployment_RestoreExact(t *testing.T) {
+	ctx := context.Background()
+	var d appsv1.Deployment
+	err := getObjectFromFile("testdata/deployment/add.yaml", &d)
+	if err != nil {
+		t.Fatalf("getObjectFromFile() error = %v", err)
+	}
+	h := &Helper{
+		Client:  testclient.NewSimpleClientset(d.DeepCopy()),
+		Dynamic: newFakeDynamicClient(),
+	}
+	_, _, err = h.AddDebugSidecarDeployment(ctx, "test", "test", "", "test", resources.SidecarOptions{})
+	if err != nil {
+		t.Fatalf("Helper.AddDebugSidecarDeployment() error = %v", err)
+	}
+	added, err := h.Client.AppsV1().Deployments("test").Get(ctx, "test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get deployment error = %v", err)
+	}
+	if _, ok := added.Annotations[resources.AddedHashAnnotation()]; !ok {
+		t.Errorf("Helper.AddDebugSidecarDeployment() did not record the pod template it wrote")
+	}
+	removed, restored, err := h.RemoveDebugSidecarDeployment(ctx, "test", "test", resources.RemoveOptions{RestoreExact: true})
+	if err != nil {
+		t.Fatalf("Helper.RemoveDebugSidecarDeployment() error = %v", err)
+	}
+	if !restored.Exact {
+		t.Errorf("Helper.RemoveDebugSidecarDeployment() did not restore the snapshot: %s", restored.Reason)
+	}
+	if !apiequality.Semantic.DeepEqual(removed.Spec.Template, d.Spec.Template) {
+		t.Errorf("Helper.RemoveDebugSidecarDeployment() template = %v, want %v", removed.Spec.Template, d.Spec.Template)
+	}
+	for _, k := range []string{resources.SnapshotAnnotation(), resources.AddedHashAnnotation()} {
+		if _, ok := removed.Annotations[k]; ok {
+			t.Errorf("Helper.RemoveDebugSidecarDeployment() kept the annotation %s", k)
+		}
+	}
+}
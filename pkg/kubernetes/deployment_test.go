@@ -2,12 +2,16 @@ package kubernetes
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	testclient "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -31,6 +35,7 @@ func TestHelper_GetDDApplyInfo(t *testing.T) {
 			},
 			initfile: `testdata/deployment/get-ddinfo.yaml`,
 			want: resources.DDConfig{
+				SchemaVersion:      resources.DDConfigSchemaVersion,
 				ContainerToDebug:   "dotnet-container",
 				TmpdirAdded:        true,
 				DebugContainerName: "debug",
@@ -95,6 +100,7 @@ func TestHelper_GetDDPodApplyInfo(t *testing.T) {
 			},
 			initfile: `testdata/deployment/get-ddinfo-pod.yaml`,
 			want: resources.DDConfig{
+				SchemaVersion:      resources.DDConfigSchemaVersion,
 				ContainerToDebug:   "dotnet-container",
 				TmpdirAdded:        true,
 				DebugContainerName: "debug",
@@ -138,3 +144,102 @@ func TestHelper_GetDDPodApplyInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestHelper_AddDebugSidecarDeployment_AlreadyPresent(t *testing.T) {
+	ctx := context.Background()
+	var d appsv1.Deployment
+	err := getObjectFromFile("testdata/deployment/remove.yaml", &d)
+	if err != nil {
+		t.Errorf("getObjectFromFile() error = %v", err)
+		return
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dd-monitor-apikey-inuse",
+			Namespace: d.Namespace,
+			Labels:    map[string]string{resources.SecretLabel(): d.Name},
+		},
+	}
+	c := testclient.NewSimpleClientset(&d, secret)
+	h := &Helper{
+		Client:  c,
+		Dynamic: newFakeDynamicClient(),
+	}
+	_, _, err = h.AddDebugSidecarDeployment(ctx, d.Namespace, d.Name, "", "test", resources.SidecarOptions{})
+	if !errors.Is(err, dmserrors.ErrAlreadyPresent) {
+		t.Errorf("Helper.AddDebugSidecarDeployment() error = %v, want %v", err, dmserrors.ErrAlreadyPresent)
+	}
+	var workloadErr *dmserrors.WorkloadError
+	if !errors.As(err, &workloadErr) || workloadErr.Kind != "Deployment" || workloadErr.Name != d.Name {
+		t.Errorf("Helper.AddDebugSidecarDeployment() error = %v, want WorkloadError for deployment %s", err, d.Name)
+	}
+	_, err = c.CoreV1().Secrets(d.Namespace).Get(ctx, secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Helper.AddDebugSidecarDeployment() deleted the secret of the sidecar already present: %v", err)
+	}
+	for _, a := range c.Actions() {
+		if a.GetResource().Resource == "secrets" && a.GetVerb() != "get" {
+			t.Errorf("Helper.AddDebugSidecarDeployment() %s secrets before finding the sidecar already present", a.GetVerb())
+		}
+	}
+}
+
+func TestHelper_AddDebugSidecarDeployment_CleanupCreated(t *testing.T) {
+	ctx := context.Background()
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			},
+		},
+	}
+	reused := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dd-monitor-apikey-reused",
+			Namespace: "test",
+			Labels:    map[string]string{resources.SecretLabel(): "test"},
+		},
+	}
+	tests := []struct {
+		name        string
+		secret      *corev1.Secret
+		wantSecrets int
+	}{
+		{
+			name:        "Deletes the secret created",
+			wantSecrets: 0,
+		},
+		{
+			name:        "Keeps the secret reused",
+			secret:      reused,
+			wantSecrets: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{deployment.DeepCopy()}
+			if tt.secret != nil {
+				objects = append(objects, tt.secret.DeepCopy())
+			}
+			c := testclient.NewSimpleClientset(objects...)
+			h := &Helper{
+				Client:  c,
+				Dynamic: newFakeDynamicClient(),
+			}
+			// Adding fails after the secret is created or reused, as the container is not found
+			_, _, err := h.AddDebugSidecarDeployment(ctx, "test", "test", "missing", "test", resources.SidecarOptions{PodMonitorName: "test-dd-monitor"})
+			if err == nil {
+				t.Fatalf("Helper.AddDebugSidecarDeployment() error = nil, want container not found")
+			}
+			secrets, _ := c.CoreV1().Secrets("test").List(ctx, metav1.ListOptions{})
+			if len(secrets.Items) != tt.wantSecrets {
+				t.Errorf("Helper.AddDebugSidecarDeployment() left %d secrets after failing, want %d", len(secrets.Items), tt.wantSecrets)
+			}
+			podMonitors, _ := h.Dynamic.Resource(resources.PodMonitorResource).Namespace("test").List(ctx, metav1.ListOptions{})
+			if len(podMonitors.Items) != 0 {
+				t.Errorf("Helper.AddDebugSidecarDeployment() left %d PodMonitors after failing", len(podMonitors.Items))
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Diagnosis is the result of checking the debug sidecar of a pod, or of a pod of a workload
+type Diagnosis struct {
+	Namespace string `json:"namespace"`
+	// Workload is the kind/name of the workload the pod was picked from, empty if a pod was diagnosed
+	Workload string `json:"workload,omitempty"`
+	// Pod is the pod diagnosed, empty if the workload has no pods with the debug sidecar
+	Pod      string              `json:"pod,omitempty"`
+	Findings []resources.Finding `json:"findings"`
+}
+
+// DiagnosePod checks the spec and container statuses of a pod with the debug sidecar, its secret, and the .NET processes
+// dotnet-monitor finds through a tunnel, authenticating with token if set
+func (h *Helper) DiagnosePod(ctx context.Context, namespace, podname, token string) (Diagnosis, error) {
+	pod, err := h.Client.CoreV1().Pods(namespace).Get(ctx, podname, metav1.GetOptions{})
+	if err != nil {
+		return Diagnosis{}, err
+	}
+	return h.diagnosePod(ctx, *pod, token)
+}
+
+// DiagnoseWorkload checks the debug sidecar of a running pod of a deployment or daemonset, see DiagnosePod
+func (h *Helper) DiagnoseWorkload(ctx context.Context, namespace, kind, name, token string) (Diagnosis, error) {
+	var template corev1.PodTemplateSpec
+	var selector *metav1.LabelSelector
+	switch strings.ToLower(kind) {
+	case "daemonset":
+		d, err := h.Client.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return Diagnosis{}, err
+		}
+		kind, template, selector = "DaemonSet", d.Spec.Template, d.Spec.Selector
+	default:
+		d, err := h.Client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return Diagnosis{}, err
+		}
+		kind, template, selector = "Deployment", d.Spec.Template, d.Spec.Selector
+	}
+	if !resources.HasDebugSidecar(template.Annotations) {
+		return Diagnosis{}, workloadError(kind, name, dmserrors.ErrNotPresent)
+	}
+	workload := fmt.Sprintf("%s/%s", strings.ToLower(kind), name)
+	ls, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return Diagnosis{}, err
+	}
+	pods, err := h.Client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: ls.String()})
+	if err != nil {
+		return Diagnosis{}, err
+	}
+	pod, ok := pickDebugPod(pods.Items)
+	if !ok {
+		return Diagnosis{
+			Namespace: namespace,
+			Workload:  workload,
+			Findings: []resources.Finding{{
+				Check:    "pods",
+				Severity: resources.FindingError,
+				Message:  fmt.Sprintf("none of the %d pods of %s have the debug sidecar", len(pods.Items), workload),
+				Fix:      fmt.Sprintf("Wait for the rollout with kubectl rollout status %s", workload),
+			}},
+		}, nil
+	}
+	diagnosis, err := h.diagnosePod(ctx, pod, token)
+	diagnosis.Workload = workload
+	return diagnosis, err
+}
+
+// pickDebugPod returns the pod with the debug sidecar to diagnose, preferring running pods
+func pickDebugPod(pods []corev1.Pod) (corev1.Pod, bool) {
+	var picked *corev1.Pod
+	for i, p := range pods {
+		if !resources.HasDebugSidecar(p.Annotations) || p.DeletionTimestamp != nil {
+			continue
+		}
+		if picked == nil || (picked.Status.Phase != corev1.PodRunning && p.Status.Phase == corev1.PodRunning) {
+			picked = &pods[i]
+		}
+	}
+	if picked == nil {
+		return corev1.Pod{}, false
+	}
+	return *picked, true
+}
+
+func (h *Helper) diagnosePod(ctx context.Context, pod corev1.Pod, token string) (Diagnosis, error) {
+	config, err := resources.DDConfigFromAnnotations(pod.Annotations)
+	if err != nil {
+		return Diagnosis{}, workloadError("Pod", pod.Name, err)
+	}
+	diagnosis := Diagnosis{
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Findings:  resources.DiagnosePod(pod, config),
+	}
+	secret, err := h.Client.CoreV1().Secrets(pod.Namespace).Get(ctx, config.SecretName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		diagnosis.Findings = append(diagnosis.Findings, resources.DiagnoseSecret(nil, config.SecretName))
+	case err != nil:
+		diagnosis.Findings = append(diagnosis.Findings, resources.Finding{
+			Check:    "secret",
+			Severity: resources.FindingWarning,
+			Message:  fmt.Sprintf("unable to get secret %s: %v", config.SecretName, err),
+		})
+	default:
+		diagnosis.Findings = append(diagnosis.Findings, resources.DiagnoseSecret(secret, config.SecretName))
+	}
+	if !debugContainerReady(pod, config.DebugContainerName) {
+		diagnosis.Findings = append(diagnosis.Findings, resources.Finding{
+			Check:    "dotnet processes",
+			Severity: resources.FindingWarning,
+			Message:  "skipped querying dotnet-monitor, as the debug container is not ready",
+		})
+		return diagnosis, nil
+	}
+	err = h.WithTunnel(ctx, pod.Namespace, pod.Name, func(baseURL string) error {
+		diagnosis.Findings = append(diagnosis.Findings, diagnoseMonitor(ctx, baseURL, token))
+		return nil
+	})
+	if err != nil {
+		diagnosis.Findings = append(diagnosis.Findings, resources.Finding{
+			Check:    "dotnet processes",
+			Severity: resources.FindingError,
+			Message:  fmt.Sprintf("unable to port-forward to dotnet-monitor: %v", err),
+			Fix:      "Check you are allowed to port-forward with dmsctl auth can-i",
+		})
+	}
+	return diagnosis, nil
+}
+
+// diagnoseMonitor queries the .NET processes from the dotnet-monitor api at baseURL
+func diagnoseMonitor(ctx context.Context, baseURL, token string) resources.Finding {
+	var processes []resources.MonitorProcess
+	err := monitorGet(ctx, baseURL, token, "/processes", &processes)
+	var statusErr *monitorStatusError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized:
+		message := "dotnet-monitor rejected the token"
+		if token == "" {
+			message = "dotnet-monitor requires a token to list the .NET processes"
+		}
+		return resources.Finding{
+			Check:    "dotnet processes",
+			Severity: resources.FindingWarning,
+			Message:  message,
+			Fix:      "Pass the token printed when the sidecar was added with --token",
+		}
+	case err != nil:
+		return resources.Finding{
+			Check:    "dotnet processes",
+			Severity: resources.FindingError,
+			Message:  fmt.Sprintf("unable to list the .NET processes: %v", err),
+			Fix:      "Check the logs of the debug container with kubectl logs",
+		}
+	}
+	return resources.DiagnoseProcesses(processes)
+}
+
+func debugContainerReady(pod corev1.Pod, container string) bool {
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Name == container {
+			return s.Ready
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHelper_DiagnoseWorkload(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	template, err := resources.AddDebugContainerPodTemplate(template, "default", "app", "mcr.microsoft.com/dotnet/monitor:8", "dd-monitor-apikey-test", resources.SidecarOptions{})
+	if err != nil {
+		t.Fatalf("AddDebugContainerPodTemplate() error = %v", err)
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+			Template: template,
+		},
+	}
+	pod := func(name string, phase corev1.PodPhase, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: template.Labels, Annotations: annotations},
+			Spec:       template.Spec,
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	secret := resources.GenerateSecret("default", "subject", "key", "test")
+	secret.Name = "dd-monitor-apikey-test"
+	tests := []struct {
+		name         string
+		objects      []runtime.Object
+		wantPod      string
+		wantProblems map[string]string
+		wantErrIs    error
+	}{
+		{
+			name: "Picks the running pod with the debug sidecar",
+			objects: []runtime.Object{
+				deployment, &secret,
+				pod("test-old", corev1.PodRunning, nil),
+				pod("test-pending", corev1.PodPending, template.Annotations),
+				pod("test-running", corev1.PodRunning, template.Annotations),
+			},
+			wantPod: "test-running",
+			wantProblems: map[string]string{
+				"debug container":  resources.FindingWarning,
+				"target container": resources.FindingWarning,
+				"user":             resources.FindingWarning,
+				"dotnet processes": resources.FindingWarning,
+			},
+		},
+		{
+			name: "Secret missing",
+			objects: []runtime.Object{
+				deployment,
+				pod("test-running", corev1.PodRunning, template.Annotations),
+			},
+			wantPod: "test-running",
+			wantProblems: map[string]string{
+				"debug container":  resources.FindingWarning,
+				"target container": resources.FindingWarning,
+				"user":             resources.FindingWarning,
+				"secret":           resources.FindingError,
+				"dotnet processes": resources.FindingWarning,
+			},
+		},
+		{
+			name: "No pods with the debug sidecar",
+			objects: []runtime.Object{
+				deployment, &secret,
+				pod("test-old", corev1.PodRunning, nil),
+			},
+			wantProblems: map[string]string{"pods": resources.FindingError},
+		},
+		{
+			name: "Debug sidecar not added",
+			objects: []runtime.Object{
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			},
+			wantErrIs: dmserrors.ErrNotPresent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Helper{Client: testclient.NewSimpleClientset(tt.objects...)}
+			got, err := h.DiagnoseWorkload(context.Background(), "default", "deployment", "test", "")
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Errorf("Helper.DiagnoseWorkload() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Helper.DiagnoseWorkload() error = %v", err)
+			}
+			if got.Pod != tt.wantPod || got.Workload != "deployment/test" {
+				t.Errorf("Helper.DiagnoseWorkload() diagnosed %s of %s, want %s of deployment/test", got.Pod, got.Workload, tt.wantPod)
+			}
+			problems := map[string]string{}
+			for _, f := range got.Findings {
+				if f.Severity != resources.FindingOK {
+					problems[f.Check] = f.Severity
+				}
+			}
+			if len(problems) != len(tt.wantProblems) {
+				t.Errorf("Helper.DiagnoseWorkload() problems = %v, want %v", problems, tt.wantProblems)
+			}
+			for check, severity := range tt.wantProblems {
+				if problems[check] != severity {
+					t.Errorf("Helper.DiagnoseWorkload() %s = %q, want %q", check, problems[check], severity)
+				}
+			}
+		})
+	}
+}
+
+func Test_diagnoseMonitor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "Bearer broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "Bearer empty":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			_, _ = w.Write([]byte(`[{"pid": 1, "uid": "abc", "name": "app", "isDefault": true}]`))
+		}
+	}))
+	defer server.Close()
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{token: "", want: resources.FindingWarning},
+		{token: "broken", want: resources.FindingError},
+		{token: "empty", want: resources.FindingError},
+		{token: "valid", want: resources.FindingOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if got := diagnoseMonitor(context.Background(), server.URL, tt.token); got.Severity != tt.want {
+				t.Errorf("diagnoseMonitor() = %+v, want severity %s", got, tt.want)
+			}
+		})
+	}
+}
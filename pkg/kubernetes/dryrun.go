@@ -0,0 +1,24 @@
+package kubernetes
+
+import (
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// patchOptions returns the patch options of the field manager for the dry run strategy
+func patchOptions(dryRun string) metav1.PatchOptions {
+	opts := metav1.PatchOptions{FieldManager: FieldManager}
+	if dryRun == resources.DryRunServer {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return opts
+}
+
+// deleteOptions returns the delete options for the dry run strategy
+func deleteOptions(dryRun string) metav1.DeleteOptions {
+	var opts metav1.DeleteOptions
+	if dryRun == resources.DryRunServer {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return opts
+}
@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestHelper_AddDebugSidecarDeployment_DryRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		dryRun    string
+		wantPatch bool
+	}{
+		{
+			name:   "Client dry run does not update the deployment",
+			dryRun: resources.DryRunClient,
+		},
+		{
+			name:      "Server dry run sends patch with dry run",
+			dryRun:    resources.DryRunServer,
+			wantPatch: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var d appsv1.Deployment
+			err := getObjectFromFile("testdata/deployment/add.yaml", &d)
+			if err != nil {
+				t.Errorf("getObjectFromFile() error = %v", err)
+				return
+			}
+			c := testclient.NewSimpleClientset(&d)
+			h := &Helper{
+				Client:  c,
+				Dynamic: newFakeDynamicClient(),
+			}
+			opts := resources.SidecarOptions{
+				DryRun:         tt.dryRun,
+				PodMonitorName: resources.PodMonitorName("test"),
+			}
+			got, token, err := h.AddDebugSidecarDeployment(ctx, "test", "test", "test", "test", opts)
+			if err != nil {
+				t.Errorf("Helper.AddDebugSidecarDeployment() error = %v", err)
+				return
+			}
+			if token != "" {
+				t.Errorf("Helper.AddDebugSidecarDeployment() token = %q, want no token in dry run", token)
+			}
+			if !resources.HasDebugSidecar(got.Spec.Template.Annotations) {
+				t.Errorf("Helper.AddDebugSidecarDeployment() returned deployment without debug sidecar")
+			}
+			assertDryRunActions(t, c, "deployments", tt.wantPatch)
+			_, err = h.Dynamic.Resource(resources.PodMonitorResource).Namespace("test").Get(ctx, opts.PodMonitorName, metav1.GetOptions{})
+			if err == nil {
+				t.Errorf("PodMonitor %s created in dry run", opts.PodMonitorName)
+			}
+		})
+	}
+}
+
+func TestHelper_RemoveDebugSidecarDeployment_DryRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		dryRun    string
+		wantPatch bool
+	}{
+		{
+			name:   "Client dry run does not update the deployment",
+			dryRun: resources.DryRunClient,
+		},
+		{
+			name:      "Server dry run sends patch with dry run",
+			dryRun:    resources.DryRunServer,
+			wantPatch: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var d appsv1.Deployment
+			err := getObjectFromFile("testdata/deployment/remove.yaml", &d)
+			if err != nil {
+				t.Errorf("getObjectFromFile() error = %v", err)
+				return
+			}
+			c := testclient.NewSimpleClientset(&d)
+			h := &Helper{
+				Client:  c,
+				Dynamic: newFakeDynamicClient(),
+			}
+			got, _, err := h.RemoveDebugSidecarDeployment(ctx, "test", "test", resources.RemoveOptions{DryRun: tt.dryRun})
+			if err != nil {
+				t.Errorf("Helper.RemoveDebugSidecarDeployment() error = %v", err)
+				return
+			}
+			if resources.HasDebugSidecar(got.Spec.Template.Annotations) {
+				t.Errorf("Helper.RemoveDebugSidecarDeployment() returned deployment with debug sidecar")
+			}
+			assertDryRunActions(t, c, "deployments", tt.wantPatch)
+		})
+	}
+}
+
+// assertDryRunActions fails the test if the client did anything but read, or a patch of resource without dry run
+func assertDryRunActions(t *testing.T, c *testclient.Clientset, resource string, wantPatch bool) {
+	t.Helper()
+	patched := false
+	for _, a := range c.Actions() {
+		switch action := a.(type) {
+		case clienttesting.PatchActionImpl:
+			if action.GetResource().Resource != resource || !reflect.DeepEqual(action.PatchOptions.DryRun, []string{metav1.DryRunAll}) {
+				t.Errorf("unexpected patch in dry run: %s %s %v", action.GetResource().Resource, action.GetSubresource(), action.PatchOptions)
+			}
+			patched = true
+		case clienttesting.GetActionImpl, clienttesting.ListActionImpl:
+		default:
+			t.Errorf("unexpected action in dry run: %s %s", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+	if patched != wantPatch {
+		t.Errorf("patch sent = %v, want %v", patched, wantPatch)
+	}
+}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// EventReasonSidecarAdded is the reason of the event recorded when the debug sidecar is added to a workload
+	EventReasonSidecarAdded = "DebugSidecarAdded"
+	// EventReasonSidecarRemoved is the reason of the event recorded when the debug sidecar is removed from a workload
+	EventReasonSidecarRemoved = "DebugSidecarRemoved"
+	// EventReasonSidecarExpired is the reason of the event recorded when an expired debug sidecar is reaped
+	EventReasonSidecarExpired = "DebugSidecarExpired"
+	eventSourceComponent      = "dmsctl"
+)
+
+// WhoAmI returns the username the cluster authenticates the client as, using a SelfSubjectReview.
+// Returns fallback if the cluster does not support SelfSubjectReview
+func (h *Helper) WhoAmI(ctx context.Context, fallback string) string {
+	review, err := h.Client.AuthenticationV1().SelfSubjectReviews().Create(ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err != nil || review.Status.UserInfo.Username == "" {
+		return fallback
+	}
+	return review.Status.UserInfo.Username
+}
+
+// RecordWorkloadEvent records a Normal event on an apps/v1 workload, shown by kubectl describe
+func (h *Helper) RecordWorkloadEvent(ctx context.Context, kind string, workload metav1.Object, reason, message string) error {
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", workload.GetName(), now.UnixNano()),
+			Namespace: workload.GetNamespace(),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Namespace:  workload.GetNamespace(),
+			Name:       workload.GetName(),
+			UID:        workload.GetUID(),
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: eventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	_, err := h.Client.CoreV1().Events(workload.GetNamespace()).Create(ctx, event, metav1.CreateOptions{})
+	return err
+}
+
+// AuditMessage returns the event message for an action on the debug sidecar, like "Debug sidecar added"
+func AuditMessage(action string, audit resources.AuditInfo) string {
+	message := action
+	if audit.User != "" {
+		message += " by " + audit.User
+	}
+	if audit.CLIVersion != "" {
+		message += fmt.Sprintf(" using dmsctl %s", audit.CLIVersion)
+	}
+	if audit.Reason != "" {
+		message += ": " + audit.Reason
+	}
+	return message
+}
@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestHelper_RecordWorkloadEvent(t *testing.T) {
+	c := testclient.NewSimpleClientset()
+	h := &Helper{
+		Client: c,
+	}
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "1234"}}
+	err := h.RecordWorkloadEvent(context.Background(), "Deployment", d, EventReasonSidecarAdded, "Debug sidecar added")
+	if err != nil {
+		t.Errorf("Helper.RecordWorkloadEvent() error = %v", err)
+		return
+	}
+	events, _ := c.CoreV1().Events("default").List(context.Background(), metav1.ListOptions{})
+	if len(events.Items) != 1 {
+		t.Errorf("%d events recorded, want 1", len(events.Items))
+		return
+	}
+	e := events.Items[0]
+	if e.InvolvedObject.Kind != "Deployment" || e.InvolvedObject.Name != "test" || e.InvolvedObject.UID != "1234" {
+		t.Errorf("Event involved object = %v, want Deployment test with uid 1234", e.InvolvedObject)
+	}
+	if e.Reason != EventReasonSidecarAdded || e.Message != "Debug sidecar added" {
+		t.Errorf("Event reason and message = %s: %s", e.Reason, e.Message)
+	}
+}
+
+func TestHelper_WhoAmI(t *testing.T) {
+	tests := []struct {
+		name     string
+		reactor  k8stesting.ReactionFunc
+		fallback string
+		want     string
+	}{
+		{
+			name: "Returns user from SelfSubjectReview",
+			reactor: func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, &authenticationv1.SelfSubjectReview{
+					Status: authenticationv1.SelfSubjectReviewStatus{
+						UserInfo: authenticationv1.UserInfo{Username: "alice@example.com"},
+					},
+				}, nil
+			},
+			fallback: "kubeconfig-user",
+			want:     "alice@example.com",
+		},
+		{
+			name: "Returns fallback if SelfSubjectReview is not supported",
+			reactor: func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("the server could not find the requested resource")
+			},
+			fallback: "kubeconfig-user",
+			want:     "kubeconfig-user",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testclient.NewSimpleClientset()
+			c.PrependReactor("create", "selfsubjectreviews", tt.reactor)
+			h := &Helper{
+				Client: c,
+			}
+			if got := h.WhoAmI(context.Background(), tt.fallback); got != tt.want {
+				t.Errorf("Helper.WhoAmI() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		audit resources.AuditInfo
+		want  string
+	}{
+		{
+			name:  "Message with all audit info",
+			audit: resources.AuditInfo{User: "alice", CLIVersion: "v1.2.3", Reason: "INC-1234"},
+			want:  "Debug sidecar added by alice using dmsctl v1.2.3: INC-1234",
+		},
+		{
+			name:  "Message without audit info",
+			audit: resources.AuditInfo{},
+			want:  "Debug sidecar added",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AuditMessage("Debug sidecar added", tt.audit); got != tt.want {
+				t.Errorf("AuditMessage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Factory creates the helper, REST config and namespace of the commands, port-forward and the autocompletion
+type Factory interface {
+	// Helper returns a helper with clients for the cluster
+	Helper() (Helper, error)
+	// ToRESTConfig returns the REST config of the cluster
+	ToRESTConfig() (*rest.Config, error)
+	// Namespace returns the namespace of the request, and if it was set explicitly with --namespace
+	Namespace() (string, bool, error)
+	// User returns the user of the kubeconfig context, used when the cluster is unable to tell who we are
+	User() (string, error)
+	// Contexts returns the names of the contexts in the kubeconfig, sorted
+	Contexts() ([]string, error)
+}
+
+// configFlagsFactory is a Factory using the kubectl compatible global flags
+type configFlagsFactory struct {
+	flags *genericclioptions.ConfigFlags
+}
+
+// NewFactory returns a Factory using the kubeconfig, context, impersonation and other kubectl compatible flags.
+// Without a kubeconfig the in-cluster config is used, so the cli can run as a CronJob
+func NewFactory(flags *genericclioptions.ConfigFlags) Factory {
+	return &configFlagsFactory{flags: flags}
+}
+
+func (f *configFlagsFactory) Helper() (Helper, error) {
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return Helper{}, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return Helper{}, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return Helper{}, err
+	}
+	return Helper{
+		Client:  clientset,
+		Dynamic: dynamicClient,
+		Config:  config,
+	}, nil
+}
+
+func (f *configFlagsFactory) ToRESTConfig() (*rest.Config, error) {
+	return f.flags.ToRESTConfig()
+}
+
+func (f *configFlagsFactory) Namespace() (string, bool, error) {
+	namespace, explicit, err := f.flags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get namespace from current context: %w", err)
+	}
+	return namespace, explicit, nil
+}
+
+func (f *configFlagsFactory) User() (string, error) {
+	if f.flags.AuthInfoName != nil && *f.flags.AuthInfoName != "" {
+		return *f.flags.AuthInfoName, nil
+	}
+	config, err := f.flags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return "", fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	contextName := config.CurrentContext
+	if f.flags.Context != nil && *f.flags.Context != "" {
+		contextName = *f.flags.Context
+	}
+	context, ok := config.Contexts[contextName]
+	if !ok {
+		return "", fmt.Errorf("context %s not found in kubeconfig", contextName)
+	}
+	return context.AuthInfo, nil
+}
+
+func (f *configFlagsFactory) Contexts() ([]string, error) {
+	config, err := f.flags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+users:
+- name: dev-user
+  user:
+    token: dev-token
+- name: prod-user
+  user:
+    token: prod-token
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev-user
+    namespace: dev-namespace
+- name: prod
+  context:
+    cluster: prod
+    user: prod-user
+`
+
+func testConfigFlags(t *testing.T, set func(f *genericclioptions.ConfigFlags)) *genericclioptions.ConfigFlags {
+	t.Helper()
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(kubeconfig, []byte(testKubeconfig), 0600)
+	if err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	f := genericclioptions.NewConfigFlags(false)
+	*f.KubeConfig = kubeconfig
+	if set != nil {
+		set(f)
+	}
+	return f
+}
+
+func TestFactory(t *testing.T) {
+	tests := []struct {
+		name            string
+		set             func(f *genericclioptions.ConfigFlags)
+		wantHost        string
+		wantNamespace   string
+		wantExplicit    bool
+		wantUser        string
+		wantImpersonate string
+	}{
+		{
+			name:          "Current context of the kubeconfig",
+			wantHost:      "https://dev.example.com",
+			wantNamespace: "dev-namespace",
+			wantUser:      "dev-user",
+		},
+		{
+			name: "Context flag",
+			set: func(f *genericclioptions.ConfigFlags) {
+				*f.Context = "prod"
+			},
+			wantHost:      "https://prod.example.com",
+			wantNamespace: "default",
+			wantUser:      "prod-user",
+		},
+		{
+			name: "Namespace and user flags",
+			set: func(f *genericclioptions.ConfigFlags) {
+				*f.Namespace = "other"
+				*f.AuthInfoName = "prod-user"
+			},
+			wantHost:      "https://dev.example.com",
+			wantNamespace: "other",
+			wantExplicit:  true,
+			wantUser:      "prod-user",
+		},
+		{
+			name: "Impersonation flag",
+			set: func(f *genericclioptions.ConfigFlags) {
+				*f.Impersonate = "jane"
+			},
+			wantHost:        "https://dev.example.com",
+			wantNamespace:   "dev-namespace",
+			wantUser:        "dev-user",
+			wantImpersonate: "jane",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFactory(testConfigFlags(t, tt.set))
+			h, err := f.Helper()
+			if err != nil {
+				t.Fatalf("Factory.Helper() error = %v", err)
+			}
+			if h.Client == nil || h.Dynamic == nil || h.Config == nil {
+				t.Fatalf("Factory.Helper() = %+v, want clients and config", h)
+			}
+			if h.Config.Host != tt.wantHost {
+				t.Errorf("Factory.Helper() host = %v, want %v", h.Config.Host, tt.wantHost)
+			}
+			if h.Config.Impersonate.UserName != tt.wantImpersonate {
+				t.Errorf("Factory.Helper() impersonates %v, want %v", h.Config.Impersonate.UserName, tt.wantImpersonate)
+			}
+			namespace, explicit, err := f.Namespace()
+			if err != nil {
+				t.Fatalf("Factory.Namespace() error = %v", err)
+			}
+			if namespace != tt.wantNamespace || explicit != tt.wantExplicit {
+				t.Errorf("Factory.Namespace() = %v, %v, want %v, %v", namespace, explicit, tt.wantNamespace, tt.wantExplicit)
+			}
+			user, err := f.User()
+			if err != nil {
+				t.Fatalf("Factory.User() error = %v", err)
+			}
+			if user != tt.wantUser {
+				t.Errorf("Factory.User() = %v, want %v", user, tt.wantUser)
+			}
+			contexts, err := f.Contexts()
+			if err != nil {
+				t.Fatalf("Factory.Contexts() error = %v", err)
+			}
+			if !reflect.DeepEqual(contexts, []string{"dev", "prod"}) {
+				t.Errorf("Factory.Contexts() = %v, want [dev prod]", contexts)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// OrphanedResource represents a secret or collection rules configmap created by the cli which is no longer used by its workload
+type OrphanedResource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Owner     string `json:"owner"`
+	Reason    string `json:"reason"`
+}
+
+// FindOrphanedResources returns the secrets and collection rules configmaps created by the cli whose workload no longer exists
+// or no longer references them, in a namespace or all namespaces if namespace is empty.
+// The resources of workloads whose DDConfig is unparseable are kept, with a warning for each of those workloads
+func (h *Helper) FindOrphanedResources(ctx context.Context, namespace string) ([]OrphanedResource, []string, error) {
+	secrets, err := h.listCLISecrets(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	configmaps, err := h.listCLIRulesConfigMaps(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(secrets) == 0 && len(configmaps) == 0 {
+		return nil, nil, nil
+	}
+	workloads, warnings, err := h.workloadReferences(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	var orphans []OrphanedResource
+	for _, s := range secrets {
+		orphans = appendOrphan(orphans, workloads, "Secret", s.ObjectMeta, resources.SecretOwner(s.Labels))
+	}
+	for _, c := range configmaps {
+		orphans = appendOrphan(orphans, workloads, "ConfigMap", c.ObjectMeta, resources.RulesOwner(c.Labels))
+	}
+	return orphans, warnings, nil
+}
+
+// CollectOrphanedResources deletes the resources returned by FindOrphanedResources.
+// With the client dry run strategy nothing is deleted, with server the deletes are sent without being persisted
+func (h *Helper) CollectOrphanedResources(ctx context.Context, namespace, dryRun string) ([]OrphanedResource, []string, error) {
+	orphans, warnings, err := h.FindOrphanedResources(ctx, namespace)
+	if err != nil || dryRun == resources.DryRunClient {
+		return orphans, warnings, err
+	}
+	logger := klog.FromContext(ctx)
+	for i, o := range orphans {
+		logger.V(LogLevelChanges).Info("Deleting orphaned "+o.Kind, "namespace", o.Namespace, "name", o.Name, "dryRun", dryRun)
+		if o.Kind == "ConfigMap" {
+			err = h.Client.CoreV1().ConfigMaps(o.Namespace).Delete(ctx, o.Name, deleteOptions(dryRun))
+		} else {
+			err = h.Client.CoreV1().Secrets(o.Namespace).Delete(ctx, o.Name, deleteOptions(dryRun))
+		}
+		if err != nil {
+			return orphans[:i], warnings, fmt.Errorf("failed to delete %s %s/%s: %w", o.Kind, o.Namespace, o.Name, err)
+		}
+	}
+	return orphans, warnings, nil
+}
+
+// listCLISecrets returns the secrets created by the cli, labeled with the current or legacy secret label
+func (h *Helper) listCLISecrets(ctx context.Context, namespace string) ([]corev1.Secret, error) {
+	var secrets []corev1.Secret
+	seen := map[string]bool{}
+	for _, label := range resources.SecretLabels() {
+		sl, err := h.Client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: label})
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sl.Items {
+			key := fmt.Sprintf("%s/%s", s.Namespace, s.Name)
+			if !seen[key] {
+				seen[key] = true
+				secrets = append(secrets, s)
+			}
+		}
+	}
+	return secrets, nil
+}
+
+// listCLIRulesConfigMaps returns the collection rules configmaps created by the cli, labeled with the current or legacy rules label
+func (h *Helper) listCLIRulesConfigMaps(ctx context.Context, namespace string) ([]corev1.ConfigMap, error) {
+	var configmaps []corev1.ConfigMap
+	seen := map[string]bool{}
+	for _, label := range resources.RulesLabels() {
+		cl, err := h.Client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{LabelSelector: label})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cl.Items {
+			key := fmt.Sprintf("%s/%s", c.Namespace, c.Name)
+			if !seen[key] {
+				seen[key] = true
+				configmaps = append(configmaps, c)
+			}
+		}
+	}
+	return configmaps, nil
+}
+
+// workloadReferences maps namespace/name of the Deployments and DaemonSets to the secrets and collection rules configmaps
+// referenced in their DDConfig.
+// Workloads without the debug sidecar are mapped to no references, and workloads whose DDConfig is unparseable to nil,
+// which keeps all their resources, with a warning for each of them
+func (h *Helper) workloadReferences(ctx context.Context, namespace string) (map[string][]string, []string, error) {
+	refs := map[string][]string{}
+	var warnings []string
+	deployments, err := h.Client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range deployments.Items {
+		warnings = addWorkloadReference(refs, warnings, "Deployment", d.ObjectMeta, d.Spec.Template)
+	}
+	daemonsets, err := h.Client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, d := range daemonsets.Items {
+		warnings = addWorkloadReference(refs, warnings, "DaemonSet", d.ObjectMeta, d.Spec.Template)
+	}
+	return refs, warnings, nil
+}
+
+func addWorkloadReference(refs map[string][]string, warnings []string, kind string, meta metav1.ObjectMeta, template corev1.PodTemplateSpec) []string {
+	key := fmt.Sprintf("%s/%s", meta.Namespace, meta.Name)
+	if names, ok := refs[key]; ok && names == nil {
+		return warnings
+	}
+	if _, ok := refs[key]; !ok {
+		refs[key] = []string{}
+	}
+	w, ok := newDebugWorkload(kind, meta, template)
+	if !ok {
+		return warnings
+	}
+	if w.Unparseable() {
+		refs[key] = nil
+		return append(warnings, fmt.Sprintf("keeping the secrets and collection rules configmaps of %s %s, its debug sidecar config is unparseable: %s", kind, key, w.Error))
+	}
+	refs[key] = append(refs[key], w.Config.SecretName)
+	if w.Config.RulesConfigMap != "" {
+		refs[key] = append(refs[key], w.Config.RulesConfigMap)
+	}
+	return warnings
+}
+
+// appendOrphan appends the resource to orphans if it is no longer used by its owner
+func appendOrphan(orphans []OrphanedResource, workloads map[string][]string, kind string, meta metav1.ObjectMeta, owner string) []OrphanedResource {
+	reason := orphanReason(meta, owner, workloads)
+	if reason == "" {
+		return orphans
+	}
+	return append(orphans, OrphanedResource{
+		Kind:      kind,
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		Owner:     owner,
+		Reason:    reason,
+	})
+}
+
+// orphanReason returns why the resource is orphaned, or an empty string if it is still in use
+func orphanReason(meta metav1.ObjectMeta, owner string, workloads map[string][]string) string {
+	names, ok := workloads[fmt.Sprintf("%s/%s", meta.Namespace, owner)]
+	if !ok {
+		return fmt.Sprintf("workload %s not found", owner)
+	}
+	if names == nil {
+		// The resources referenced are unknown when the DDConfig is unparseable
+		return ""
+	}
+	for _, name := range names {
+		if name == meta.Name {
+			return ""
+		}
+	}
+	return fmt.Sprintf("not referenced by workload %s", owner)
+}
@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestHelper_CollectOrphanedResources(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"dev.local/dd-added": "true",
+				"dev.local/dd-apply": `{"containerToDebug":"app","debugContainerName":"debug","tmpdirAdded":true,"secretMount":"dd-monitor-apikey-inuse","rulesConfigMap":"dd-monitor-rules-inuse"}`,
+			},
+		},
+	}
+	secret := func(namespace, name, owner string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{resources.SecretLabel(): owner},
+			},
+		}
+	}
+	configmap := func(namespace, name, owner string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{resources.RulesLabel(): owner},
+			},
+		}
+	}
+	unparseable := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"dev.local/dd-added": "true",
+				"dev.local/dd-apply": `{"schemaVersion":99,"secretMount":"dd-monitor-apikey-unknown"}`,
+			},
+		},
+	}
+	newClient := func() *testclient.Clientset {
+		return testclient.NewSimpleClientset(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "with-sidecar", Namespace: "a"},
+				Spec:       appsv1.DeploymentSpec{Template: template},
+			},
+			&appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "without-sidecar", Namespace: "b"},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "newer-schema", Namespace: "c"},
+				Spec:       appsv1.DeploymentSpec{Template: unparseable},
+			},
+			secret("a", "dd-monitor-apikey-inuse", "with-sidecar"),
+			secret("a", "dd-monitor-apikey-stale", "with-sidecar"),
+			secret("a", "dd-monitor-apikey-deleted", "deleted"),
+			secret("b", "dd-monitor-apikey-removed", "without-sidecar"),
+			secret("c", "dd-monitor-apikey-unknown", "newer-schema"),
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "a"}},
+			configmap("a", "dd-monitor-rules-inuse", "with-sidecar"),
+			configmap("a", "dd-monitor-rules-stale", "with-sidecar"),
+			configmap("c", "dd-monitor-rules-unknown", "newer-schema"),
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "a"}},
+		)
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		dryRun    string
+		want      []string
+		warnings  int
+		remaining int
+		// remainingConfigMaps counts the configmaps left, including the unrelated one
+		remainingConfigMaps int
+	}{
+		{
+			name:                "Deletes orphaned resources in namespace",
+			namespace:           "a",
+			want:                []string{"Secret a/dd-monitor-apikey-deleted", "Secret a/dd-monitor-apikey-stale", "ConfigMap a/dd-monitor-rules-stale"},
+			remaining:           4,
+			remainingConfigMaps: 3,
+		},
+		{
+			name:                "Deletes orphaned resources in all namespaces",
+			namespace:           "",
+			want:                []string{"Secret a/dd-monitor-apikey-deleted", "Secret a/dd-monitor-apikey-stale", "Secret b/dd-monitor-apikey-removed", "ConfigMap a/dd-monitor-rules-stale"},
+			warnings:            1,
+			remaining:           3,
+			remainingConfigMaps: 3,
+		},
+		{
+			name:                "Keeps resources of workload with unparseable config",
+			namespace:           "c",
+			warnings:            1,
+			remaining:           6,
+			remainingConfigMaps: 4,
+		},
+		{
+			name:                "Client dry run does not delete resources",
+			namespace:           "",
+			dryRun:              resources.DryRunClient,
+			want:                []string{"Secret a/dd-monitor-apikey-deleted", "Secret a/dd-monitor-apikey-stale", "Secret b/dd-monitor-apikey-removed", "ConfigMap a/dd-monitor-rules-stale"},
+			warnings:            1,
+			remaining:           6,
+			remainingConfigMaps: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient()
+			h := &Helper{
+				Client: c,
+			}
+			got, warnings, err := h.CollectOrphanedResources(context.Background(), tt.namespace, tt.dryRun)
+			if err != nil {
+				t.Errorf("Helper.CollectOrphanedResources() error = %v", err)
+				return
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("Helper.CollectOrphanedResources() returned warnings %v, want %d", warnings, tt.warnings)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Helper.CollectOrphanedResources() returned %d resources, want %d", len(got), len(tt.want))
+				return
+			}
+			for i, o := range got {
+				if id := o.Kind + " " + o.Namespace + "/" + o.Name; id != tt.want[i] {
+					t.Errorf("Helper.CollectOrphanedResources()[%d] = %s, want %s", i, id, tt.want[i])
+				}
+			}
+			secrets, _ := c.CoreV1().Secrets("").List(context.Background(), metav1.ListOptions{})
+			if len(secrets.Items) != tt.remaining {
+				t.Errorf("%d secrets remaining, want %d", len(secrets.Items), tt.remaining)
+			}
+			configmaps, _ := c.CoreV1().ConfigMaps("").List(context.Background(), metav1.ListOptions{})
+			if len(configmaps.Items) != tt.remainingConfigMaps {
+				t.Errorf("%d configmaps remaining, want %d", len(configmaps.Items), tt.remainingConfigMaps)
+			}
+		})
+	}
+}
+
+func TestHelper_CollectOrphanedResources_ServerDryRun(t *testing.T) {
+	c := testclient.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "dd-monitor-apikey-deleted", Namespace: "a", Labels: map[string]string{resources.SecretLabel(): "deleted"}}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "dd-monitor-rules-deleted", Namespace: "a", Labels: map[string]string{resources.RulesLabel(): "deleted"}}},
+	)
+	var deletes []string
+	c.PrependReactor("delete", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		d := action.(clienttesting.DeleteAction)
+		if len(d.GetDeleteOptions().DryRun) != 1 || d.GetDeleteOptions().DryRun[0] != metav1.DryRunAll {
+			t.Errorf("delete of %s %s sent without server dry run, options = %v", d.GetResource().Resource, d.GetName(), d.GetDeleteOptions())
+		}
+		deletes = append(deletes, d.GetResource().Resource+"/"+d.GetName())
+		return true, nil, nil
+	})
+	h := &Helper{Client: c}
+	got, _, err := h.CollectOrphanedResources(context.Background(), "a", resources.DryRunServer)
+	if err != nil {
+		t.Fatalf("Helper.CollectOrphanedResources() error = %v", err)
+	}
+	if len(got) != 2 || len(deletes) != 2 {
+		t.Errorf("Helper.CollectOrphanedResources() returned %v and sent deletes %v, want 2 of each", got, deletes)
+	}
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DebugWorkload represents a workload with the debug sidecar attached
+type DebugWorkload struct {
+	Kind              string             `json:"kind"`
+	Namespace         string             `json:"namespace"`
+	Name              string             `json:"name"`
+	Image             string             `json:"image"`
+	ReadyPods         int32              `json:"readyPods"`
+	DesiredPods       int32              `json:"desiredPods"`
+	CreationTimestamp metav1.Time        `json:"creationTimestamp"`
+	Config            resources.DDConfig `json:"ddConfig"`
+	// Error is why the DDConfig annotation could not be parsed, like one written by a newer version of the cli.
+	// Config is empty when it is set
+	Error string `json:"error,omitempty"`
+}
+
+// Unparseable returns true if the DDConfig of the workload could not be parsed
+func (w DebugWorkload) Unparseable() bool {
+	return w.Error != ""
+}
+
+// AddedAt returns when the debug sidecar was added according to the audit info, or when the workload was created without it
+func (w DebugWorkload) AddedAt() time.Time {
+	if w.Config.Audit != nil && !w.Config.Audit.Time.IsZero() {
+		return w.Config.Audit.Time.Time
+	}
+	return w.CreationTimestamp.Time
+}
+
+// ListDebugWorkloads returns the Deployments and DaemonSets with the debug sidecar attached in a namespace, or all namespaces if namespace is empty
+func (h *Helper) ListDebugWorkloads(ctx context.Context, namespace string) ([]DebugWorkload, error) {
+	var workloads []DebugWorkload
+	deployments, err := h.Client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		w, ok := newDebugWorkload("Deployment", d.ObjectMeta, d.Spec.Template)
+		if !ok {
+			continue
+		}
+		w.ReadyPods = d.Status.ReadyReplicas
+		w.DesiredPods = d.Status.Replicas
+		if d.Spec.Replicas != nil {
+			w.DesiredPods = *d.Spec.Replicas
+		}
+		workloads = append(workloads, w)
+	}
+	daemonsets, err := h.Client.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range daemonsets.Items {
+		w, ok := newDebugWorkload("DaemonSet", d.ObjectMeta, d.Spec.Template)
+		if !ok {
+			continue
+		}
+		w.ReadyPods = d.Status.NumberReady
+		w.DesiredPods = d.Status.DesiredNumberScheduled
+		workloads = append(workloads, w)
+	}
+	sort.SliceStable(workloads, func(i, j int) bool {
+		if workloads[i].Namespace != workloads[j].Namespace {
+			return workloads[i].Namespace < workloads[j].Namespace
+		}
+		if workloads[i].Kind != workloads[j].Kind {
+			return workloads[i].Kind < workloads[j].Kind
+		}
+		return workloads[i].Name < workloads[j].Name
+	})
+	return workloads, nil
+}
+
+// newDebugWorkload returns the DebugWorkload for a workload, and false if the debug sidecar is not attached.
+// A workload with an unparseable DDConfig is returned with the parse error set
+func newDebugWorkload(kind string, meta metav1.ObjectMeta, template corev1.PodTemplateSpec) (DebugWorkload, bool) {
+	if !resources.HasDebugSidecar(template.Annotations) {
+		return DebugWorkload{}, false
+	}
+	w := DebugWorkload{
+		Kind:              kind,
+		Namespace:         meta.Namespace,
+		Name:              meta.Name,
+		CreationTimestamp: meta.CreationTimestamp,
+	}
+	ddConfig, err := resources.DDConfigFromPodTemplate(template)
+	if err != nil {
+		w.Error = err.Error()
+		return w, true
+	}
+	w.Config = ddConfig
+	for _, c := range template.Spec.Containers {
+		if c.Name == ddConfig.DebugContainerName {
+			w.Image = c.Image
+		}
+	}
+	return w, true
+}
@@ -0,0 +1,156 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHelper_ListDebugWorkloads(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"dev.local/dd-added": "true",
+				"dev.local/dd-apply": `{"containerToDebug":"app","debugContainerName":"debug","tmpdirAdded":true,"secretMount":"dd-monitor-apikey-abcde"}`,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "app:latest"},
+				{Name: "debug", Image: "mcr.microsoft.com/dotnet/monitor:8"},
+			},
+		},
+	}
+	replicas := int32(2)
+	c := testclient.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-sidecar", Namespace: "a"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Template: template},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "without-sidecar", Namespace: "a"},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "with-sidecar", Namespace: "b"},
+			Spec:       appsv1.DaemonSetSpec{Template: template},
+			Status:     appsv1.DaemonSetStatus{NumberReady: 3, DesiredNumberScheduled: 3},
+		},
+	)
+	h := &Helper{
+		Client: c,
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		want      []string
+	}{
+		{
+			name:      "Lists workloads with debug sidecar in namespace",
+			namespace: "a",
+			want:      []string{"a/Deployment/with-sidecar"},
+		},
+		{
+			name:      "Lists workloads with debug sidecar in all namespaces",
+			namespace: "",
+			want:      []string{"a/Deployment/with-sidecar", "b/DaemonSet/with-sidecar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.ListDebugWorkloads(context.Background(), tt.namespace)
+			if err != nil {
+				t.Errorf("Helper.ListDebugWorkloads() error = %v", err)
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Helper.ListDebugWorkloads() returned %d workloads, want %d", len(got), len(tt.want))
+				return
+			}
+			for i, w := range got {
+				if id := w.Namespace + "/" + w.Kind + "/" + w.Name; id != tt.want[i] {
+					t.Errorf("Helper.ListDebugWorkloads()[%d] = %s, want %s", i, id, tt.want[i])
+				}
+				if w.Image != "mcr.microsoft.com/dotnet/monitor:8" {
+					t.Errorf("Unexpected debug image %s", w.Image)
+				}
+				if w.Config.SecretName != "dd-monitor-apikey-abcde" {
+					t.Errorf("Unexpected secret name %s", w.Config.SecretName)
+				}
+			}
+			if got[0].ReadyPods != 1 || got[0].DesiredPods != 2 {
+				t.Errorf("Unexpected readiness %d/%d, want 1/2", got[0].ReadyPods, got[0].DesiredPods)
+			}
+		})
+	}
+}
+
+func TestHelper_ListDebugWorkloads_Unparseable(t *testing.T) {
+	c := testclient.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "newer-schema", Namespace: "a"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"dev.local/dd-added": "true",
+						"dev.local/dd-apply": `{"schemaVersion":99,"containerToDebug":"app"}`,
+					},
+				},
+			}},
+		},
+	)
+	h := &Helper{
+		Client: c,
+	}
+	got, err := h.ListDebugWorkloads(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("Helper.ListDebugWorkloads() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Helper.ListDebugWorkloads() returned %d workloads, want 1", len(got))
+	}
+	if !got[0].Unparseable() || !strings.Contains(got[0].Error, "schema version 99") {
+		t.Errorf("Helper.ListDebugWorkloads()[0].Error = %q, want the schema version error", got[0].Error)
+	}
+}
+
+func TestDebugWorkload_AddedAt(t *testing.T) {
+	created := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	added := metav1.NewTime(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name  string
+		audit *resources.AuditInfo
+		want  time.Time
+	}{
+		{
+			name:  "Audit time when present",
+			audit: &resources.AuditInfo{User: "alice", Time: added},
+			want:  added.Time,
+		},
+		{
+			name: "Creation time without audit info",
+			want: created.Time,
+		},
+		{
+			name:  "Creation time without audit time",
+			audit: &resources.AuditInfo{User: "alice"},
+			want:  created.Time,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := DebugWorkload{CreationTimestamp: created, Config: resources.DDConfig{Audit: tt.audit}}
+			if got := w.AddedAt(); !got.Equal(tt.want) {
+				t.Errorf("DebugWorkload.AddedAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+// Verbosity levels of the debug logs, following kubectl where -v=6 logs the http requests to the api server
+const (
+	// LogLevelChanges logs the resources created, patched and deleted
+	LogLevelChanges = 4
+	// LogLevelDetails logs the patches sent and the port-forward connections
+	LogLevelDetails = 5
+)
+
+// logWriter writes each line written to it as a log message, for the messages of the port forwarder
+type logWriter struct {
+	logger logr.Logger
+	// err logs the lines as errors instead of info messages
+	err bool
+	buf bytes.Buffer
+}
+
+func newLogWriter(ctx context.Context, level int, keysAndValues ...any) *logWriter {
+	return &logWriter{logger: klog.FromContext(ctx).WithValues(keysAndValues...).V(level)}
+}
+
+func newErrorLogWriter(ctx context.Context, keysAndValues ...any) *logWriter {
+	return &logWriter{logger: klog.FromContext(ctx).WithValues(keysAndValues...), err: true}
+}
+
+// Write logs the complete lines of p, keeping the rest until the line is completed by the next write
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Incomplete line, put it back
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			return len(p), nil
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if w.err {
+			w.logger.Error(nil, line)
+		} else {
+			w.logger.Info(line)
+		}
+	}
+}
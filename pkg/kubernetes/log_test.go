@@ -0,0 +1,34 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"k8s.io/klog/v2"
+)
+
+func Test_logWriter(t *testing.T) {
+	var got []string
+	logger := funcr.New(func(prefix, args string) {
+		got = append(got, args)
+	}, funcr.Options{Verbosity: LogLevelDetails})
+	ctx := klog.NewContext(context.Background(), logger)
+
+	w := newLogWriter(ctx, LogLevelDetails, "pod", "test")
+	fmt.Fprint(w, "Forwarding from 127.0.0.1:52323 -> 52323\nHandling ")
+	fmt.Fprint(w, "connection for 52323\n\n")
+	newLogWriter(ctx, LogLevelDetails+1).Write([]byte("Not logged\n"))
+	newErrorLogWriter(ctx, "pod", "test").Write([]byte("lost connection to pod\n"))
+
+	want := []string{
+		`"level"=5 "msg"="Forwarding from 127.0.0.1:52323 -> 52323" "pod"="test"`,
+		`"level"=5 "msg"="Handling connection for 52323" "pod"="test"`,
+		`"msg"="lost connection to pod" "error"=null "pod"="test"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logWriter logged %q, want %q", got, want)
+	}
+}
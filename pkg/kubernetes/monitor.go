@@ -0,0 +1,43 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// monitorStatusError is returned when dotnet-monitor responds with another status than 200 OK
+type monitorStatusError struct {
+	Path       string
+	Status     string
+	StatusCode int
+	Body       string
+}
+
+func (e *monitorStatusError) Error() string {
+	return fmt.Sprintf("dotnet-monitor returned %s for %s: %s", e.Status, e.Path, e.Body)
+}
+
+// monitorGet queries the dotnet-monitor api and decodes the json response into out
+func monitorGet(ctx context.Context, baseURL, token, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &monitorStatusError{Path: path, Status: resp.Status, StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
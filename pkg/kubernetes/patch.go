@@ -0,0 +1,116 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// FieldManager is the field manager of the changes made by the cli
+const FieldManager = "dmsctl"
+
+// patchDeployment reads a deployment, changes it with mutate and patches the changes, reading it again and retrying on conflicts.
+// The changed deployment is returned without patching it in client dry run
+func (h *Helper) patchDeployment(ctx context.Context, namespace, name, dryRun string, mutate func(d *appsv1.Deployment) error) (*appsv1.Deployment, error) {
+	deployments := h.Client.AppsV1().Deployments(namespace)
+	return patchWorkload(ctx, "Deployment", namespace, name, dryRun, deployments.Get, deployments.Patch, mutate)
+}
+
+// patchDaemonSet reads a daemonset, changes it with mutate and patches the changes, reading it again and retrying on conflicts.
+// The changed daemonset is returned without patching it in client dry run
+func (h *Helper) patchDaemonSet(ctx context.Context, namespace, name, dryRun string, mutate func(d *appsv1.DaemonSet) error) (*appsv1.DaemonSet, error) {
+	daemonsets := h.Client.AppsV1().DaemonSets(namespace)
+	return patchWorkload(ctx, "DaemonSet", namespace, name, dryRun, daemonsets.Get, daemonsets.Patch, mutate)
+}
+
+// patchWorkload reads a workload with get, changes it with mutate and patches the changes with patch, reading it again and retrying on conflicts.
+// The changed workload is returned without patching it in client dry run
+func patchWorkload[W interface {
+	metav1.Object
+	DeepCopy() W
+}](
+	ctx context.Context, kind, namespace, name, dryRun string,
+	get func(ctx context.Context, name string, opts metav1.GetOptions) (W, error),
+	patch func(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (W, error),
+	mutate func(w W) error,
+) (W, error) {
+	var result W
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		w, err := get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		logger := klog.FromContext(ctx).WithValues("kind", kind, "namespace", namespace, "name", name, "resourceVersion", w.GetResourceVersion())
+		modified := w.DeepCopy()
+		err = mutate(modified)
+		if err != nil {
+			return err
+		}
+		if dryRun == resources.DryRunClient {
+			logger.V(LogLevelChanges).Info("Changed pod template, not patched in client dry run")
+			result = modified
+			return nil
+		}
+		data, err := workloadPatch(w, modified)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			logger.V(LogLevelChanges).Info("Pod template unchanged, skipping patch")
+			result = w
+			return nil
+		}
+		logger.V(LogLevelChanges).Info("Patching pod template", "dryRun", dryRun)
+		logger.V(LogLevelDetails).Info("JSON patch", "patch", string(data))
+		result, err = patch(ctx, name, types.JSONPatchType, data, patchOptions(dryRun))
+		if isTestFailed(err) {
+			// Retried like a conflict, reading the workload again
+			err = apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: workloadResource(kind)}, name, err)
+		}
+		if apierrors.IsConflict(err) {
+			logger.V(LogLevelChanges).Info(kind + " changed since it was read, retrying")
+		}
+		return err
+	})
+	return result, err
+}
+
+// workloadPatch returns a JSON patch changing a workload as read into modified, or nil if it is unchanged. The patch tests
+// the lists it changes, like the containers and volumes, are as read, so the api server rejects it if others changed them
+// since. Changes made by others elsewhere in the workload, like scaling it or updating its status, do not fail the patch
+func workloadPatch(read, modified metav1.Object) ([]byte, error) {
+	from, err := json.Marshal(read)
+	if err != nil {
+		return nil, err
+	}
+	to, err := json.Marshal(modified)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := resources.JSONPatch(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, nil
+	}
+	tests, err := resources.JSONPatchTests(from, ops)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(append(tests, ops...))
+}
+
+// isTestFailed returns true if a JSON patch was rejected by one of its test operations
+func isTestFailed(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "testing value")
+}
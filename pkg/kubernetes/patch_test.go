@@ -0,0 +1,209 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// conflictReactor fails the first conflicts patches of resource with a conflict, scaling the workload
+// in between like a HorizontalPodAutoscaler, and counts the patches sent
+func conflictReactor(c *testclient.Clientset, resource string, conflicts int, patches *int) clienttesting.ReactionFunc {
+	return func(action clienttesting.Action) (bool, runtime.Object, error) {
+		*patches++
+		if *patches > conflicts {
+			return false, nil, nil
+		}
+		gvr := appsv1.SchemeGroupVersion.WithResource(resource)
+		obj, err := c.Tracker().Get(gvr, action.GetNamespace(), action.(clienttesting.PatchAction).GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		switch w := obj.(type) {
+		case *appsv1.Deployment:
+			replicas := int32(*patches + 10)
+			w.Spec.Replicas = &replicas
+		case *appsv1.DaemonSet:
+			w.Spec.MinReadySeconds = int32(*patches + 10)
+		}
+		err = c.Tracker().Update(gvr, obj, action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: resource}, "test", nil)
+	}
+}
+
+func TestHelper_AddDebugSidecarDeployment_Conflict(t *testing.T) {
+	tests := []struct {
+		name        string
+		conflicts   int
+		wantPatches int
+		wantErr     bool
+	}{
+		{
+			name:      "Retries patch on conflict with the workload read again",
+			conflicts: 2,
+			// The third patch adds the sidecar, the fourth records the pod template written for remove --restore-exact
+			wantPatches: 4,
+		},
+		{
+			name:        "Gives up after repeated conflicts and removes the secret",
+			conflicts:   100,
+			wantPatches: 5,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var d appsv1.Deployment
+			err := getObjectFromFile("testdata/deployment/add.yaml", &d)
+			if err != nil {
+				t.Errorf("getObjectFromFile() error = %v", err)
+				return
+			}
+			c := testclient.NewSimpleClientset(&d)
+			patches := 0
+			c.PrependReactor("patch", "deployments", conflictReactor(c, "deployments", tt.conflicts, &patches))
+			h := &Helper{
+				Client:  c,
+				Dynamic: newFakeDynamicClient(),
+			}
+			got, _, err := h.AddDebugSidecarDeployment(ctx, "test", "test", "test", "test", resources.SidecarOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Helper.AddDebugSidecarDeployment() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if patches != tt.wantPatches {
+				t.Errorf("Helper.AddDebugSidecarDeployment() sent %d patches, want %d", patches, tt.wantPatches)
+			}
+			if tt.wantErr {
+				if !apierrors.IsConflict(err) {
+					t.Errorf("Helper.AddDebugSidecarDeployment() error = %v, want conflict", err)
+				}
+				secrets, _ := c.CoreV1().Secrets("test").List(ctx, metav1.ListOptions{})
+				if len(secrets.Items) != 0 {
+					t.Errorf("Helper.AddDebugSidecarDeployment() left %d secrets after failing", len(secrets.Items))
+				}
+				return
+			}
+			if !resources.HasDebugSidecar(got.Spec.Template.Annotations) {
+				t.Errorf("Helper.AddDebugSidecarDeployment() returned deployment without debug sidecar")
+			}
+			if got.Spec.Replicas == nil || *got.Spec.Replicas != int32(tt.conflicts+10) {
+				t.Errorf("Helper.AddDebugSidecarDeployment() clobbered replicas scaled concurrently, got %v", got.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestHelper_RemoveDebugSidecarDaemonSet_Conflict(t *testing.T) {
+	ctx := context.Background()
+	var d appsv1.DaemonSet
+	err := getObjectFromFile("testdata/daemonset/remove.yaml", &d)
+	if err != nil {
+		t.Errorf("getObjectFromFile() error = %v", err)
+		return
+	}
+	ddConfig, err := resources.DDConfigFromPodTemplate(d.Spec.Template)
+	if err != nil {
+		t.Errorf("DDConfigFromPodTemplate() error = %v", err)
+		return
+	}
+	c := testclient.NewSimpleClientset(&d, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: ddConfig.SecretName, Namespace: d.Namespace}})
+	patches := 0
+	c.PrependReactor("patch", "daemonsets", conflictReactor(c, "daemonsets", 1, &patches))
+	h := &Helper{
+		Client:  c,
+		Dynamic: newFakeDynamicClient(),
+	}
+	got, _, err := h.RemoveDebugSidecarDaemonSet(ctx, d.Namespace, d.Name, resources.RemoveOptions{})
+	if err != nil {
+		t.Errorf("Helper.RemoveDebugSidecarDaemonSet() error = %v", err)
+		return
+	}
+	if patches != 2 {
+		t.Errorf("Helper.RemoveDebugSidecarDaemonSet() sent %d patches, want 2", patches)
+	}
+	if resources.HasDebugSidecar(got.Spec.Template.Annotations) {
+		t.Errorf("Helper.RemoveDebugSidecarDaemonSet() returned daemonset with debug sidecar")
+	}
+	if got.Spec.MinReadySeconds != 11 {
+		t.Errorf("Helper.RemoveDebugSidecarDaemonSet() clobbered concurrent change, minReadySeconds = %d", got.Spec.MinReadySeconds)
+	}
+}
+
+func TestWorkloadPatch(t *testing.T) {
+	ctx := context.Background()
+	read := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", ResourceVersion: "42"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "app:1"}},
+		}}},
+	}
+	modified := read.DeepCopy()
+	modified.Spec.Template.Annotations = map[string]string{"dev.local/dd-added": "true"}
+	modified.Spec.Template.Spec.Containers = append(modified.Spec.Template.Spec.Containers, corev1.Container{Name: "debug"})
+
+	patch, err := workloadPatch(read, modified)
+	if err != nil {
+		t.Fatalf("workloadPatch() error = %v", err)
+	}
+	var ops []resources.JSONPatchOperation
+	err = json.Unmarshal(patch, &ops)
+	if err != nil {
+		t.Fatalf("failed to parse patch %s: %v", patch, err)
+	}
+	if len(ops) != 3 || ops[0].Op != "test" || ops[0].Path != "/spec/template/spec/containers" {
+		t.Errorf("workloadPatch() = %s, want patch testing the containers first", patch)
+	}
+	for _, op := range ops {
+		if op.Path == "/metadata/resourceVersion" {
+			t.Errorf("workloadPatch() = %s, want patch without the resource version", patch)
+		}
+	}
+
+	deployments := testclient.NewSimpleClientset(read.DeepCopy()).AppsV1().Deployments("test")
+	scaled := read.DeepCopy()
+	replicas := int32(3)
+	scaled.Spec.Replicas = &replicas
+	_, err = deployments.Update(ctx, scaled, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := deployments.Patch(ctx, "test", types.JSONPatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		t.Errorf("Patch() error = %v after the deployment was scaled, want the patch to apply", err)
+	} else if len(got.Spec.Template.Spec.Containers) != 2 || *got.Spec.Replicas != 3 {
+		t.Errorf("Patch() = %d containers and %d replicas, want 2 containers and the 3 replicas scaled to", len(got.Spec.Template.Spec.Containers), *got.Spec.Replicas)
+	}
+
+	deployments = testclient.NewSimpleClientset(read.DeepCopy()).AppsV1().Deployments("test")
+	changed := read.DeepCopy()
+	changed.Spec.Template.Spec.Containers[0].Image = "app:2"
+	_, err = deployments.Update(ctx, changed, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	_, err = deployments.Patch(ctx, "test", types.JSONPatchType, patch, metav1.PatchOptions{})
+	if !isTestFailed(err) {
+		t.Errorf("Patch() error = %v after the containers changed, want failed test", err)
+	}
+
+	patch, err = workloadPatch(read, read.DeepCopy())
+	if err != nil || patch != nil {
+		t.Errorf("workloadPatch() = %s, %v, want no patch for unchanged workload", patch, err)
+	}
+}
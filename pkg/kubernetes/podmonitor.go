@@ -0,0 +1,33 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CreatePodMonitor creates a PodMonitor scraping the debug sidecar metrics endpoint of the pods matching selector
+func (h *Helper) CreatePodMonitor(ctx context.Context, namespace, name, owner string, selector *metav1.LabelSelector) error {
+	if h.Dynamic == nil {
+		return fmt.Errorf("dynamic client not configured, unable to create PodMonitor")
+	}
+	pm, err := resources.GeneratePodMonitor(namespace, name, owner, selector)
+	if err != nil {
+		return err
+	}
+	_, err = h.Dynamic.Resource(resources.PodMonitorResource).Namespace(namespace).Create(ctx, pm, metav1.CreateOptions{FieldManager: FieldManager})
+	return err
+}
+
+// RemovePodMonitor removes the PodMonitor
+func (h *Helper) RemovePodMonitor(ctx context.Context, namespace, name string) error {
+	if name == "" {
+		return nil
+	}
+	if h.Dynamic == nil {
+		return fmt.Errorf("dynamic client not configured, unable to remove PodMonitor %s", name)
+	}
+	return h.Dynamic.Resource(resources.PodMonitorResource).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// podSecurityModes lists the Pod Security admission modes and the consequence of violating them
+var podSecurityModes = []struct {
+	mode        string
+	consequence string
+}{
+	{mode: "enforce", consequence: "new pods will be rejected and the rollout will stall"},
+	{mode: "warn", consequence: "a warning will be returned when the workload is updated"},
+	{mode: "audit", consequence: "the violation will be recorded in the audit log"},
+}
+
+// PodSecurityWarnings returns warnings for the Pod Security admission levels of the namespace the sidecar security profile violates
+func (h *Helper) PodSecurityWarnings(ctx context.Context, namespace, profile string) ([]string, error) {
+	ns, err := h.Client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = resources.SecurityProfileLegacy
+	}
+	var warnings []string
+	for _, m := range podSecurityModes {
+		level := ns.Labels[fmt.Sprintf("pod-security.kubernetes.io/%s", m.mode)]
+		if level == "" || resources.SecurityProfileSatisfies(profile, level) {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("namespace %s %ss the %s Pod Security Standard, which the %s security profile violates: %s", namespace, m.mode, level, profile, m.consequence))
+	}
+	return warnings, nil
+}
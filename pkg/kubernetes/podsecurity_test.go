@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHelper_PodSecurityWarnings(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		profile string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "No Pod Security labels",
+			labels:  map[string]string{},
+			profile: resources.SecurityProfileLegacy,
+			want:    nil,
+		},
+		{
+			name: "Legacy profile in restricted namespace",
+			labels: map[string]string{
+				"pod-security.kubernetes.io/enforce": "restricted",
+			},
+			profile: "",
+			want: []string{
+				"namespace test enforces the restricted Pod Security Standard, which the legacy security profile violates: new pods will be rejected and the rollout will stall",
+			},
+		},
+		{
+			name: "Baseline profile in namespace enforcing baseline and warning restricted",
+			labels: map[string]string{
+				"pod-security.kubernetes.io/enforce": "baseline",
+				"pod-security.kubernetes.io/warn":    "restricted",
+			},
+			profile: resources.SecurityProfileBaseline,
+			want: []string{
+				"namespace test warns the restricted Pod Security Standard, which the baseline security profile violates: a warning will be returned when the workload is updated",
+			},
+		},
+		{
+			name: "Restricted profile in restricted namespace",
+			labels: map[string]string{
+				"pod-security.kubernetes.io/enforce": "restricted",
+				"pod-security.kubernetes.io/audit":   "restricted",
+			},
+			profile: resources.SecurityProfileRestricted,
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testclient.NewSimpleClientset(&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "test",
+					Labels: tt.labels,
+				},
+			})
+			h := &Helper{
+				Client: c,
+			}
+			got, err := h.PodSecurityWarnings(context.Background(), "test", tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Helper.PodSecurityWarnings() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Helper.PodSecurityWarnings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
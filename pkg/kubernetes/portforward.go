@@ -8,40 +8,21 @@ import (
 	"os"
 	"os/signal"
 
-	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/transport/spdy"
-	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/klog/v2"
 )
 
-// PortForward runs port-forward to the given pod and waits for Interrupt
-func (h *Helper) PortForward(ctx context.Context, namespace string, podname string) error {
-	pod, err := h.Client.CoreV1().Pods(namespace).Get(ctx, podname, metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-
-	if pod.Status.Phase != corev1.PodRunning {
-		return fmt.Errorf("unable to forward port because pod is not running. Current status=%v", pod.Status.Phase)
-	}
-	_, err = h.GetDDPodApplyInfo(ctx, namespace, podname)
-	if err != nil {
-		if errors.IsNotPresent(err) {
-			return fmt.Errorf("debug sidecar not attached to pod %s", podname)
-		}
-	}
-
-	f, config, err := getRestSetup()
-	if err != nil {
-		return err
-	}
+// monitorPort is the port dotnet-monitor listens on in the debug sidecar
+const monitorPort = "52323"
 
-	restClient, err := f.RESTClient()
+// PortForward runs port-forward to the given pod and waits for Interrupt. The messages of the port forwarder are logged,
+// and ready is called with the local url of dotnet-monitor once the port is forwarded, if set
+func (h *Helper) PortForward(ctx context.Context, namespace string, podname string, ready func(localURL string)) error {
+	req, config, err := h.portForwardRequest(ctx, namespace, podname)
 	if err != nil {
 		return err
 	}
@@ -59,34 +40,95 @@ func (h *Helper) PortForward(ctx context.Context, namespace string, podname stri
 		}
 	}()
 
-	req := restClient.Post().
-		Resource("pods").
-		Namespace(pod.Namespace).
-		Name(pod.Name).
-		SubResource("portforward")
-
-	return forwardPorts("POST", req.URL(), config, stopCh, readyCh)
-
+	fw, err := newPortForwarder(ctx, req.URL(), config, []string{fmt.Sprintf("%s:%s", monitorPort, monitorPort)}, stopCh, readyCh, "namespace", namespace, "pod", podname)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-readyCh:
+			if ready != nil {
+				ready(fmt.Sprintf("http://localhost:%s", monitorPort))
+			}
+		case <-done:
+		}
+	}()
+	return fw.ForwardPorts()
 }
 
-func getRestSetup() (cmdutil.Factory, *rest.Config, error) {
-	kubeConfigFlags := genericclioptions.NewConfigFlags(true)
-	matchVersionKubeConfigFlags := cmdutil.NewMatchVersionFlags(kubeConfigFlags)
-	f := cmdutil.NewFactory(matchVersionKubeConfigFlags)
-	config, err := f.ToRESTConfig()
-	config.GroupVersion = &schema.GroupVersion{Group: "", Version: "v1"}
-	return f, config, err
-}
+// WithTunnel forwards a random local port to dotnet-monitor in the given pod and calls fn with the local base url.
+// The tunnel is closed when fn returns.
+func (h *Helper) WithTunnel(ctx context.Context, namespace, podname string, fn func(baseURL string) error) error {
+	req, config, err := h.portForwardRequest(ctx, namespace, podname)
+	if err != nil {
+		return err
+	}
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	defer close(stopCh)
 
-func forwardPorts(method string, url *url.URL, config *rest.Config, stop, ready chan struct{}) error {
-	transport, upgrader, err := spdy.RoundTripperFor(config)
+	fw, err := newPortForwarder(ctx, req.URL(), config, []string{fmt.Sprintf("0:%s", monitorPort)}, stopCh, readyCh, "namespace", namespace, "pod", podname)
 	if err != nil {
 		return err
 	}
-	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, method, url)
-	fw, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, []string{"52323:52323"}, stop, ready, os.Stdout, os.Stderr)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+	select {
+	case <-readyCh:
+	case err = <-errCh:
+		return fmt.Errorf("failed to forward port to pod %s: %v", podname, err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	ports, err := fw.GetPorts()
 	if err != nil {
 		return err
 	}
-	return fw.ForwardPorts()
+	if len(ports) == 0 {
+		return fmt.Errorf("failed to forward port to pod %s: no local port allocated", podname)
+	}
+	klog.FromContext(ctx).V(LogLevelDetails).Info("Opened tunnel to dotnet-monitor", "namespace", namespace, "pod", podname, "localPort", ports[0].Local)
+	defer klog.FromContext(ctx).V(LogLevelDetails).Info("Closing tunnel to dotnet-monitor", "namespace", namespace, "pod", podname)
+	return fn(fmt.Sprintf("http://localhost:%d", ports[0].Local))
+}
+
+func (h *Helper) portForwardRequest(ctx context.Context, namespace, podname string) (*rest.Request, *rest.Config, error) {
+	pod, err := h.Client.CoreV1().Pods(namespace).Get(ctx, podname, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil, nil, fmt.Errorf("unable to forward port because pod is not running. Current status=%v", pod.Status.Phase)
+	}
+	_, err = h.GetDDPodApplyInfo(ctx, namespace, podname)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if h.Config == nil {
+		return nil, nil, fmt.Errorf("unable to forward port to pod %s without a REST config", podname)
+	}
+	req := h.Client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("portforward")
+	return req, h.Config, nil
+}
+
+// newPortForwarder returns a port forwarder logging its messages, like the connections handled, with keysAndValues
+func newPortForwarder(ctx context.Context, url *url.URL, config *rest.Config, ports []string, stop, ready chan struct{}, keysAndValues ...any) (*portforward.PortForwarder, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+	out := newLogWriter(ctx, LogLevelDetails, keysAndValues...)
+	errOut := newErrorLogWriter(ctx, keysAndValues...)
+	return portforward.NewOnAddresses(dialer, []string{"localhost"}, ports, stop, ready, out, errOut)
 }
@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ReapExpiredSidecars removes the debug sidecars which expired before now, with their secrets,
+// from the workloads in a namespace or all namespaces if namespace is empty.
+// An event is recorded on each reaped workload. Failing to remove one sidecar does not stop the others from being removed.
+// Workloads whose DDConfig is unparseable are skipped, with a warning for each of them
+func (h *Helper) ReapExpiredSidecars(ctx context.Context, namespace string, now time.Time, audit resources.AuditInfo) ([]DebugWorkload, []string, error) {
+	workloads, err := h.ListDebugWorkloads(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reaped []DebugWorkload
+	var warnings []string
+	var errs []error
+	for _, w := range workloads {
+		if w.Unparseable() {
+			warnings = append(warnings, fmt.Sprintf("skipping %s %s/%s, its debug sidecar config is unparseable: %s", w.Kind, w.Namespace, w.Name, w.Error))
+			continue
+		}
+		if !w.Config.Expired(now) {
+			continue
+		}
+		var workload metav1.Object
+		switch w.Kind {
+		case "Deployment":
+			workload, _, err = h.RemoveDebugSidecarDeployment(ctx, w.Namespace, w.Name, resources.RemoveOptions{})
+		case "DaemonSet":
+			workload, _, err = h.RemoveDebugSidecarDaemonSet(ctx, w.Namespace, w.Name, resources.RemoveOptions{})
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove expired sidecar from %s %s/%s: %w", w.Kind, w.Namespace, w.Name, err))
+			continue
+		}
+		reaped = append(reaped, w)
+		message := AuditMessage(fmt.Sprintf("Expired debug sidecar removed, expired at %s", w.Config.ExpiresAt.Format(time.RFC3339)), audit)
+		err = h.RecordWorkloadEvent(ctx, w.Kind, workload, EventReasonSidecarExpired, message)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to record event on %s %s/%s: %v", w.Kind, w.Namespace, w.Name, err))
+		}
+	}
+	return reaped, warnings, utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,148 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHelper_ReapExpiredSidecars(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	template := func(expiresAt string) corev1.PodTemplateSpec {
+		ddConfig := `{"containerToDebug":"app","debugContainerName":"debug","tmpdirAdded":true,"secretMount":"dd-monitor-apikey-abcde"`
+		if expiresAt != "" {
+			ddConfig += `,"expiresAt":"` + expiresAt + `"`
+		}
+		return corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"dev.local/dd-added": "true",
+					"dev.local/dd-apply": ddConfig + "}",
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "app", Image: "app:latest", VolumeMounts: []corev1.VolumeMount{{Name: "tmp", MountPath: "/tmp"}}},
+					{Name: "debug", Image: "mcr.microsoft.com/dotnet/monitor:8"},
+				},
+				Volumes: []corev1.Volume{{Name: "tmp"}, {Name: "dd-monitor-apikey-abcde"}},
+			},
+		}
+	}
+	secret := func(namespace string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dd-monitor-apikey-abcde",
+				Namespace: namespace,
+				Labels:    map[string]string{resources.SecretLabel(): "expired"},
+			},
+		}
+	}
+	newClient := func() *testclient.Clientset {
+		return testclient.NewSimpleClientset(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "expired", Namespace: "a"},
+				Spec:       appsv1.DeploymentSpec{Template: template("2024-01-01T11:00:00Z")},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "not-expired", Namespace: "a"},
+				Spec:       appsv1.DeploymentSpec{Template: template("2024-01-01T16:00:00Z")},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "no-expiry", Namespace: "a"},
+				Spec:       appsv1.DeploymentSpec{Template: template("")},
+			},
+			&appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "expired", Namespace: "b"},
+				Spec:       appsv1.DaemonSetSpec{Template: template("2024-01-01T11:00:00Z")},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "newer-schema", Namespace: "c"},
+				Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							"dev.local/dd-added": "true",
+							"dev.local/dd-apply": `{"schemaVersion":99,"expiresAt":"2024-01-01T11:00:00Z"}`,
+						},
+					},
+				}},
+			},
+			secret("a"),
+			secret("b"),
+		)
+	}
+	tests := []struct {
+		name      string
+		namespace string
+		want      []string
+		warnings  int
+	}{
+		{
+			name:      "Reaps expired sidecars in namespace",
+			namespace: "a",
+			want:      []string{"a/Deployment/expired"},
+		},
+		{
+			name:      "Reaps expired sidecars in all namespaces",
+			namespace: "",
+			want:      []string{"a/Deployment/expired", "b/DaemonSet/expired"},
+			warnings:  1,
+		},
+		{
+			name:      "Skips sidecars with unparseable config",
+			namespace: "c",
+			warnings:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient()
+			h := &Helper{
+				Client: c,
+			}
+			got, warnings, err := h.ReapExpiredSidecars(context.Background(), tt.namespace, now, resources.AuditInfo{User: "reaper"})
+			if err != nil {
+				t.Errorf("Helper.ReapExpiredSidecars() error = %v", err)
+				return
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("Helper.ReapExpiredSidecars() returned warnings %v, want %d", warnings, tt.warnings)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Helper.ReapExpiredSidecars() reaped %d workloads, want %d", len(got), len(tt.want))
+				return
+			}
+			for i, w := range got {
+				if id := w.Namespace + "/" + w.Kind + "/" + w.Name; id != tt.want[i] {
+					t.Errorf("Helper.ReapExpiredSidecars()[%d] = %s, want %s", i, id, tt.want[i])
+				}
+			}
+			remaining, err := h.ListDebugWorkloads(context.Background(), tt.namespace)
+			if err != nil {
+				t.Errorf("Helper.ListDebugWorkloads() error = %v", err)
+				return
+			}
+			for _, w := range remaining {
+				if w.Config.Expired(now) {
+					t.Errorf("Expired sidecar still attached to %s %s/%s", w.Kind, w.Namespace, w.Name)
+				}
+			}
+			for _, w := range got {
+				_, err := c.CoreV1().Secrets(w.Namespace).Get(context.Background(), w.Config.SecretName, metav1.GetOptions{})
+				if err == nil {
+					t.Errorf("Secret %s/%s of expired sidecar not deleted", w.Namespace, w.Config.SecretName)
+				}
+			}
+			events, _ := c.CoreV1().Events("").List(context.Background(), metav1.ListOptions{})
+			if len(events.Items) != len(tt.want) {
+				t.Errorf("%d events recorded, want %d", len(events.Items), len(tt.want))
+			}
+		})
+	}
+}
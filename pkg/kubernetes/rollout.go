@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// deploymentProgressDeadlineExceeded is the reason of the Progressing condition of a Deployment not progressing within its progress deadline
+const deploymentProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+// WaitForRollout watches the rollout of a Deployment or DaemonSet, like kubectl rollout status, until it is done, fails or times out.
+// Progress is called with a message describing the rollout status each time it changes
+func (h *Helper) WaitForRollout(ctx context.Context, kind, namespace, name string, timeout time.Duration, progress func(status string)) error {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+	var (
+		objType runtime.Object
+		lw      *cache.ListWatch
+	)
+	switch kind {
+	case "Deployment":
+		objType = &appsv1.Deployment{}
+		lw = &cache.ListWatch{
+			ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = fieldSelector
+				return h.Client.AppsV1().Deployments(namespace).List(ctx, options)
+			},
+			WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = fieldSelector
+				return h.Client.AppsV1().Deployments(namespace).Watch(ctx, options)
+			},
+		}
+	case "DaemonSet":
+		objType = &appsv1.DaemonSet{}
+		lw = &cache.ListWatch{
+			ListWithContextFunc: func(ctx context.Context, options metav1.ListOptions) (runtime.Object, error) {
+				options.FieldSelector = fieldSelector
+				return h.Client.AppsV1().DaemonSets(namespace).List(ctx, options)
+			},
+			WatchFuncWithContext: func(ctx context.Context, options metav1.ListOptions) (watch.Interface, error) {
+				options.FieldSelector = fieldSelector
+				return h.Client.AppsV1().DaemonSets(namespace).Watch(ctx, options)
+			},
+		}
+	default:
+		return fmt.Errorf("unsupported workload kind %s, supported kinds are Deployment and DaemonSet", kind)
+	}
+
+	ctx, cancel := watchtools.ContextWithOptionalTimeout(ctx, timeout)
+	defer cancel()
+	last := ""
+	_, err := watchtools.UntilWithSync(ctx, lw, objType, nil, func(e watch.Event) (bool, error) {
+		if e.Type == watch.Deleted {
+			return false, fmt.Errorf("%s %s was deleted during the rollout", kind, name)
+		}
+		status, done, err := rolloutStatus(e.Object)
+		if err != nil {
+			return false, err
+		}
+		if status != last {
+			last = status
+			progress(status)
+		}
+		return done, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("%w after %s waiting for the rollout of %s %s: %s", dmserrors.ErrTimeout, timeout, kind, name, last)
+	}
+	return err
+}
+
+// rolloutStatus returns a message describing the rollout status of a workload, and if it is done.
+// The status follows kubectl rollout status, but a Deployment failing to create pods, like when rejected by
+// Pod Security admission, fails the rollout without waiting for its progress deadline
+func rolloutStatus(obj runtime.Object) (string, bool, error) {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		return deploymentRolloutStatus(w)
+	case *appsv1.DaemonSet:
+		return daemonSetRolloutStatus(w)
+	}
+	return "", false, fmt.Errorf("unsupported workload %T", obj)
+}
+
+func deploymentRolloutStatus(d *appsv1.Deployment) (string, bool, error) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return "Waiting for deployment spec update to be observed", false, nil
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentReplicaFailure && c.Status == corev1.ConditionTrue {
+			return "", false, fmt.Errorf("deployment %q failed to create pods: %s", d.Name, c.Message)
+		}
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == deploymentProgressDeadlineExceeded {
+			return "", false, fmt.Errorf("deployment %q exceeded its progress deadline", d.Name)
+		}
+	}
+	if d.Spec.Replicas != nil && d.Status.UpdatedReplicas < *d.Spec.Replicas {
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated", d.Name, d.Status.UpdatedReplicas, *d.Spec.Replicas), false, nil
+	}
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination", d.Name, d.Status.Replicas-d.Status.UpdatedReplicas), false, nil
+	}
+	if d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available", d.Name, d.Status.AvailableReplicas, d.Status.UpdatedReplicas), false, nil
+	}
+	return fmt.Sprintf("deployment %q successfully rolled out", d.Name), true, nil
+}
+
+func daemonSetRolloutStatus(d *appsv1.DaemonSet) (string, bool, error) {
+	if d.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		return "", false, fmt.Errorf("daemon set %q uses the %s update strategy, its pods are not updated until deleted", d.Name, appsv1.OnDeleteDaemonSetStrategyType)
+	}
+	if d.Generation > d.Status.ObservedGeneration {
+		return "Waiting for daemon set spec update to be observed", false, nil
+	}
+	if d.Status.UpdatedNumberScheduled < d.Status.DesiredNumberScheduled {
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d out of %d new pods have been updated", d.Name, d.Status.UpdatedNumberScheduled, d.Status.DesiredNumberScheduled), false, nil
+	}
+	if d.Status.NumberAvailable < d.Status.DesiredNumberScheduled {
+		return fmt.Sprintf("Waiting for daemon set %q rollout to finish: %d of %d updated pods are available", d.Name, d.Status.NumberAvailable, d.Status.DesiredNumberScheduled), false, nil
+	}
+	return fmt.Sprintf("daemon set %q successfully rolled out", d.Name), true, nil
+}
@@ -0,0 +1,167 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+)
+
+func testDeployment(status appsv1.DeploymentStatus) *appsv1.Deployment {
+	replicas := int32(2)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     status,
+	}
+}
+
+func testDaemonSet(strategy appsv1.DaemonSetUpdateStrategyType, status appsv1.DaemonSetStatus) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", Generation: 2},
+		Spec:       appsv1.DaemonSetSpec{UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: strategy}},
+		Status:     status,
+	}
+}
+
+var (
+	deploymentRolledOut = appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}
+	daemonSetRolledOut  = appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}
+)
+
+func TestHelper_WaitForRollout(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		obj     runtime.Object
+		timeout time.Duration
+		wantErr string
+	}{
+		{
+			name: "Rolled out deployment",
+			kind: "Deployment",
+			obj:  testDeployment(deploymentRolledOut),
+		},
+		{
+			name: "Deployment failing to create pods",
+			kind: "Deployment",
+			obj: testDeployment(appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           2,
+				UpdatedReplicas:    0,
+				Conditions: []appsv1.DeploymentCondition{{
+					Type:    appsv1.DeploymentReplicaFailure,
+					Status:  corev1.ConditionTrue,
+					Message: `pods "test" is forbidden: violates PodSecurity "restricted:latest"`,
+				}},
+			}),
+			wantErr: "failed to create pods",
+		},
+		{
+			name: "Deployment exceeding its progress deadline",
+			kind: "Deployment",
+			obj: testDeployment(appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           3,
+				UpdatedReplicas:    1,
+				Conditions: []appsv1.DeploymentCondition{{
+					Type:   appsv1.DeploymentProgressing,
+					Status: corev1.ConditionFalse,
+					Reason: deploymentProgressDeadlineExceeded,
+				}},
+			}),
+			wantErr: "progress deadline",
+		},
+		{
+			name:    "Deployment not rolled out within timeout",
+			kind:    "Deployment",
+			obj:     testDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1}),
+			timeout: 100 * time.Millisecond,
+			wantErr: "timed out",
+		},
+		{
+			name: "Rolled out daemonset",
+			kind: "DaemonSet",
+			obj:  testDaemonSet(appsv1.RollingUpdateDaemonSetStrategyType, daemonSetRolledOut),
+		},
+		{
+			name:    "Daemonset with OnDelete update strategy",
+			kind:    "DaemonSet",
+			obj:     testDaemonSet(appsv1.OnDeleteDaemonSetStrategyType, daemonSetRolledOut),
+			wantErr: "OnDelete",
+		},
+		{
+			name:    "Unsupported kind",
+			kind:    "StatefulSet",
+			obj:     testDeployment(deploymentRolledOut),
+			wantErr: "unsupported workload kind",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Helper{Client: testclient.NewSimpleClientset(tt.obj)}
+			var statuses []string
+			err := h.WaitForRollout(context.Background(), tt.kind, "test", "test", tt.timeout, func(status string) {
+				statuses = append(statuses, status)
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Helper.WaitForRollout() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Helper.WaitForRollout() error = %v", err)
+				return
+			}
+			if len(statuses) == 0 || !strings.Contains(statuses[len(statuses)-1], "successfully rolled out") {
+				t.Errorf("Helper.WaitForRollout() progress = %v, want rollout to finish", statuses)
+			}
+		})
+	}
+}
+
+func TestHelper_WaitForRollout_Progress(t *testing.T) {
+	ctx := context.Background()
+	d := testDeployment(appsv1.DeploymentStatus{ObservedGeneration: 1})
+	c := testclient.NewSimpleClientset(d)
+	h := &Helper{Client: c}
+
+	progressed := make(chan struct{}, 10)
+	done := make(chan error)
+	var statuses []string
+	go func() {
+		done <- h.WaitForRollout(ctx, "Deployment", "test", "test", 10*time.Second, func(status string) {
+			statuses = append(statuses, status)
+			progressed <- struct{}{}
+		})
+	}()
+
+	<-progressed
+	for _, status := range []appsv1.DeploymentStatus{
+		{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1},
+		{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+		deploymentRolledOut,
+	} {
+		d.Status = status
+		_, err := c.AppsV1().Deployments("test").UpdateStatus(ctx, d, metav1.UpdateOptions{})
+		if err != nil {
+			t.Fatalf("UpdateStatus() error = %v", err)
+		}
+		<-progressed
+	}
+
+	err := <-done
+	if err != nil {
+		t.Errorf("Helper.WaitForRollout() error = %v", err)
+	}
+	if len(statuses) != 4 {
+		t.Errorf("Helper.WaitForRollout() progress = %v, want 4 statuses", statuses)
+	}
+}
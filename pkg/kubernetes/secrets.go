@@ -2,43 +2,86 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
-	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/utils/jwx"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
 )
 
-// CreateJWKSecret creates a secret with a JWK public-key and subject
-func (h *Helper) CreateJWKSecret(ctx context.Context, namespace, owner string) (name string, token string, err error) {
+// CreateJWKSecret creates a secret with a JWK public-key and subject, or reuses the existing secret of the owner.
+// created is false if the secret is reused, and the token is empty as only the public key is stored
+func (h *Helper) CreateJWKSecret(ctx context.Context, namespace, owner string, ownerRefs ...metav1.OwnerReference) (name string, token string, created bool, err error) {
+	logger := klog.FromContext(ctx).WithValues("namespace", namespace, "owner", owner)
 	s, err := h.FetchJWKSecret(ctx, namespace, owner)
-	if err != nil && errors.IsNotFound(err) {
+	if dmserrors.IsNotFound(err) {
 		token, subject, key, err := jwx.CreateJWTKey()
-		s = resources.GenerateSecret(namespace, subject, key, owner)
-		_, err = h.Client.CoreV1().Secrets(namespace).Create(ctx, &s, metav1.CreateOptions{})
-		return s.Name, token, err
+		if err != nil {
+			return "", "", false, err
+		}
+		s = resources.GenerateSecret(namespace, subject, key, owner, ownerRefs...)
+		logger.V(LogLevelChanges).Info("Creating secret", "name", s.Name, "subject", subject)
+		_, err = h.Client.CoreV1().Secrets(namespace).Create(ctx, &s, metav1.CreateOptions{FieldManager: FieldManager})
+		if err != nil {
+			return "", "", false, err
+		}
+		return s.Name, token, true, nil
 	}
-	return s.Name, "Existing secret, token not available", err
+	if err != nil {
+		return "", "", false, err
+	}
+	logger.V(LogLevelChanges).Info("Reusing existing secret", "name", s.Name)
+	return s.Name, "", false, nil
+}
+
+// IssueJWKToken issues a new token for the debug sidecar of a Deployment or DaemonSet, replacing the key pair in its secret.
+// The sidecar reads the key from the secret mounted at /etc/dotnet-monitor, so the tokens issued before are rejected once the
+// kubelet updates the mounted secret in the running pods, without restarting them
+func (h *Helper) IssueJWKToken(ctx context.Context, namespace, kind, name string) (secretname, token string, err error) {
+	ddConfig, err := h.GetWorkloadDDConfig(ctx, namespace, kind, name)
+	if err != nil {
+		return "", "", err
+	}
+	token, subject, key, err := jwx.CreateJWTKey()
+	if err != nil {
+		return "", "", err
+	}
+	patch, err := json.Marshal(map[string]interface{}{"data": resources.GenerateSecret(namespace, subject, key, name).Data})
+	if err != nil {
+		return "", "", err
+	}
+	klog.FromContext(ctx).V(LogLevelChanges).Info("Replacing the key of secret", "namespace", namespace, "name", ddConfig.SecretName, "subject", subject)
+	_, err = h.Client.CoreV1().Secrets(namespace).Patch(ctx, ddConfig.SecretName, types.MergePatchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+	if err != nil {
+		return "", "", err
+	}
+	return ddConfig.SecretName, token, nil
 }
 
 // RemoveJWKSecret removes secret
 func (h *Helper) RemoveJWKSecret(ctx context.Context, namespace, secretname string) error {
+	klog.FromContext(ctx).V(LogLevelChanges).Info("Deleting secret", "namespace", namespace, "name", secretname)
 	return h.Client.CoreV1().Secrets(namespace).Delete(ctx, secretname, metav1.DeleteOptions{})
 }
 
 // FetchJWKSecret fetches secret from kubernetes based on owner
 func (h *Helper) FetchJWKSecret(ctx context.Context, namespace, owner string) (corev1.Secret, error) {
-	sl, err := h.Client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", resources.SecretLabel, owner)})
-	if err != nil {
-		return corev1.Secret{}, err
-	}
-	if len(sl.Items) == 0 {
-		return corev1.Secret{}, fmt.Errorf("resource not found")
-	}
-	if len(sl.Items) > 1 {
-		return corev1.Secret{}, fmt.Errorf("multiple resources found")
+	for _, label := range resources.SecretLabels() {
+		sl, err := h.Client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", label, owner)})
+		if err != nil {
+			return corev1.Secret{}, err
+		}
+		if len(sl.Items) > 1 {
+			return corev1.Secret{}, fmt.Errorf("multiple debug sidecar secrets found for %s", owner)
+		}
+		if len(sl.Items) == 1 {
+			return sl.Items[0], nil
+		}
 	}
-	return sl.Items[0], nil
+	return corev1.Secret{}, &dmserrors.WorkloadError{Name: owner, Err: dmserrors.ErrSecretNotFound}
 }
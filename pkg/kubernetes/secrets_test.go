@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -9,8 +10,12 @@ import (
 	"time"
 
 	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -265,11 +270,14 @@ func TestHelper_CreateJWKSecret(t *testing.T) {
 			h := &Helper{
 				Client: c,
 			}
-			secretName, token, err := h.CreateJWKSecret(tt.args.ctx, tt.args.namespace, tt.args.owner)
+			secretName, token, created, err := h.CreateJWKSecret(tt.args.ctx, tt.args.namespace, tt.args.owner)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Helper.CreateJWKSecret() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if !created {
+				t.Errorf("Helper.CreateJWKSecret() created = false, want true")
+			}
 			secretRegexp := regexp.MustCompile(fmt.Sprintf("%s[a-zA-Z0-9_.-]{5}", resources.SecretBaseName))
 			if !secretRegexp.MatchString(secretName) {
 				t.Errorf("Secretname %s did not match regex %s", secretName, secretRegexp)
@@ -314,3 +322,125 @@ func createFakeClientWithSecretWatcher(ctx context.Context, handlers *cache.Reso
 	<-watcherStarted
 	return c
 }
+
+func TestHelper_IssueJWKToken(t *testing.T) {
+	ctx := context.Background()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dd-monitor-apikey-abcde", Namespace: "test"},
+		Data:       map[string][]byte{resources.SubjectKey: []byte("old-subject")},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"dev.local/dd-added": "true",
+					"dev.local/dd-apply": `{"containerToDebug":"app","debugContainerName":"debug","secretMount":"dd-monitor-apikey-abcde"}`,
+				},
+			},
+		}},
+	}
+	h := &Helper{
+		Client: testclient.NewSimpleClientset(secret, deployment),
+	}
+	secretName, token, err := h.IssueJWKToken(ctx, "test", "deployment", "test")
+	if err != nil {
+		t.Fatalf("Helper.IssueJWKToken() error = %v", err)
+	}
+	if secretName != secret.Name {
+		t.Errorf("Helper.IssueJWKToken() secret = %s, want %s", secretName, secret.Name)
+	}
+	s, err := h.Client.CoreV1().Secrets("test").Get(ctx, secret.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get secret error = %v", err)
+	}
+	parsedToken, err := jwt.Parse([]byte(token), jwt.WithVerify(false))
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	err = jwt.Validate(parsedToken, jwt.WithSubject(string(s.Data[resources.SubjectKey])))
+	if err != nil {
+		t.Errorf("Token not valid for the subject %s in the secret: %v", s.Data[resources.SubjectKey], err)
+	}
+
+	_, _, err = h.IssueJWKToken(ctx, "test", "daemonset", "test")
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("Helper.IssueJWKToken() error = %v for missing daemonset, want not found", err)
+	}
+}
+
+func TestHelper_IssueJWKToken_SidecarReadsRotatedKey(t *testing.T) {
+	ctx := context.Background()
+	for _, image := range []string{"mcr.microsoft.com/dotnet/monitor:6", "mcr.microsoft.com/dotnet/monitor:7", "mcr.microsoft.com/dotnet/monitor:8"} {
+		t.Run(image, func(t *testing.T) {
+			template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+			template, err := resources.AddDebugContainerPodTemplate(template, "test", "app", image, "dd-monitor-apikey-abcde", resources.SidecarOptions{})
+			if err != nil {
+				t.Fatalf("AddDebugContainerPodTemplate() error = %v", err)
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "dd-monitor-apikey-abcde", Namespace: "test"},
+				Data:       map[string][]byte{resources.SubjectKey: []byte("old-subject")},
+			}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+				Spec:       appsv1.DeploymentSpec{Template: template},
+			}
+			h := &Helper{
+				Client: testclient.NewSimpleClientset(secret, deployment),
+			}
+			_, token, err := h.IssueJWKToken(ctx, "test", "deployment", "test")
+			if err != nil {
+				t.Fatalf("Helper.IssueJWKToken() error = %v", err)
+			}
+			s, err := h.Client.CoreV1().Secrets("test").Get(ctx, secret.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get secret error = %v", err)
+			}
+
+			// dotnet-monitor reads the api key from the files of /etc/dotnet-monitor, which the kubelet updates in running pods.
+			// Environment variables would override them with the values of the secret when the container started
+			config, err := resources.DDConfigFromPodTemplate(template)
+			if err != nil {
+				t.Fatalf("DDConfigFromPodTemplate() error = %v", err)
+			}
+			var debug corev1.Container
+			for _, c := range template.Spec.Containers {
+				if c.Name == config.DebugContainerName {
+					debug = c
+				}
+			}
+			for _, e := range debug.Env {
+				if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+					t.Errorf("debug container sets %s from key %s of secret %s when it starts", e.Name, e.ValueFrom.SecretKeyRef.Key, e.ValueFrom.SecretKeyRef.Name)
+				}
+			}
+			mounted := ""
+			for _, vm := range debug.VolumeMounts {
+				for _, v := range template.Spec.Volumes {
+					if vm.MountPath == "/etc/dotnet-monitor" && vm.Name == v.Name && v.Secret != nil {
+						mounted = v.Secret.SecretName
+					}
+				}
+			}
+			if mounted != secret.Name {
+				t.Fatalf("debug container mounts secret %q at /etc/dotnet-monitor, want %s", mounted, secret.Name)
+			}
+			jwkJSON, err := base64.URLEncoding.DecodeString(string(s.Data["Authentication__MonitorApiKey__PublicKey"]))
+			if err != nil {
+				t.Fatalf("Failed to decode the public key of the secret: %v", err)
+			}
+			key, err := jwk.ParseKey(jwkJSON)
+			if err != nil {
+				t.Fatalf("Failed to parse the public key of the secret: %v", err)
+			}
+			parsedToken, err := jwt.Parse([]byte(token), jwt.WithKey(jwa.ES384, key))
+			if err != nil {
+				t.Fatalf("Token not signed by the key in the mounted secret: %v", err)
+			}
+			if parsedToken.Subject() != string(s.Data[resources.SubjectKey]) {
+				t.Errorf("Token subject = %s, want %s of the mounted secret", parsedToken.Subject(), s.Data[resources.SubjectKey])
+			}
+		})
+	}
+}
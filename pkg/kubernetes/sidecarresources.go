@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// sidecarResources are the resources created or reused for a debug sidecar
+type sidecarResources struct {
+	secretName string
+	token      string
+	// secretCreated is false if the existing secret of the workload is reused, or in dry run
+	secretCreated bool
+	// podMonitor is the name of the PodMonitor created, empty if none is
+	podMonitor string
+}
+
+// createSidecarResources creates the api key secret and the PodMonitor of the debug sidecar.
+// In dry run only the secret name is generated, and the token is empty
+func (h *Helper) createSidecarResources(ctx context.Context, namespace, owner string, ownerRef metav1.OwnerReference, selector *metav1.LabelSelector, opts resources.SidecarOptions) (sidecarResources, error) {
+	if resources.IsDryRun(opts.DryRun) {
+		s := resources.GenerateSecret(namespace, "", "", owner)
+		klog.FromContext(ctx).V(LogLevelChanges).Info("Skipping creation of the sidecar resources in dry run", "namespace", namespace, "secret", s.Name, "dryRun", opts.DryRun)
+		return sidecarResources{secretName: s.Name}, nil
+	}
+	var (
+		created sidecarResources
+		err     error
+	)
+	created.secretName, created.token, created.secretCreated, err = h.CreateJWKSecret(ctx, namespace, owner, ownerRef)
+	if err != nil {
+		return sidecarResources{}, err
+	}
+	if opts.PodMonitorName != "" {
+		err = h.CreatePodMonitor(ctx, namespace, opts.PodMonitorName, owner, selector)
+		if err != nil {
+			h.cleanupSidecarResources(ctx, namespace, created)
+			return sidecarResources{}, err
+		}
+		created.podMonitor = opts.PodMonitorName
+	}
+	return created, nil
+}
+
+// cleanupSidecarResources removes the secret and PodMonitor created for a debug sidecar which could not be added.
+// A reused secret is kept, as it is still referenced by the workload or its earlier revisions
+func (h *Helper) cleanupSidecarResources(ctx context.Context, namespace string, created sidecarResources) {
+	logger := klog.FromContext(ctx).WithValues("namespace", namespace)
+	if created.secretCreated {
+		logger.V(LogLevelChanges).Info("Cleaning up the sidecar secret", "secret", created.secretName)
+		err := h.RemoveJWKSecret(ctx, namespace, created.secretName)
+		if err != nil {
+			logger.Error(err, "Failed to clean up secret", "secret", created.secretName)
+		}
+	}
+	if created.podMonitor != "" {
+		logger.V(LogLevelChanges).Info("Cleaning up the sidecar PodMonitor", "podMonitor", created.podMonitor)
+		err := h.RemovePodMonitor(ctx, namespace, created.podMonitor)
+		if err != nil {
+			logger.Error(err, "Failed to clean up PodMonitor", "podMonitor", created.podMonitor)
+		}
+	}
+}
+
+// removeSidecarResources removes the secret, collection rules configmap and PodMonitor created for the debug sidecar
+func (h *Helper) removeSidecarResources(ctx context.Context, namespace string, ddConfig resources.DDConfig) error {
+	err := h.RemoveJWKSecret(ctx, namespace, ddConfig.SecretName)
+	if err != nil {
+		return err
+	}
+	err = h.RemoveRulesConfigMap(ctx, namespace, ddConfig.RulesConfigMap)
+	if err != nil {
+		return err
+	}
+	return h.RemovePodMonitor(ctx, namespace, ddConfig.PodMonitor)
+}
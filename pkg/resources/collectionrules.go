@@ -0,0 +1,362 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	// RulesConfigMapBaseName is the base name of the collection rules configmap generated by the cli
+	RulesConfigMapBaseName = "dd-monitor-rules-"
+	// rulesSettingsKey is the key in the configmap dotnet-monitor reads its settings from
+	rulesSettingsKey = "settings.json"
+)
+
+// triggerTypes lists the collection rule triggers supported by dotnet-monitor
+var triggerTypes = map[string]bool{
+	"Startup":               true,
+	"AspNetRequestCount":    true,
+	"AspNetRequestDuration": true,
+	"AspNetResponseStatus":  true,
+	"EventCounter":          true,
+	"EventMeter":            true,
+	"CPUUsage":              true,
+	"GCHeapSize":            true,
+	"ThreadpoolQueueLength": true,
+}
+
+// actionTypes lists the collection rule actions supported by dotnet-monitor and whether they produce an artifact that needs egress
+var actionTypes = map[string]bool{
+	"CollectDump":            true,
+	"CollectGCDump":          true,
+	"CollectTrace":           true,
+	"CollectLiveMetrics":     true,
+	"CollectLogs":            true,
+	"CollectStacks":          true,
+	"CollectExceptions":      true,
+	"Execute":                false,
+	"LoadProfiler":           false,
+	"SetEnvironmentVariable": false,
+	"GetEnvironmentVariable": false,
+}
+
+// timeSpanRegexp matches the .NET TimeSpan format [d.]hh:mm:ss[.fffffff]
+var timeSpanRegexp = regexp.MustCompile(`^(\d+\.)?\d{1,2}:\d{2}:\d{2}(\.\d{1,7})?$`)
+
+// ruleNameRegexp matches the rule names that can be used as configuration keys
+var ruleNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// CollectionRulesFile represents the collection rules file supplied by the user
+type CollectionRulesFile struct {
+	// CollectionRules maps the rule name to the rule
+	CollectionRules map[string]CollectionRule `json:"CollectionRules"`
+	// Egress holds the egress providers referenced by the actions
+	Egress map[string]map[string]interface{} `json:"Egress,omitempty"`
+}
+
+// CollectionRule represents a dotnet-monitor collection rule
+type CollectionRule struct {
+	Filters []CollectionRuleFilter `json:"Filters,omitempty"`
+	Trigger CollectionRuleTrigger  `json:"Trigger"`
+	Actions []CollectionRuleAction `json:"Actions"`
+	Limits  *CollectionRuleLimits  `json:"Limits,omitempty"`
+}
+
+// CollectionRuleFilter represents a process filter for a collection rule
+type CollectionRuleFilter struct {
+	Key       string `json:"Key"`
+	Value     string `json:"Value"`
+	MatchType string `json:"MatchType,omitempty"`
+}
+
+// CollectionRuleTrigger represents the trigger of a collection rule
+type CollectionRuleTrigger struct {
+	Type     string                 `json:"Type"`
+	Settings map[string]interface{} `json:"Settings,omitempty"`
+}
+
+// CollectionRuleAction represents an action executed when a collection rule is triggered
+type CollectionRuleAction struct {
+	Name     string                 `json:"Name,omitempty"`
+	Type     string                 `json:"Type"`
+	Settings map[string]interface{} `json:"Settings,omitempty"`
+}
+
+// CollectionRuleLimits represents the limits of a collection rule
+type CollectionRuleLimits struct {
+	ActionCount                      *int   `json:"ActionCount,omitempty"`
+	ActionCountSlidingWindowDuration string `json:"ActionCountSlidingWindowDuration,omitempty"`
+	RuleDuration                     string `json:"RuleDuration,omitempty"`
+}
+
+// CollectionRuleState represents the state of a collection rule as reported by dotnet-monitor
+type CollectionRuleState struct {
+	State       string `json:"state"`
+	StateReason string `json:"stateReason"`
+}
+
+// CollectionRuleDetails represents the detailed state of a collection rule as reported by dotnet-monitor
+type CollectionRuleDetails struct {
+	CollectionRuleState
+	LifetimeOccurrences                   int    `json:"lifetimeOccurrences"`
+	SlidingWindowOccurrences              int    `json:"slidingWindowOccurrences"`
+	ActionCountLimit                      int    `json:"actionCountLimit"`
+	ActionCountSlidingWindowDurationLimit string `json:"actionCountSlidingWindowDurationLimit"`
+	SlidingWindowDurationCountdown        string `json:"slidingWindowDurationCountdown"`
+	RuleFinishedCountdown                 string `json:"ruleFinishedCountdown"`
+}
+
+// ParseCollectionRules parses and validates a collection rules file in yaml or json format
+func ParseCollectionRules(data []byte) (CollectionRulesFile, error) {
+	rules := CollectionRulesFile{}
+	err := yaml.Unmarshal(data, &rules)
+	if err != nil {
+		return CollectionRulesFile{}, fmt.Errorf("failed to parse collection rules: %v", err)
+	}
+	err = rules.Validate()
+	if err != nil {
+		return CollectionRulesFile{}, err
+	}
+	return rules, nil
+}
+
+// Validate returns an error if the collection rules are not valid
+func (f CollectionRulesFile) Validate() error {
+	if len(f.CollectionRules) == 0 {
+		return fmt.Errorf("no collection rules defined")
+	}
+	for _, name := range f.ruleNames() {
+		if !ruleNameRegexp.MatchString(name) {
+			return fmt.Errorf("collection rule name %q may only contain letters, digits, '-' and '_'", name)
+		}
+		err := f.validateRule(f.CollectionRules[name])
+		if err != nil {
+			return fmt.Errorf("collection rule %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (f CollectionRulesFile) validateRule(rule CollectionRule) error {
+	for i, filter := range rule.Filters {
+		if filter.Key == "" || filter.Value == "" {
+			return fmt.Errorf("filter %d must have a key and a value", i)
+		}
+	}
+	err := validateTrigger(rule.Trigger)
+	if err != nil {
+		return err
+	}
+	if len(rule.Actions) == 0 {
+		return fmt.Errorf("at least one action must be defined")
+	}
+	for i, action := range rule.Actions {
+		err = f.validateAction(action)
+		if err != nil {
+			return fmt.Errorf("action %d: %v", i, err)
+		}
+	}
+	if rule.Limits != nil {
+		if rule.Limits.ActionCount != nil && *rule.Limits.ActionCount < 1 {
+			return fmt.Errorf("limits: ActionCount must be greater than 0")
+		}
+		err = validateTimeSpan("limits: ActionCountSlidingWindowDuration", rule.Limits.ActionCountSlidingWindowDuration)
+		if err != nil {
+			return err
+		}
+		err = validateTimeSpan("limits: RuleDuration", rule.Limits.RuleDuration)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTrigger(trigger CollectionRuleTrigger) error {
+	if !triggerTypes[trigger.Type] {
+		return fmt.Errorf("unknown trigger type %q", trigger.Type)
+	}
+	switch trigger.Type {
+	case "EventCounter":
+		if trigger.Settings["ProviderName"] == nil || trigger.Settings["CounterName"] == nil {
+			return fmt.Errorf("trigger EventCounter requires ProviderName and CounterName")
+		}
+		if trigger.Settings["GreaterThan"] == nil && trigger.Settings["LessThan"] == nil {
+			return fmt.Errorf("trigger EventCounter requires GreaterThan or LessThan")
+		}
+	case "EventMeter":
+		if trigger.Settings["MeterName"] == nil || trigger.Settings["InstrumentName"] == nil {
+			return fmt.Errorf("trigger EventMeter requires MeterName and InstrumentName")
+		}
+	case "CPUUsage", "GCHeapSize", "ThreadpoolQueueLength":
+		if trigger.Settings["GreaterThan"] == nil && trigger.Settings["LessThan"] == nil {
+			return fmt.Errorf("trigger %s requires GreaterThan or LessThan", trigger.Type)
+		}
+	case "AspNetRequestCount", "AspNetRequestDuration", "AspNetResponseStatus":
+		if trigger.Settings["RequestCount"] == nil && trigger.Settings["ResponseCount"] == nil {
+			return fmt.Errorf("trigger %s requires RequestCount or ResponseCount", trigger.Type)
+		}
+		for _, key := range []string{"RequestCount", "ResponseCount"} {
+			if v, ok := trigger.Settings[key]; ok && !isPositiveInteger(v) {
+				return fmt.Errorf("trigger %s: %s must be a whole number greater than 0, got %v", trigger.Type, key, v)
+			}
+		}
+		if v, ok := trigger.Settings["RequestDuration"]; ok {
+			if s, _ := v.(string); isZeroTimeSpan(s) {
+				return fmt.Errorf("trigger %s: RequestDuration must be longer than 00:00:00", trigger.Type)
+			}
+		}
+	}
+	for _, key := range []string{"SlidingWindowDuration", "RequestDuration"} {
+		if v, ok := trigger.Settings[key]; ok {
+			s, isString := v.(string)
+			if !isString {
+				return fmt.Errorf("trigger: %s must be a TimeSpan string", key)
+			}
+			err := validateTimeSpan("trigger: "+key, s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f CollectionRulesFile) validateAction(action CollectionRuleAction) error {
+	needsEgress, ok := actionTypes[action.Type]
+	if !ok {
+		return fmt.Errorf("unknown action type %q", action.Type)
+	}
+	if !needsEgress {
+		return nil
+	}
+	egress, _ := action.Settings["Egress"].(string)
+	if egress == "" {
+		return fmt.Errorf("action %s requires an Egress provider", action.Type)
+	}
+	if !f.hasEgressProvider(egress) {
+		return fmt.Errorf("egress provider %q not defined in Egress", egress)
+	}
+	return nil
+}
+
+func (f CollectionRulesFile) hasEgressProvider(name string) bool {
+	for _, providers := range f.Egress {
+		if p, ok := providers[name]; ok && p != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isPositiveInteger returns true if a setting parsed from yaml or json is a whole number greater than 0
+func isPositiveInteger(v interface{}) bool {
+	switch n := v.(type) {
+	case float64:
+		return n > 0 && n == float64(int64(n))
+	case int:
+		return n > 0
+	case int64:
+		return n > 0
+	}
+	return false
+}
+
+// isZeroTimeSpan returns true if a TimeSpan setting is empty or only zeros, like 00:00:00
+func isZeroTimeSpan(value string) bool {
+	return strings.Trim(value, "0:.") == ""
+}
+
+func validateTimeSpan(field, value string) error {
+	if value == "" || timeSpanRegexp.MatchString(value) {
+		return nil
+	}
+	return fmt.Errorf("%s %q is not a valid TimeSpan (expected [d.]hh:mm:ss)", field, value)
+}
+
+func (f CollectionRulesFile) ruleNames() []string {
+	names := make([]string, 0, len(f.CollectionRules))
+	for name := range f.CollectionRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateRulesConfigMap generates a kubernetes configmap containing the collection rules as dotnet-monitor settings.
+// The owner references make kubernetes delete the configmap with the workload
+func GenerateRulesConfigMap(namespace, name, owner string, rules CollectionRulesFile, ownerRefs ...metav1.OwnerReference) (corev1.ConfigMap, error) {
+	if name == "" {
+		name = fmt.Sprintf("%s%s", RulesConfigMapBaseName, utilrand.String(5))
+	}
+	b, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return corev1.ConfigMap{}, err
+	}
+	return corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				RulesLabel(): owner,
+			},
+			OwnerReferences: ownerRefs,
+		},
+		Data: map[string]string{
+			rulesSettingsKey: string(b),
+		},
+	}, nil
+}
+
+// AddCollectionRulesPodTemplate mounts the collection rules configmap into the debug sidecar of a PodTemplateSpec object
+func AddCollectionRulesPodTemplate(template corev1.PodTemplateSpec, configmapname string) (corev1.PodTemplateSpec, error) {
+	// The slices and maps of the template are shared with the workload it was read from
+	template = *template.DeepCopy()
+	appliedConfig, err := DDConfigFromPodTemplate(template)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	if appliedConfig.RulesConfigMap == configmapname {
+		return template, nil
+	}
+	found := false
+	for i, v := range template.Spec.Volumes {
+		if v.Name != appliedConfig.SecretName {
+			continue
+		}
+		found = true
+		template.Spec.Volumes[i].VolumeSource = corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: appliedConfig.SecretName},
+						},
+					},
+					{
+						ConfigMap: &corev1.ConfigMapProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: configmapname},
+						},
+					},
+				},
+			},
+		}
+	}
+	if !found {
+		return corev1.PodTemplateSpec{}, fmt.Errorf("could not find volume %s in pod template", appliedConfig.SecretName)
+	}
+	appliedConfig.RulesConfigMap = configmapname
+	err = setDDConfigAnnotations(template.Annotations, appliedConfig)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	return template, nil
+}
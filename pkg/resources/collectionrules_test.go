@@ -0,0 +1,337 @@
+package resources
+
+import (
+	"encoding/json"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseCollectionRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name: "Valid cpu and working set rules",
+			input: `
+CollectionRules:
+  HighCpu:
+    Trigger:
+      Type: EventCounter
+      Settings:
+        ProviderName: System.Runtime
+        CounterName: cpu-usage
+        GreaterThan: 80
+        SlidingWindowDuration: "00:01:00"
+    Actions:
+    - Type: CollectDump
+      Settings:
+        Egress: tmp
+    Limits:
+      ActionCount: 1
+      ActionCountSlidingWindowDuration: "1:00:00"
+  HighWorkingSet:
+    Trigger:
+      Type: EventCounter
+      Settings:
+        ProviderName: System.Runtime
+        CounterName: working-set
+        GreaterThan: 512
+    Actions:
+    - Type: CollectGCDump
+      Settings:
+        Egress: tmp
+Egress:
+  FileSystem:
+    tmp:
+      DirectoryPath: /tmp/dumps
+`,
+			wantErr: false,
+		},
+		{
+			name: "Egress provider referenced without Egress section",
+			input: `
+CollectionRules:
+  Startup:
+    Trigger:
+      Type: Startup
+    Actions:
+    - Type: CollectTrace
+      Settings:
+        Egress: blob
+`,
+			wantErr: true,
+		},
+		{
+			name:    "No rules",
+			input:   `CollectionRules: {}`,
+			wantErr: true,
+		},
+		{
+			name: "Unknown trigger type",
+			input: `
+CollectionRules:
+  Rule:
+    Trigger:
+      Type: Sometimes
+    Actions:
+    - Type: CollectStacks
+      Settings:
+        Egress: tmp
+`,
+			wantErr: true,
+		},
+		{
+			name: "EventCounter trigger without threshold",
+			input: `
+CollectionRules:
+  Rule:
+    Trigger:
+      Type: EventCounter
+      Settings:
+        ProviderName: System.Runtime
+        CounterName: cpu-usage
+    Actions:
+    - Type: CollectDump
+      Settings:
+        Egress: tmp
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid sliding window duration",
+			input: `
+CollectionRules:
+  Rule:
+    Trigger:
+      Type: CPUUsage
+      Settings:
+        GreaterThan: 80
+        SlidingWindowDuration: 1m
+    Actions:
+    - Type: CollectDump
+      Settings:
+        Egress: tmp
+`,
+			wantErr: true,
+		},
+		{
+			name: "No actions",
+			input: `
+CollectionRules:
+  Rule:
+    Trigger:
+      Type: Startup
+`,
+			wantErr: true,
+		},
+		{
+			name: "Artifact action without egress",
+			input: `
+CollectionRules:
+  Rule:
+    Trigger:
+      Type: Startup
+    Actions:
+    - Type: CollectDump
+`,
+			wantErr: true,
+		},
+		{
+			name: "Egress provider not defined",
+			input: `
+CollectionRules:
+  Rule:
+    Trigger:
+      Type: Startup
+    Actions:
+    - Type: CollectDump
+      Settings:
+        Egress: missing
+Egress:
+  FileSystem:
+    tmp:
+      DirectoryPath: /tmp/dumps
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid action count limit",
+			input: `
+CollectionRules:
+  Rule:
+    Trigger:
+      Type: Startup
+    Actions:
+    - Type: Execute
+      Settings:
+        Path: /bin/true
+    Limits:
+      ActionCount: 0
+`,
+			wantErr: true,
+		},
+		{
+			name: "Valid AspNet request count trigger",
+			input: `
+CollectionRules:
+  ManyRequests:
+    Trigger:
+      Type: AspNetRequestCount
+      Settings:
+        RequestCount: 100
+        SlidingWindowDuration: "00:01:00"
+    Actions:
+    - Type: Execute
+`,
+			wantErr: false,
+		},
+		{
+			name: "AspNet trigger with negative threshold",
+			input: `
+CollectionRules:
+  ManyRequests:
+    Trigger:
+      Type: AspNetRequestCount
+      Settings:
+        RequestCount: -1
+    Actions:
+    - Type: Execute
+`,
+			wantErr: true,
+		},
+		{
+			name: "AspNet trigger with empty threshold",
+			input: `
+CollectionRules:
+  Errors:
+    Trigger:
+      Type: AspNetResponseStatus
+      Settings:
+        StatusCodes: ["500"]
+        ResponseCount: ""
+    Actions:
+    - Type: Execute
+`,
+			wantErr: true,
+		},
+		{
+			name: "AspNet trigger with zero request duration",
+			input: `
+CollectionRules:
+  SlowRequests:
+    Trigger:
+      Type: AspNetRequestDuration
+      Settings:
+        RequestCount: 5
+        RequestDuration: "00:00:00"
+    Actions:
+    - Type: Execute
+`,
+			wantErr: true,
+		},
+		{
+			name: "Invalid rule name",
+			input: `
+CollectionRules:
+  "High Cpu":
+    Trigger:
+      Type: Startup
+    Actions:
+    - Type: Execute
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCollectionRules([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCollectionRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateRulesConfigMap(t *testing.T) {
+	rules := CollectionRulesFile{
+		CollectionRules: map[string]CollectionRule{
+			"Startup": {
+				Trigger: CollectionRuleTrigger{Type: "Startup"},
+				Actions: []CollectionRuleAction{{Type: "Execute"}},
+			},
+		},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "owner", UID: "uid"}
+	cm, err := GenerateRulesConfigMap("test", "", "owner", rules, ownerRef)
+	if err != nil {
+		t.Errorf("GenerateRulesConfigMap() error = %v", err)
+		return
+	}
+	if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0] != ownerRef {
+		t.Errorf("ConfigMap owner references = %v, want %v", cm.OwnerReferences, ownerRef)
+	}
+	if cm.Labels[RulesLabel()] != "owner" {
+		t.Errorf("ConfigMap owner %s does not match expected owner %s", cm.Labels[RulesLabel()], "owner")
+	}
+	var settings map[string]interface{}
+	err = json.Unmarshal([]byte(cm.Data[rulesSettingsKey]), &settings)
+	if err != nil {
+		t.Errorf("ConfigMap settings is not valid json: %v", err)
+	}
+	if _, ok := settings["CollectionRules"]; !ok {
+		t.Errorf("ConfigMap settings does not contain CollectionRules: %s", cm.Data[rulesSettingsKey])
+	}
+	if _, ok := settings["Egress"]; ok {
+		t.Errorf("ConfigMap settings contains empty Egress section: %s", cm.Data[rulesSettingsKey])
+	}
+}
+
+func TestAddCollectionRulesPodTemplate(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"dev.local/dd-added": "true",
+				"dev.local/dd-apply": `{"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":false,"secretMount":"secret"}`,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					Name: "secret",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{SecretName: "secret"},
+					},
+				},
+			},
+		},
+	}
+	actual, err := AddCollectionRulesPodTemplate(template, "rules")
+	if err != nil {
+		t.Errorf("AddCollectionRulesPodTemplate() error = %v", err)
+		return
+	}
+	projected := actual.Spec.Volumes[0].Projected
+	if projected == nil || len(projected.Sources) != 2 {
+		t.Errorf("Expected secret volume to be projected with secret and configmap, got %v", actual.Spec.Volumes[0])
+		return
+	}
+	if projected.Sources[0].Secret.Name != "secret" || projected.Sources[1].ConfigMap.Name != "rules" {
+		t.Errorf("Unexpected projected sources %v", projected.Sources)
+	}
+	ddConfig, err := DDConfigFromPodTemplate(actual)
+	if err != nil {
+		t.Errorf("DDConfigFromPodTemplate() error = %v", err)
+		return
+	}
+	if ddConfig.RulesConfigMap != "rules" {
+		t.Errorf("DDConfig.RulesConfigMap = %s, want %s", ddConfig.RulesConfigMap, "rules")
+	}
+	_, err = AddCollectionRulesPodTemplate(corev1.PodTemplateSpec{}, "rules")
+	if err == nil {
+		t.Errorf("Expected error when debug sidecar is not present")
+	}
+}
@@ -8,27 +8,25 @@ import (
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
 )
 
-const secretMountPath = "/etc/dotnet-monitor"
+const (
+	secretMountPath = "/etc/dotnet-monitor"
+	// MetricsPort is the port dotnet-monitor serves prometheus metrics on
+	MetricsPort = 52325
+	// MetricsPortName is the name of the metrics port in the debug sidecar
+	MetricsPortName = "monitor-metrics"
+)
 
-func generateSidecarContainerSpec(containername, mountname, debugimage, secretname string) corev1.Container {
-	return corev1.Container{
-		Name:            containername,
-		Image:           debugimage,
-		ImagePullPolicy: corev1.PullIfNotPresent,
-		Ports:           []corev1.ContainerPort{{ContainerPort: 52323}},
-		Args: []string{
-			"--urls",
-			"http://*:52323",
-		},
+func generateSidecarContainerSpec(containername, mountname, debugimage, secretname string, version MonitorVersion, opts SidecarOptions) corev1.Container {
+	c := corev1.Container{
+		Name:                     containername,
+		Image:                    debugimage,
+		ImagePullPolicy:          corev1.PullIfNotPresent,
+		Ports:                    []corev1.ContainerPort{{ContainerPort: 52323}},
+		Args:                     version.args(opts.Metrics),
+		Env:                      version.env(),
 		TerminationMessagePath:   "/dev/termination-log",
 		TerminationMessagePolicy: "File",
-		SecurityContext: &corev1.SecurityContext{
-			Capabilities: &corev1.Capabilities{
-				Add: []corev1.Capability{
-					corev1.Capability("SYS_PTRACE"),
-				},
-			},
-		},
+		SecurityContext:          version.securityContext(opts.SecurityProfile),
 		Resources: corev1.ResourceRequirements{
 			Requests: corev1.ResourceList{
 				corev1.ResourceCPU:    resource.MustParse("50m"),
@@ -50,6 +48,10 @@ func generateSidecarContainerSpec(containername, mountname, debugimage, secretna
 			},
 		},
 	}
+	if opts.Metrics {
+		c.Ports = append(c.Ports, corev1.ContainerPort{Name: MetricsPortName, ContainerPort: MetricsPort})
+	}
+	return c
 }
 
 func getDebugContainerName(existingContainers []corev1.Container) string {
@@ -71,7 +73,8 @@ func debugContainerNameTaken(containers []corev1.Container) bool {
 func removeContainer(containers []corev1.Container, containerName string) []corev1.Container {
 	for i, c := range containers {
 		if c.Name == containerName {
-			return append(containers[:i], containers[i+1:]...)
+			// Copy, to not shift the elements of the slice shared with the template passed in
+			return append(append([]corev1.Container{}, containers[:i]...), containers[i+1:]...)
 		}
 	}
 	return containers
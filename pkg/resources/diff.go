@@ -0,0 +1,156 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change in a unified diff
+const diffContextLines = 3
+
+const (
+	colorReset = "\033[0m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// diffLine represents a line of a line diff, with op ' ', '-' or '+'
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// PodTemplateDiff returns a unified diff between the yaml of two pod templates, empty if they are equal
+func PodTemplateDiff(fromName, toName string, from, to corev1.PodTemplateSpec) (string, error) {
+	a, err := yaml.Marshal(from)
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(to)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(fromName, toName, string(a), string(b)), nil
+}
+
+// UnifiedDiff returns a unified diff between two texts, empty if they are equal
+func UnifiedDiff(fromName, toName, from, to string) string {
+	lines := lineDiff(from, to)
+	var sb strings.Builder
+	for start := 0; start < len(lines); {
+		change := start
+		for change < len(lines) && lines[change].op == ' ' {
+			change++
+		}
+		if change == len(lines) {
+			break
+		}
+		// Extend the hunk over changes separated by at most twice the context
+		end := change
+		for {
+			for end < len(lines) && lines[end].op != ' ' {
+				end++
+			}
+			unchanged := 0
+			for end+unchanged < len(lines) && lines[end+unchanged].op == ' ' {
+				unchanged++
+			}
+			if end+unchanged == len(lines) || unchanged > 2*diffContextLines {
+				end += min(unchanged, diffContextLines)
+				break
+			}
+			end += unchanged
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&sb, lines, max(change-diffContextLines, start), end)
+		start = end
+	}
+	return sb.String()
+}
+
+// ColorDiff colors the removed, added and hunk header lines of a unified diff for a terminal
+func ColorDiff(diff string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			sb.WriteString(line)
+		case strings.HasPrefix(line, "-"):
+			sb.WriteString(colorRed + strings.TrimSuffix(line, "\n") + colorReset + "\n")
+		case strings.HasPrefix(line, "+"):
+			sb.WriteString(colorGreen + strings.TrimSuffix(line, "\n") + colorReset + "\n")
+		case strings.HasPrefix(line, "@@"):
+			sb.WriteString(colorCyan + strings.TrimSuffix(line, "\n") + colorReset + "\n")
+		default:
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
+
+// lineDiff returns the lines of from and to, marked as unchanged, removed or added
+func lineDiff(from, to string) []diffLine {
+	dmp := diffmatchpatch.New()
+	a, b, lineArray := dmp.DiffLinesToChars(from, to)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lineArray)
+	var lines []diffLine
+	for _, d := range diffs {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, text := range strings.SplitAfter(d.Text, "\n") {
+			if text != "" {
+				lines = append(lines, diffLine{op: op, text: strings.TrimSuffix(text, "\n")})
+			}
+		}
+	}
+	return lines
+}
+
+// writeHunk writes the lines from start to end as a hunk with a @@ header
+func writeHunk(sb *strings.Builder, lines []diffLine, start, end int) {
+	fromLine, toLine := 1, 1
+	for _, l := range lines[:start] {
+		if l.op != '+' {
+			fromLine++
+		}
+		if l.op != '-' {
+			toLine++
+		}
+	}
+	fromCount, toCount := 0, 0
+	for _, l := range lines[start:end] {
+		if l.op != '+' {
+			fromCount++
+		}
+		if l.op != '-' {
+			toCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, l := range lines[start:end] {
+		fmt.Fprintf(sb, "%c%s\n", l.op, l.text)
+	}
+}
+
+// hunkRange formats the start line and line count of a hunk like diff -u
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
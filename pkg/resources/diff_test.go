@@ -0,0 +1,58 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "Equal texts",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "Changed line with context",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			to:   "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "Separate hunks for distant changes",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			to:   "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name: "Added lines at end",
+			from: "1\n",
+			to:   "1\n2\n3\n",
+			want: "--- a\n+++ b\n@@ -1 +1,3 @@\n 1\n+2\n+3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("a", "b", tt.from, tt.to); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorDiff(t *testing.T) {
+	got := ColorDiff("--- a\n+++ b\n@@ -1 +1 @@\n-1\n+one\n")
+	want := "--- a\n+++ b\n" + colorCyan + "@@ -1 +1 @@" + colorReset + "\n" + colorRed + "-1" + colorReset + "\n" + colorGreen + "+one" + colorReset + "\n"
+	if got != want {
+		t.Errorf("ColorDiff() = %q, want %q", got, want)
+	}
+	if strings.Contains(ColorDiff(" unchanged\n"), "\033") {
+		t.Errorf("ColorDiff() colored an unchanged line")
+	}
+}
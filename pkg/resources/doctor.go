@@ -0,0 +1,283 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Severities of the findings of dmsctl doctor
+const (
+	FindingOK      = "ok"
+	FindingWarning = "warning"
+	FindingError   = "error"
+)
+
+// Finding is the result of one check of the debug sidecar of a pod, with how to fix it if it failed
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Fix is what to do about a warning or error
+	Fix string `json:"fix,omitempty"`
+}
+
+// MonitorProcess represents a .NET process as reported by the dotnet-monitor /processes api
+type MonitorProcess struct {
+	PID       int    `json:"pid"`
+	UID       string `json:"uid"`
+	Name      string `json:"name"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// DiagnosePod checks the spec and container statuses of a pod with the debug sidecar described by config.
+// The secret and the dotnet-monitor api need the cluster, and are checked by the caller
+func DiagnosePod(pod corev1.Pod, config DDConfig) []Finding {
+	findings := []Finding{diagnosePodPhase(pod)}
+	debug, ok := findContainer(pod.Spec.Containers, config.DebugContainerName)
+	if !ok {
+		return append(findings, Finding{
+			Check:    "debug container",
+			Severity: FindingError,
+			Message:  fmt.Sprintf("debug container %s is not in the pod, the pod was created before the sidecar was added", config.DebugContainerName),
+			Fix:      "Wait for the rollout of the workload, or delete the pod so it is recreated with the sidecar",
+		})
+	}
+	target, ok := findContainer(pod.Spec.Containers, targetContainerName(pod.Spec, config))
+	if !ok {
+		return append(findings, Finding{
+			Check:    "target container",
+			Severity: FindingError,
+			Message:  fmt.Sprintf("container to debug %s is not in the pod", config.ContainerToDebug),
+			Fix:      "Remove the sidecar and add it again with --container set to one of the containers of the pod",
+		})
+	}
+	findings = append(findings,
+		diagnoseContainerStatus(pod, "debug container", debug.Name),
+		diagnoseContainerStatus(pod, "target container", target.Name),
+		diagnoseTmpVolume(pod.Spec, target, debug),
+		diagnoseIdentity(pod.Spec, target, debug),
+		diagnoseSecretVolume(pod.Spec, debug, config.SecretName),
+	)
+	return findings
+}
+
+// DiagnoseProcesses checks the .NET processes dotnet-monitor found in the pod
+func DiagnoseProcesses(processes []MonitorProcess) Finding {
+	if len(processes) == 0 {
+		return Finding{
+			Check:    "dotnet processes",
+			Severity: FindingError,
+			Message:  "dotnet-monitor found no .NET processes, the container to debug is not running a .NET app or its diagnostic socket is not in the shared /tmp",
+			Fix:      "Check the container runs a .NET app, and that DOTNET_EnableDiagnostics is not set to 0",
+		}
+	}
+	names := make([]string, 0, len(processes))
+	for _, p := range processes {
+		names = append(names, fmt.Sprintf("%s (pid %d)", p.Name, p.PID))
+	}
+	return Finding{
+		Check:    "dotnet processes",
+		Severity: FindingOK,
+		Message:  fmt.Sprintf("dotnet-monitor found %s", strings.Join(names, ", ")),
+	}
+}
+
+func diagnosePodPhase(pod corev1.Pod) Finding {
+	if pod.Status.Phase != corev1.PodRunning {
+		return Finding{
+			Check:    "pod",
+			Severity: FindingError,
+			Message:  fmt.Sprintf("pod is %s, not Running", pod.Status.Phase),
+			Fix:      fmt.Sprintf("Check the events of the pod with kubectl describe pod %s", pod.Name),
+		}
+	}
+	return Finding{Check: "pod", Severity: FindingOK, Message: "pod is Running"}
+}
+
+// diagnoseContainerStatus checks a container is running and ready, and not crash-looping
+func diagnoseContainerStatus(pod corev1.Pod, check, container string) Finding {
+	var status *corev1.ContainerStatus
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == container {
+			status = &pod.Status.ContainerStatuses[i]
+		}
+	}
+	logs := fmt.Sprintf("Check the logs with kubectl logs %s -c %s --previous", pod.Name, container)
+	switch {
+	case status == nil:
+		return Finding{Check: check, Severity: FindingWarning, Message: fmt.Sprintf("container %s has no status yet", container)}
+	case status.State.Waiting != nil && status.State.Waiting.Reason == "CrashLoopBackOff":
+		message := fmt.Sprintf("container %s is crash-looping after %d restarts", container, status.RestartCount)
+		if t := status.LastTerminationState.Terminated; t != nil {
+			message += fmt.Sprintf(", last exit code %d (%s)", t.ExitCode, t.Reason)
+		}
+		return Finding{Check: check, Severity: FindingError, Message: message, Fix: logs}
+	case status.State.Waiting != nil:
+		return Finding{
+			Check:    check,
+			Severity: FindingError,
+			Message:  fmt.Sprintf("container %s is waiting: %s %s", container, status.State.Waiting.Reason, status.State.Waiting.Message),
+			Fix:      fmt.Sprintf("Check the events of the pod with kubectl describe pod %s", pod.Name),
+		}
+	case status.State.Terminated != nil:
+		return Finding{
+			Check:    check,
+			Severity: FindingError,
+			Message:  fmt.Sprintf("container %s terminated with exit code %d (%s)", container, status.State.Terminated.ExitCode, status.State.Terminated.Reason),
+			Fix:      logs,
+		}
+	case !status.Ready:
+		return Finding{Check: check, Severity: FindingWarning, Message: fmt.Sprintf("container %s is running but not ready", container)}
+	case status.RestartCount > 0:
+		return Finding{Check: check, Severity: FindingWarning, Message: fmt.Sprintf("container %s is running, but restarted %d times", container, status.RestartCount), Fix: logs}
+	}
+	return Finding{Check: check, Severity: FindingOK, Message: fmt.Sprintf("container %s is running and ready", container)}
+}
+
+// diagnoseTmpVolume checks the container to debug and the debug container mount the same volume at /tmp, where the diagnostic socket is
+func diagnoseTmpVolume(podSpec corev1.PodSpec, target, debug corev1.Container) Finding {
+	existing, volume, err := getTmpVolume(podSpec, target.Name)
+	fix := "Remove the sidecar and add it again, so /tmp of both containers is mounted from the same volume"
+	if err != nil || !existing {
+		return Finding{
+			Check:    "shared /tmp",
+			Severity: FindingError,
+			Message:  fmt.Sprintf("container %s does not mount a volume at /tmp, so dotnet-monitor cannot reach its diagnostic socket", target.Name),
+			Fix:      fix,
+		}
+	}
+	for _, vm := range debug.VolumeMounts {
+		if vm.MountPath == "/tmp" && vm.Name == volume.Name {
+			return Finding{Check: "shared /tmp", Severity: FindingOK, Message: fmt.Sprintf("/tmp is shared with volume %s", volume.Name)}
+		}
+	}
+	return Finding{
+		Check:    "shared /tmp",
+		Severity: FindingError,
+		Message:  fmt.Sprintf("debug container %s does not mount volume %s of container %s at /tmp", debug.Name, volume.Name, target.Name),
+		Fix:      fix,
+	}
+}
+
+// diagnoseIdentity checks the debug container runs as the user of the container to debug, as the diagnostic socket is only accessible to it
+func diagnoseIdentity(podSpec corev1.PodSpec, target, debug corev1.Container) Finding {
+	targetUser, _ := targetIdentity(podSpec, target.Name)
+	debugUser, _ := targetIdentity(podSpec, debug.Name)
+	fix := "Remove the sidecar and add it again with --share-identity, the default, so the sidecar runs as the user of the container"
+	unverifiedFix := "If dumps fail with access denied, set runAsUser on the container and add the sidecar again with --share-identity"
+	switch {
+	case targetUser == nil && debugUser == nil:
+		return Finding{
+			Check:    "user",
+			Severity: FindingWarning,
+			Message:  "both containers run as the user of their image, unable to verify they match",
+			Fix:      unverifiedFix,
+		}
+	case targetUser == nil || debugUser == nil:
+		return Finding{
+			Check:    "user",
+			Severity: FindingWarning,
+			Message:  fmt.Sprintf("container %s runs as %s and debug container %s as %s, unable to verify they match", target.Name, userString(targetUser), debug.Name, userString(debugUser)),
+			Fix:      unverifiedFix,
+		}
+	case *targetUser != *debugUser:
+		return Finding{
+			Check:    "user",
+			Severity: FindingError,
+			Message:  fmt.Sprintf("container %s runs as uid %d and debug container %s as uid %d, so dotnet-monitor cannot access the diagnostic socket", target.Name, *targetUser, debug.Name, *debugUser),
+			Fix:      fix,
+		}
+	}
+	return Finding{Check: "user", Severity: FindingOK, Message: fmt.Sprintf("both containers run as uid %d", *targetUser)}
+}
+
+// diagnoseSecretVolume checks the debug container mounts the secret of the DDConfig, as a secret volume
+// or projected with the collection rules configmap
+func diagnoseSecretVolume(podSpec corev1.PodSpec, debug corev1.Container, secretName string) Finding {
+	for _, v := range podSpec.Volumes {
+		if !volumeHasSecret(v, secretName) {
+			continue
+		}
+		for _, vm := range debug.VolumeMounts {
+			if vm.Name == v.Name && vm.MountPath == secretMountPath {
+				return Finding{Check: "secret volume", Severity: FindingOK, Message: fmt.Sprintf("secret %s is mounted at %s", secretName, secretMountPath)}
+			}
+		}
+	}
+	return Finding{
+		Check:    "secret volume",
+		Severity: FindingError,
+		Message:  fmt.Sprintf("debug container %s does not mount secret %s at %s", debug.Name, secretName, secretMountPath),
+		Fix:      "Remove the sidecar and add it again",
+	}
+}
+
+// volumeHasSecret returns true if the volume is the secret, or projects it
+func volumeHasSecret(v corev1.Volume, secretName string) bool {
+	if v.Secret != nil {
+		return v.Secret.SecretName == secretName
+	}
+	if v.Projected == nil {
+		return false
+	}
+	for _, source := range v.Projected.Sources {
+		if source.Secret != nil && source.Secret.Name == secretName {
+			return true
+		}
+	}
+	return false
+}
+
+// targetContainerName returns the container to debug, the container other than the debug container if it was not supplied
+func targetContainerName(podSpec corev1.PodSpec, config DDConfig) string {
+	if config.ContainerToDebug != "" {
+		return config.ContainerToDebug
+	}
+	for _, c := range podSpec.Containers {
+		if c.Name != config.DebugContainerName {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+func findContainer(containers []corev1.Container, name string) (corev1.Container, bool) {
+	for _, c := range containers {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return corev1.Container{}, false
+}
+
+func userString(uid *int64) string {
+	if uid == nil {
+		return "the user of its image"
+	}
+	return fmt.Sprintf("uid %d", *uid)
+}
+
+// DiagnoseSecret checks the secret of the debug sidecar exists and has the api key dotnet-monitor authenticates with. secret is nil if not found
+func DiagnoseSecret(secret *corev1.Secret, secretName string) Finding {
+	if secret == nil {
+		return Finding{
+			Check:    "secret",
+			Severity: FindingError,
+			Message:  fmt.Sprintf("secret %s of the debug sidecar is not found, so the debug container cannot start", secretName),
+			Fix:      "Remove the sidecar and add it again to create a new secret",
+		}
+	}
+	for _, key := range []string{SubjectKey, publicKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			return Finding{
+				Check:    "secret",
+				Severity: FindingError,
+				Message:  fmt.Sprintf("secret %s has no %s, so dotnet-monitor rejects all tokens", secretName, key),
+				Fix:      "Remove the sidecar and add it again to create a new secret",
+			}
+		}
+	}
+	return Finding{Check: "secret", Severity: FindingOK, Message: fmt.Sprintf("secret %s has the api key", secretName)}
+}
@@ -0,0 +1,204 @@
+package resources
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testDebugPod returns a running pod with the debug sidecar added to container app running as uid 1000
+func testDebugPod(t *testing.T) (corev1.Pod, DDConfig) {
+	t.Helper()
+	uid := int64(1000)
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:            "app",
+				SecurityContext: &corev1.SecurityContext{RunAsUser: &uid},
+			}},
+		},
+	}
+	template, err := AddDebugContainerPodTemplate(template, "default", "app", "mcr.microsoft.com/dotnet/monitor:8", "dd-monitor-apikey-test", SidecarOptions{})
+	if err != nil {
+		t.Fatalf("AddDebugContainerPodTemplate() error = %v", err)
+	}
+	config, err := DDConfigFromPodTemplate(template)
+	if err != nil {
+		t.Fatalf("DDConfigFromPodTemplate() error = %v", err)
+	}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: template.Annotations},
+		Spec:       template.Spec,
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				{Name: config.DebugContainerName, Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+	return pod, config
+}
+
+func TestDiagnosePod(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(pod *corev1.Pod, config *DDConfig)
+		// want is the severity of the checks expected to not be ok
+		want map[string]string
+	}{
+		{
+			name: "Healthy debug sidecar",
+			want: map[string]string{},
+		},
+		{
+			name: "Debug container crash-looping",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				pod.Status.ContainerStatuses[1] = corev1.ContainerStatus{
+					Name:                 config.DebugContainerName,
+					RestartCount:         4,
+					State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+				}
+			},
+			want: map[string]string{"debug container": FindingError},
+		},
+		{
+			name: "Target container not sharing /tmp",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				pod.Spec.Containers[0].VolumeMounts = nil
+			},
+			want: map[string]string{"shared /tmp": FindingError},
+		},
+		{
+			name: "Debug container running as another user",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				uid := int64(2000)
+				pod.Spec.Containers[0].SecurityContext.RunAsUser = &uid
+			},
+			want: map[string]string{"user": FindingError},
+		},
+		{
+			name: "Users set by the images",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				pod.Spec.Containers[0].SecurityContext = nil
+				pod.Spec.Containers[1].SecurityContext.RunAsUser = nil
+			},
+			want: map[string]string{"user": FindingWarning},
+		},
+		{
+			name: "Secret of DDConfig not mounted",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				config.SecretName = "dd-monitor-apikey-other"
+			},
+			want: map[string]string{"secret volume": FindingError},
+		},
+		{
+			name: "Secret projected with the collection rules configmap",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				template, err := AddCollectionRulesPodTemplate(corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}, "test-dd-rules")
+				if err != nil {
+					t.Fatalf("AddCollectionRulesPodTemplate() error = %v", err)
+				}
+				pod.Spec = template.Spec
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "Projected volume without the secret of DDConfig",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				template, err := AddCollectionRulesPodTemplate(corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}, "test-dd-rules")
+				if err != nil {
+					t.Fatalf("AddCollectionRulesPodTemplate() error = %v", err)
+				}
+				pod.Spec = template.Spec
+				config.SecretName = "dd-monitor-apikey-other"
+			},
+			want: map[string]string{"secret volume": FindingError},
+		},
+		{
+			name: "Pod created before the sidecar was added",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				pod.Spec.Containers = pod.Spec.Containers[:1]
+			},
+			want: map[string]string{"debug container": FindingError},
+		},
+		{
+			name: "Pod pending",
+			modify: func(pod *corev1.Pod, config *DDConfig) {
+				pod.Status.Phase = corev1.PodPending
+				pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+				pod.Status.ContainerStatuses[0].Ready = false
+			},
+			want: map[string]string{"pod": FindingError, "target container": FindingError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod, config := testDebugPod(t)
+			if tt.modify != nil {
+				tt.modify(&pod, &config)
+			}
+			got := map[string]string{}
+			for _, f := range DiagnosePod(pod, config) {
+				if f.Severity != FindingOK {
+					got[f.Check] = f.Severity
+					if f.Fix == "" && f.Severity == FindingError {
+						t.Errorf("DiagnosePod() finding %s: %s has no fix", f.Check, f.Message)
+					}
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("DiagnosePod() problems = %v, want %v", got, tt.want)
+			}
+			for check, severity := range tt.want {
+				if got[check] != severity {
+					t.Errorf("DiagnosePod() %s = %q, want %q", check, got[check], severity)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnoseSecret(t *testing.T) {
+	secret := GenerateSecret("default", "subject", "key", "test")
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+		want   string
+	}{
+		{
+			name:   "Secret with api key",
+			secret: &secret,
+			want:   FindingOK,
+		},
+		{
+			name:   "Secret missing",
+			secret: nil,
+			want:   FindingError,
+		},
+		{
+			name:   "Secret without public key",
+			secret: &corev1.Secret{Data: map[string][]byte{SubjectKey: []byte("subject")}},
+			want:   FindingError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiagnoseSecret(tt.secret, secret.Name); got.Severity != tt.want {
+				t.Errorf("DiagnoseSecret() = %+v, want severity %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnoseProcesses(t *testing.T) {
+	if got := DiagnoseProcesses(nil); got.Severity != FindingError {
+		t.Errorf("DiagnoseProcesses() without processes = %+v, want error", got)
+	}
+	got := DiagnoseProcesses([]MonitorProcess{{PID: 1, Name: "app"}})
+	if got.Severity != FindingOK || got.Message != "dotnet-monitor found app (pid 1)" {
+		t.Errorf("DiagnoseProcesses() = %+v, want app (pid 1) found", got)
+	}
+}
@@ -0,0 +1,26 @@
+package resources
+
+import "fmt"
+
+const (
+	// DryRunNone applies the changes
+	DryRunNone = "none"
+	// DryRunClient only renders the changes locally
+	DryRunClient = "client"
+	// DryRunServer sends the changes to the api server without persisting them
+	DryRunServer = "server"
+)
+
+// ValidateDryRun returns an error if the dry run strategy is not none, client or server
+func ValidateDryRun(dryRun string) error {
+	switch dryRun {
+	case "", DryRunNone, DryRunClient, DryRunServer:
+		return nil
+	}
+	return fmt.Errorf("invalid dry run strategy %s, supported strategies are %s, %s and %s", dryRun, DryRunNone, DryRunClient, DryRunServer)
+}
+
+// IsDryRun returns true if the dry run strategy does not apply the changes
+func IsDryRun(dryRun string) bool {
+	return dryRun == DryRunClient || dryRun == DryRunServer
+}
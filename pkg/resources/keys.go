@@ -0,0 +1,145 @@
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// LegacyKeyPrefix is the domain prefix of the annotation and label keys used before the prefix was configurable.
+// Keys under it are still detected, so sidecars added by earlier versions can be found and removed
+const LegacyKeyPrefix = "dev.local/"
+
+const (
+	addedKey      = "dd-added"
+	applyKey      = "dd-apply"
+	secretKey     = "dd-secret"
+	rulesKey      = "dd-rules"
+	podMonitorKey = "dd-podmonitor"
+	snapshotKey   = "dd-original"
+	addedHashKey  = "dd-added-hash"
+)
+
+// keyPrefix is the domain prefix of the annotation and label keys written by the cli
+var keyPrefix = LegacyKeyPrefix
+
+// SetKeyPrefix sets the domain prefix of the annotation and label keys written by the cli, like monitor.altinn.no/.
+// An empty prefix resets it to LegacyKeyPrefix
+func SetKeyPrefix(prefix string) error {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		keyPrefix = LegacyKeyPrefix
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(prefix); len(errs) > 0 {
+		return fmt.Errorf("invalid key prefix %s: %s", prefix, strings.Join(errs, ", "))
+	}
+	keyPrefix = prefix + "/"
+	return nil
+}
+
+// KeyPrefix returns the domain prefix of the annotation and label keys written by the cli
+func KeyPrefix() string {
+	return keyPrefix
+}
+
+// AddedAnnotation returns the pod template annotation marking the debug sidecar as added
+func AddedAnnotation() string {
+	return keyPrefix + addedKey
+}
+
+// ApplyAnnotation returns the pod template annotation holding the DDConfig
+func ApplyAnnotation() string {
+	return keyPrefix + applyKey
+}
+
+// SecretLabel returns the label used to identify the owner of the secret
+func SecretLabel() string {
+	return keyPrefix + secretKey
+}
+
+// RulesLabel returns the label used to identify the owner of the collection rules configmap
+func RulesLabel() string {
+	return keyPrefix + rulesKey
+}
+
+// PodMonitorLabel returns the label used to identify the owner of the PodMonitor
+func PodMonitorLabel() string {
+	return keyPrefix + podMonitorKey
+}
+
+// SecretLabels returns the secret owner labels to look secrets up by, the current one first followed by the legacy one
+func SecretLabels() []string {
+	return keys(secretKey)
+}
+
+// SecretOwner returns the owner of a secret created by the cli, from the current or legacy label
+func SecretOwner(labels map[string]string) string {
+	v, _ := lookup(labels, secretKey)
+	return v
+}
+
+// RulesLabels returns the collection rules configmap owner labels to look configmaps up by, the current one first followed by the legacy one
+func RulesLabels() []string {
+	return keys(rulesKey)
+}
+
+// RulesOwner returns the owner of a collection rules configmap created by the cli, from the current or legacy label
+func RulesOwner(labels map[string]string) string {
+	v, _ := lookup(labels, rulesKey)
+	return v
+}
+
+// HasDebugSidecar returns true if the annotations of a pod or pod template mark the debug sidecar as added, under the current or legacy prefix
+func HasDebugSidecar(annotations map[string]string) bool {
+	v, _ := lookup(annotations, addedKey)
+	return v == "true"
+}
+
+// DDConfigFromAnnotations returns the DDConfig in the annotations of a pod or pod template, under the current or legacy prefix
+func DDConfigFromAnnotations(annotations map[string]string) (DDConfig, error) {
+	if !HasDebugSidecar(annotations) {
+		return DDConfig{}, dmserrors.ErrNotPresent
+	}
+	v, _ := lookup(annotations, applyKey)
+	return ParseDDConfig(v)
+}
+
+// setDDConfigAnnotations marks the debug sidecar as added and stores the DDConfig under the current prefix, replacing legacy keys
+func setDDConfigAnnotations(annotations map[string]string, appliedConfig DDConfig) error {
+	v, err := MarshalDDConfig(appliedConfig)
+	if err != nil {
+		return err
+	}
+	removeDDConfigAnnotations(annotations)
+	annotations[AddedAnnotation()] = "true"
+	annotations[ApplyAnnotation()] = v
+	return nil
+}
+
+// removeDDConfigAnnotations removes the debug sidecar annotations under the current and legacy prefix
+func removeDDConfigAnnotations(annotations map[string]string) {
+	for _, k := range append(keys(addedKey), keys(applyKey)...) {
+		delete(annotations, k)
+	}
+}
+
+// keys returns the key under the current prefix, followed by the legacy key if the prefix is not the legacy one
+func keys(name string) []string {
+	if keyPrefix == LegacyKeyPrefix {
+		return []string{keyPrefix + name}
+	}
+	return []string{keyPrefix + name, LegacyKeyPrefix + name}
+}
+
+// lookup returns the value of the key under the current prefix, or else under the legacy prefix
+func lookup(m map[string]string, name string) (string, bool) {
+	for _, k := range keys(name) {
+		if v, ok := m[k]; ok {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,99 @@
+package resources
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetKeyPrefix(t *testing.T) {
+	t.Cleanup(func() { SetKeyPrefix("") })
+	tests := []struct {
+		name    string
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Prefix with trailing slash",
+			prefix: "monitor.altinn.no/",
+			want:   "monitor.altinn.no/dd-added",
+		},
+		{
+			name:   "Prefix without trailing slash",
+			prefix: "monitor.altinn.no",
+			want:   "monitor.altinn.no/dd-added",
+		},
+		{
+			name:   "Empty prefix resets to legacy prefix",
+			prefix: "",
+			want:   "dev.local/dd-added",
+		},
+		{
+			name:    "Invalid prefix",
+			prefix:  "Monitor_Altinn",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := SetKeyPrefix(tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetKeyPrefix() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && AddedAnnotation() != tt.want {
+				t.Errorf("AddedAnnotation() = %s, want %s", AddedAnnotation(), tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyPrefixLegacyCompatibility(t *testing.T) {
+	err := SetKeyPrefix("monitor.altinn.no/")
+	if err != nil {
+		t.Fatalf("SetKeyPrefix() error = %v", err)
+	}
+	t.Cleanup(func() { SetKeyPrefix("") })
+	legacy := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"dev.local/dd-added": "true",
+				"dev.local/dd-apply": `{"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":false,"secretMount":"secret"}`,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "test"}, {Name: "debug"}},
+			Volumes:    []corev1.Volume{{Name: "secret"}},
+		},
+	}
+	if !HasDebugSidecar(legacy.Annotations) {
+		t.Errorf("HasDebugSidecar() = false for legacy annotations")
+	}
+	if _, err := AddDebugContainerPodTemplate(legacy, "test", "test", "test:latest", "secret", SidecarOptions{}); err == nil {
+		t.Errorf("AddDebugContainerPodTemplate() added a second sidecar to a template with legacy annotations")
+	}
+	removed, err := RemoveDebugContainerPodTemplate(legacy, "test", "test")
+	if err != nil {
+		t.Errorf("RemoveDebugContainerPodTemplate() error = %v", err)
+		return
+	}
+	if len(removed.Annotations) != 0 || len(removed.Spec.Containers) != 1 {
+		t.Errorf("RemoveDebugContainerPodTemplate() left annotations %v and %d containers", removed.Annotations, len(removed.Spec.Containers))
+	}
+	added, err := AddDebugContainerPodTemplate(removed, "test", "test", "test:latest", "secret", SidecarOptions{})
+	if err != nil {
+		t.Errorf("AddDebugContainerPodTemplate() error = %v", err)
+		return
+	}
+	if added.Annotations["monitor.altinn.no/dd-added"] != "true" || added.Annotations["monitor.altinn.no/dd-apply"] == "" {
+		t.Errorf("AddDebugContainerPodTemplate() annotations = %v, want keys under monitor.altinn.no/", added.Annotations)
+	}
+	if SecretOwner(map[string]string{"dev.local/dd-secret": "owner"}) != "owner" {
+		t.Errorf("SecretOwner() did not return owner from legacy label")
+	}
+	if RulesOwner(map[string]string{"dev.local/dd-rules": "owner"}) != "owner" {
+		t.Errorf("RulesOwner() did not return owner from legacy label")
+	}
+}
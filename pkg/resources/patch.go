@@ -0,0 +1,150 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONPatchOperation is a RFC 6902 JSON patch operation
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON omits the value of remove operations only, as add, replace and test operations require it,
+// also when it is null, empty or zero
+func (o JSONPatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	// operation has the fields but not the methods of JSONPatchOperation, so it is marshalled without calling MarshalJSON again
+	type operation JSONPatchOperation
+	return json.Marshal(operation(o))
+}
+
+// jsonPointerEscaper escapes a key for use as a RFC 6901 JSON pointer reference token
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// jsonPointerUnescaper unescapes a RFC 6901 JSON pointer reference token
+var jsonPointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// JSONPatch returns the RFC 6902 operations changing the JSON document from into to.
+// Items appended to a list are added at its end, so the patch applies to a list changed by others
+func JSONPatch(from, to []byte) ([]JSONPatchOperation, error) {
+	var f, t interface{}
+	err := json.Unmarshal(from, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse json patch source: %v", err)
+	}
+	err = json.Unmarshal(to, &t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse json patch target: %v", err)
+	}
+	return jsonPatch("", f, t), nil
+}
+
+// JSONPatchTests returns RFC 6902 test operations checking the lists the operations change are as in the JSON document
+// from, so the patch fails instead of changing items that moved because others changed a list. The outermost list on the
+// path of an operation is tested, covering the lists nested in its items. Other values are changed by key, so they are
+// not tested and changes made by others elsewhere in the document do not fail the patch
+func JSONPatchTests(from []byte, ops []JSONPatchOperation) ([]JSONPatchOperation, error) {
+	var f interface{}
+	err := json.Unmarshal(from, &f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse json patch source: %v", err)
+	}
+	var tests []JSONPatchOperation
+	tested := map[string]bool{}
+	for _, op := range ops {
+		path, list, ok := outermostList(f, op.Path)
+		if !ok || tested[path] {
+			continue
+		}
+		tested[path] = true
+		tests = append(tests, JSONPatchOperation{Op: "test", Path: path, Value: list})
+	}
+	return tests, nil
+}
+
+// outermostList returns the path and value of the first list in doc on the RFC 6901 JSON pointer path, and false if there is none
+func outermostList(doc interface{}, path string) (string, []interface{}, bool) {
+	current := doc
+	prefix := ""
+	for _, token := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		if list, ok := current.([]interface{}); ok {
+			return prefix, list, true
+		}
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return "", nil, false
+		}
+		current, ok = m[jsonPointerUnescaper.Replace(token)]
+		if !ok {
+			return "", nil, false
+		}
+		prefix += "/" + token
+	}
+	return "", nil, false
+}
+
+// jsonPatch returns the operations changing the value at path from from into to
+func jsonPatch(path string, from, to interface{}) []JSONPatchOperation {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		var ops []JSONPatchOperation
+		for _, k := range sortedKeys(f) {
+			if _, ok := t[k]; !ok {
+				ops = append(ops, JSONPatchOperation{Op: "remove", Path: path + "/" + jsonPointerEscaper.Replace(k)})
+			}
+		}
+		for _, k := range sortedKeys(t) {
+			p := path + "/" + jsonPointerEscaper.Replace(k)
+			if fv, ok := f[k]; ok {
+				ops = append(ops, jsonPatch(p, fv, t[k])...)
+			} else {
+				ops = append(ops, JSONPatchOperation{Op: "add", Path: p, Value: t[k]})
+			}
+		}
+		return ops
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok {
+			break
+		}
+		var ops []JSONPatchOperation
+		for i := 0; i < len(f) && i < len(t); i++ {
+			ops = append(ops, jsonPatch(fmt.Sprintf("%s/%d", path, i), f[i], t[i])...)
+		}
+		for i := len(f) - 1; i >= len(t); i-- {
+			ops = append(ops, JSONPatchOperation{Op: "remove", Path: fmt.Sprintf("%s/%d", path, i)})
+		}
+		for i := len(f); i < len(t); i++ {
+			ops = append(ops, JSONPatchOperation{Op: "add", Path: path + "/-", Value: t[i]})
+		}
+		return ops
+	}
+	if reflect.DeepEqual(from, to) {
+		return nil
+	}
+	return []JSONPatchOperation{{Op: "replace", Path: path, Value: to}}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
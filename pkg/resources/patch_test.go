@@ -0,0 +1,178 @@
+package resources
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []JSONPatchOperation
+	}{
+		{
+			name: "No changes",
+			from: `{"a":{"b":[1,2]}}`,
+			to:   `{"a":{"b":[1,2]}}`,
+			want: nil,
+		},
+		{
+			name: "Adds and removes keys, escaping the path",
+			from: `{"metadata":{"annotations":{"old/key":"x"}}}`,
+			to:   `{"metadata":{"annotations":{"dev.local/dd-added":"true"}}}`,
+			want: []JSONPatchOperation{
+				{Op: "remove", Path: "/metadata/annotations/old~1key"},
+				{Op: "add", Path: "/metadata/annotations/dev.local~1dd-added", Value: "true"},
+			},
+		},
+		{
+			name: "Appends to list and changes item",
+			from: `{"containers":[{"name":"app"}]}`,
+			to:   `{"containers":[{"name":"app","image":"app:1"},{"name":"debug"}]}`,
+			want: []JSONPatchOperation{
+				{Op: "add", Path: "/containers/0/image", Value: "app:1"},
+				{Op: "add", Path: "/containers/-", Value: map[string]interface{}{"name": "debug"}},
+			},
+		},
+		{
+			name: "Removes from the end of list",
+			from: `{"volumes":["a","b","c"]}`,
+			to:   `{"volumes":["a"]}`,
+			want: []JSONPatchOperation{
+				{Op: "remove", Path: "/volumes/2"},
+				{Op: "remove", Path: "/volumes/1"},
+			},
+		},
+		{
+			name: "Replaces with zero and empty values",
+			from: `{"replicas":2,"paused":true,"name":"a"}`,
+			to:   `{"replicas":0,"paused":false,"name":""}`,
+			want: []JSONPatchOperation{
+				{Op: "replace", Path: "/name", Value: ""},
+				{Op: "replace", Path: "/paused", Value: false},
+				{Op: "replace", Path: "/replicas", Value: float64(0)},
+			},
+		},
+		{
+			name: "Replaces value of another type",
+			from: `{"replicas":"2"}`,
+			to:   `{"replicas":2}`,
+			want: []JSONPatchOperation{
+				{Op: "replace", Path: "/replicas", Value: float64(2)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tt.from), []byte(tt.to))
+			if err != nil {
+				t.Errorf("JSONPatch() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSONPatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatchTests(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []JSONPatchOperation
+	}{
+		{
+			name: "Tests the outermost list of changes in nested lists once",
+			from: `{"spec":{"containers":[{"name":"app","volumeMounts":[{"name":"tmp"}]}],"replicas":1}}`,
+			to:   `{"spec":{"containers":[{"name":"app","volumeMounts":[]},{"name":"debug"}],"replicas":1}}`,
+			want: []JSONPatchOperation{
+				{Op: "test", Path: "/spec/containers", Value: []interface{}{
+					map[string]interface{}{"name": "app", "volumeMounts": []interface{}{map[string]interface{}{"name": "tmp"}}},
+				}},
+			},
+		},
+		{
+			name: "Does not test changes outside lists",
+			from: `{"metadata":{"annotations":{"a/b":"x"}},"replicas":1}`,
+			to:   `{"metadata":{"annotations":{"a/b":"y"}},"replicas":2}`,
+			want: nil,
+		},
+		{
+			name: "Does not test lists added",
+			from: `{"spec":{}}`,
+			to:   `{"spec":{"volumes":["a"]}}`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := JSONPatch([]byte(tt.from), []byte(tt.to))
+			if err != nil {
+				t.Fatalf("JSONPatch() error = %v", err)
+			}
+			got, err := JSONPatchTests([]byte(tt.from), ops)
+			if err != nil {
+				t.Fatalf("JSONPatchTests() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSONPatchTests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatchOperation_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		op   JSONPatchOperation
+		want string
+	}{
+		{
+			name: "Omits value of remove",
+			op:   JSONPatchOperation{Op: "remove", Path: "/a"},
+			want: `{"op":"remove","path":"/a"}`,
+		},
+		{
+			name: "Keeps null value of add",
+			op:   JSONPatchOperation{Op: "add", Path: "/a", Value: nil},
+			want: `{"op":"add","path":"/a","value":null}`,
+		},
+		{
+			name: "Keeps zero value of replace",
+			op:   JSONPatchOperation{Op: "replace", Path: "/replicas", Value: float64(0)},
+			want: `{"op":"replace","path":"/replicas","value":0}`,
+		},
+		{
+			name: "Keeps empty value of replace",
+			op:   JSONPatchOperation{Op: "replace", Path: "/a", Value: ""},
+			want: `{"op":"replace","path":"/a","value":""}`,
+		},
+		{
+			name: "Keeps false value of test",
+			op:   JSONPatchOperation{Op: "test", Path: "/a", Value: false},
+			want: `{"op":"test","path":"/a","value":false}`,
+		},
+		{
+			name: "Keeps empty object value of add",
+			op:   JSONPatchOperation{Op: "add", Path: "/a", Value: map[string]interface{}{}},
+			want: `{"op":"add","path":"/a","value":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal([]JSONPatchOperation{tt.op})
+			if err != nil {
+				t.Errorf("json.Marshal() error = %v", err)
+				return
+			}
+			if want := "[" + tt.want + "]"; string(got) != want {
+				t.Errorf("json.Marshal() = %s, want %s", got, want)
+			}
+		})
+	}
+}
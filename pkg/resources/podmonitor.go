@@ -0,0 +1,51 @@
+package resources
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// PodMonitorBaseName is the base name of the PodMonitor generated by the cli
+	PodMonitorBaseName = "dd-monitor-"
+)
+
+// PodMonitorResource is the prometheus-operator PodMonitor resource
+var PodMonitorResource = schema.GroupVersionResource{Group: "monitoring.coreos.com", Version: "v1", Resource: "podmonitors"}
+
+// PodMonitorName returns the name of the PodMonitor for the metrics endpoint of a workload
+func PodMonitorName(owner string) string {
+	return PodMonitorBaseName + owner
+}
+
+// GeneratePodMonitor generates a prometheus-operator PodMonitor scraping the debug sidecar metrics endpoint
+func GeneratePodMonitor(namespace, name, owner string, selector *metav1.LabelSelector) (*unstructured.Unstructured, error) {
+	sel, err := runtime.DefaultUnstructuredConverter.ToUnstructured(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": PodMonitorResource.GroupVersion().String(),
+			"kind":       "PodMonitor",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					PodMonitorLabel(): owner,
+				},
+			},
+			"spec": map[string]interface{}{
+				"selector": sel,
+				"podMetricsEndpoints": []interface{}{
+					map[string]interface{}{
+						"port": MetricsPortName,
+						"path": "/metrics",
+					},
+				},
+			},
+		},
+	}, nil
+}
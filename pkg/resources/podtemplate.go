@@ -1,39 +1,67 @@
 package resources
 
 import (
-	"encoding/json"
 	"fmt"
+	"sort"
 
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 )
 
 // AddDebugContainerPodTemplate adds debug sidecar to a PodTemplateSpec object
-func AddDebugContainerPodTemplate(template corev1.PodTemplateSpec, namespace, containerToDebug, debugimage, secretname string) (corev1.PodTemplateSpec, error) {
-	if template.Annotations["dev.local/dd-added"] == "true" {
-		return corev1.PodTemplateSpec{}, fmt.Errorf("debug sidecar already present")
+func AddDebugContainerPodTemplate(template corev1.PodTemplateSpec, namespace, containerToDebug, debugimage, secretname string, opts SidecarOptions) (corev1.PodTemplateSpec, error) {
+	// The slices and maps of the template are shared with the workload it was read from
+	template = *template.DeepCopy()
+	if HasDebugSidecar(template.Annotations) {
+		return corev1.PodTemplateSpec{}, dmserrors.ErrAlreadyPresent
 	}
 	debugSidecarName := getDebugContainerName(template.Spec.Containers)
 	existingVolume, tmpVolume, err := getTmpVolume(template.Spec, containerToDebug)
 	if err != nil {
 		return corev1.PodTemplateSpec{}, err
 	}
+
+	version, err := ResolveMonitorVersion(debugimage, opts.MonitorVersion)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	err = ValidateSecurityProfile(opts.SecurityProfile)
 	if err != nil {
 		return corev1.PodTemplateSpec{}, err
 	}
 
-	debugSidecar := generateSidecarContainerSpec(debugSidecarName, tmpVolume.Name, debugimage, secretname)
+	debugSidecar := generateSidecarContainerSpec(debugSidecarName, tmpVolume.Name, debugimage, secretname, version, opts)
 	appliedConfig := DDConfig{
 		ContainerToDebug:   containerToDebug,
 		DebugContainerName: debugSidecarName,
 		TmpdirAdded:        !existingVolume,
 		SecretName:         secretname,
+		Metrics:            opts.Metrics,
+		PodMonitor:         opts.PodMonitorName,
+		MonitorVersion:     version.Major,
+		SecurityProfile:    opts.SecurityProfile,
+		ExpiresAt:          opts.ExpiresAt,
+		Audit:              opts.Audit,
+	}
+	if !opts.SkipIdentitySharing {
+		target := containerToDebug
+		if len(template.Spec.Containers) == 1 {
+			target = template.Spec.Containers[0].Name
+		}
+		runAsUser, runAsGroup := targetIdentity(template.Spec, target)
+		if runAsUser == nil && version.RunAsUser != nil {
+			// The container to debug runs as the user of its image, root unless the image sets one, and the sidecar
+			// image runs as non-root. Root is used so the sidecar can open the diagnostic socket of the container
+			runAsUser = int64Ptr(0)
+		}
+		appliedConfig.RunAsUser, appliedConfig.RunAsGroup = shareIdentity(debugSidecar.SecurityContext, runAsUser, runAsGroup, opts.SecurityProfile)
 	}
 
 	if len(template.Spec.Containers) > 1 {
-		for _, c := range template.Spec.Containers {
+		for i, c := range template.Spec.Containers {
 			if c.Name == containerToDebug {
 				if !existingVolume {
-					c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+					template.Spec.Containers[i].VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
 						Name:      tmpVolume.Name,
 						MountPath: "/tmp",
 					})
@@ -64,22 +92,27 @@ func AddDebugContainerPodTemplate(template corev1.PodTemplateSpec, namespace, co
 	if template.Annotations == nil {
 		template.Annotations = make(map[string]string)
 	}
-	template.Annotations["dev.local/dd-added"] = "true"
-	b, err := json.Marshal(appliedConfig)
+	if opts.Metrics && opts.MetricsAnnotations {
+		for k, v := range metricsAnnotations() {
+			if _, exists := template.Annotations[k]; !exists {
+				template.Annotations[k] = v
+				appliedConfig.AddedAnnotations = append(appliedConfig.AddedAnnotations, k)
+			}
+		}
+		sort.Strings(appliedConfig.AddedAnnotations)
+	}
+	err = setDDConfigAnnotations(template.Annotations, appliedConfig)
 	if err != nil {
 		return corev1.PodTemplateSpec{}, err
 	}
-	template.Annotations["dev.local/dd-apply"] = string(b)
 	return template, nil
 }
 
 // RemoveDebugContainerPodTemplate removes debug sidecar from a PodTemplateSpec object
 func RemoveDebugContainerPodTemplate(template corev1.PodTemplateSpec, namespace, containerToDebug string) (corev1.PodTemplateSpec, error) {
-	if template.Annotations["dev.local/dd-added"] == "" {
-		return corev1.PodTemplateSpec{}, fmt.Errorf("debug sidecar not present")
-	}
-	appliedConfig := DDConfig{}
-	err := json.Unmarshal([]byte(template.Annotations["dev.local/dd-apply"]), &appliedConfig)
+	// The slices and maps of the template are shared with the workload it was read from
+	template = *template.DeepCopy()
+	appliedConfig, err := DDConfigFromAnnotations(template.Annotations)
 	if err != nil {
 		return corev1.PodTemplateSpec{}, err
 	}
@@ -94,20 +127,23 @@ func RemoveDebugContainerPodTemplate(template corev1.PodTemplateSpec, namespace,
 		template.Spec.Containers = removeTmpVolumeMount(template.Spec.Containers, containerToDebug, tmpVolume.Name)
 	}
 	template.Spec.Volumes = removeVolume(template.Spec.Volumes, appliedConfig.SecretName)
-	delete(template.Annotations, "dev.local/dd-added")
-	delete(template.Annotations, "dev.local/dd-apply")
+	for _, k := range appliedConfig.AddedAnnotations {
+		delete(template.Annotations, k)
+	}
+	removeDDConfigAnnotations(template.Annotations)
 	return template, nil
 }
 
 // DDConfigFromPodTemplate returns the DDConfig defined in PodTemplateSpec object
 func DDConfigFromPodTemplate(template corev1.PodTemplateSpec) (DDConfig, error) {
-	if template.Annotations["dev.local/dd-added"] != "true" {
-		return DDConfig{}, fmt.Errorf("debug sidecar not present")
-	}
-	appliedConfig := DDConfig{}
-	err := json.Unmarshal([]byte(template.Annotations["dev.local/dd-apply"]), &appliedConfig)
-	if err != nil {
-		return DDConfig{}, err
+	return DDConfigFromAnnotations(template.Annotations)
+}
+
+// metricsAnnotations returns the prometheus.io annotations used by common prometheus scrape configs
+func metricsAnnotations() map[string]string {
+	return map[string]string{
+		"prometheus.io/scrape": "true",
+		"prometheus.io/port":   fmt.Sprintf("%d", MetricsPort),
+		"prometheus.io/path":   "/metrics",
 	}
-	return appliedConfig, nil
 }
@@ -1,13 +1,16 @@
 package resources
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	"github.com/ghodss/yaml"
 	"github.com/sergi/go-diff/diffmatchpatch"
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +31,7 @@ func TestAddDebugContainerPodTemplate(t *testing.T) {
 		containerToDebug string
 		debugimage       string
 		secretname       string
+		opts             SidecarOptions
 	}
 	tests := []struct {
 		name       string
@@ -35,6 +39,7 @@ func TestAddDebugContainerPodTemplate(t *testing.T) {
 		inputfile  string
 		goldenfile string
 		wantErr    bool
+		wantErrIs  error
 	}{
 		{
 			name: "Add debug container to pod template",
@@ -70,18 +75,157 @@ func TestAddDebugContainerPodTemplate(t *testing.T) {
 			},
 			inputfile: "testdata/add-pod-template/podtemplate_test_container_exists.yaml",
 			wantErr:   true,
+			wantErrIs: dmserrors.ErrAlreadyPresent,
+		},
+		{
+			name: "Add dotnet-monitor 6 debug container to pod template",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:6.0",
+				secretname:       "secret",
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_one_container.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_version_6.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add dotnet-monitor 7 debug container to pod template",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:7.3.2-alpine",
+				secretname:       "secret",
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_one_container.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_version_7.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add dotnet-monitor 8 debug container to pod template",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:8",
+				secretname:       "secret",
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_one_container.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_version_8.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add debug container to pod template with version override",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+				debugimage:       "myregistry.io/monitor:custom",
+				secretname:       "secret",
+				opts:             SidecarOptions{MonitorVersion: 6},
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_one_container.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_version_override.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add debug container to pod template with restricted security profile",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:6.0",
+				secretname:       "secret",
+				opts:             SidecarOptions{SecurityProfile: SecurityProfileRestricted},
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_one_container.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_profile_restricted.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add debug container to pod template with baseline security profile",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:8",
+				secretname:       "secret",
+				opts:             SidecarOptions{SecurityProfile: SecurityProfileBaseline},
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_one_container.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_profile_baseline.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add debug container to pod template sharing user and group of container to debug",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "test",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:8",
+				secretname:       "secret",
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_security_context.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_share_identity.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add debug container to pod template sharing root user of container to debug",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "test2",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:8",
+				secretname:       "secret",
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_security_context.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_share_identity_root.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add debug container to pod template without sharing user and group",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "test",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:8",
+				secretname:       "secret",
+				opts:             SidecarOptions{SkipIdentitySharing: true},
+			},
+			inputfile:  "testdata/add-pod-template/podtemplate_test_security_context.yaml",
+			goldenfile: "testdata/add-pod-template/podtemplate_test_skip_share_identity.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Add debug container to pod template with unknown security profile",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+				debugimage:       "mcr.microsoft.com/dotnet/monitor:8",
+				secretname:       "secret",
+				opts:             SidecarOptions{SecurityProfile: "privileged"},
+			},
+			inputfile: "testdata/add-pod-template/podtemplate_test_one_container.yaml",
+			wantErr:   true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			input, err := unMarshalInputfile(tt.inputfile)
-			actual, err := AddDebugContainerPodTemplate(input, tt.args.namespace, tt.args.containerToDebug, tt.args.debugimage, tt.args.secretname)
+			if err != nil {
+				t.Errorf("unMarshalInputfile() error = %v", err)
+				return
+			}
+			actual, err := AddDebugContainerPodTemplate(input, tt.args.namespace, tt.args.containerToDebug, tt.args.debugimage, tt.args.secretname, tt.args.opts)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Expected error, no error returned")
+				t.Errorf("AddDebugContainerPodTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("AddDebugContainerPodTemplate() error = %v, want %v", err, tt.wantErrIs)
+			}
+			if tt.wantErr {
 				return
 			}
 			var expected corev1.PodTemplateSpec
 			err = readUpdateGoldenFile(tt.goldenfile, *update, &expected, actual)
+			if err != nil {
+				t.Errorf("readUpdateGoldenFile() error = %v", err)
+				return
+			}
 			eS := podTemplateToString(expected)
 			aS := podTemplateToString(actual)
 			if eS != aS {
@@ -93,6 +237,25 @@ func TestAddDebugContainerPodTemplate(t *testing.T) {
 	}
 }
 
+func TestAddDebugContainerPodTemplateKeepsInput(t *testing.T) {
+	input, err := unMarshalInputfile("testdata/add-pod-template/podtemplate_test_one_container.yaml")
+	if err != nil {
+		t.Errorf("unMarshalInputfile() error = %v", err)
+		return
+	}
+	before := podTemplateToString(input)
+	_, err = AddDebugContainerPodTemplate(input, "test", "", "test:latest", "secret", SidecarOptions{})
+	if err != nil {
+		t.Errorf("AddDebugContainerPodTemplate() error = %v", err)
+		return
+	}
+	if after := podTemplateToString(input); before != after {
+		dmp := diffmatchpatch.New()
+		diffs := dmp.DiffMain(before, after, false)
+		t.Errorf("AddDebugContainerPodTemplate() changed its input. Difference:\n%s", dmp.DiffPrettyText(diffs))
+	}
+}
+
 func TestRemoveDebugContainerPodTemplate(t *testing.T) {
 	type args struct {
 		namespace        string
@@ -134,6 +297,35 @@ func TestRemoveDebugContainerPodTemplate(t *testing.T) {
 			inputfile: "testdata/remove-pod-template/podtemplate_test_container_exists.yaml",
 			wantErr:   true,
 		},
+		{
+			name: "Remove debug container added with unversioned schema 1",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+			},
+			inputfile:  "testdata/remove-pod-template/schema/podtemplate_test_schema_v1.yaml",
+			goldenfile: "testdata/remove-pod-template/schema/podtemplate_test_schema_v1.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Remove debug container added with schema 2",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+			},
+			inputfile:  "testdata/remove-pod-template/schema/podtemplate_test_schema_v2.yaml",
+			goldenfile: "testdata/remove-pod-template/schema/podtemplate_test_schema_v2.golden",
+			wantErr:    false,
+		},
+		{
+			name: "Remove debug container added with unsupported schema",
+			args: args{
+				namespace:        "test",
+				containerToDebug: "",
+			},
+			inputfile: "testdata/remove-pod-template/schema/podtemplate_test_schema_unsupported.yaml",
+			wantErr:   true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -224,6 +416,7 @@ func TestDDConfigFromPodTemplate(t *testing.T) {
 				},
 			},
 			want: DDConfig{
+				SchemaVersion:      DDConfigSchemaVersion,
 				ContainerToDebug:   "test",
 				DebugContainerName: "debug",
 				TmpdirAdded:        false,
@@ -270,3 +463,86 @@ func TestDDConfigFromPodTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestDebugContainerPodTemplateMetrics(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"prometheus.io/path": "/custom",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "test"}},
+		},
+	}
+	opts := SidecarOptions{Metrics: true, MetricsAnnotations: true}
+	added, err := AddDebugContainerPodTemplate(template, "test", "", "test:latest", "secret", opts)
+	if err != nil {
+		t.Errorf("AddDebugContainerPodTemplate() error = %v", err)
+		return
+	}
+	sidecar := added.Spec.Containers[len(added.Spec.Containers)-1]
+	if len(sidecar.Ports) != 2 || sidecar.Ports[1].ContainerPort != MetricsPort {
+		t.Errorf("Expected metrics port %d on debug container, got %v", MetricsPort, sidecar.Ports)
+	}
+	if added.Annotations["prometheus.io/scrape"] != "true" {
+		t.Errorf("Expected prometheus.io/scrape annotation, got %v", added.Annotations)
+	}
+	if added.Annotations["prometheus.io/path"] != "/custom" {
+		t.Errorf("Existing annotation prometheus.io/path was overwritten, got %s", added.Annotations["prometheus.io/path"])
+	}
+	removed, err := RemoveDebugContainerPodTemplate(added, "test", "test")
+	if err != nil {
+		t.Errorf("RemoveDebugContainerPodTemplate() error = %v", err)
+		return
+	}
+	want := map[string]string{"prometheus.io/path": "/custom"}
+	if !reflect.DeepEqual(removed.Annotations, want) {
+		t.Errorf("RemoveDebugContainerPodTemplate() annotations = %v, want %v", removed.Annotations, want)
+	}
+}
+
+func TestDDConfigExpired(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		ddConfig string
+		want     bool
+	}{
+		{
+			name:     "Sidecar without expiry",
+			ddConfig: `{"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":false,"secretMount":"secret"}`,
+			want:     false,
+		},
+		{
+			name:     "Sidecar expired",
+			ddConfig: `{"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":false,"secretMount":"secret","expiresAt":"2024-01-01T11:59:59Z"}`,
+			want:     true,
+		},
+		{
+			name:     "Sidecar not yet expired",
+			ddConfig: `{"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":false,"secretMount":"secret","expiresAt":"2024-01-01T16:00:00Z"}`,
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"dev.local/dd-added": "true",
+						"dev.local/dd-apply": tt.ddConfig,
+					},
+				},
+			}
+			ddConfig, err := DDConfigFromPodTemplate(template)
+			if err != nil {
+				t.Errorf("DDConfigFromPodTemplate() error = %v", err)
+				return
+			}
+			if got := ddConfig.Expired(now); got != tt.want {
+				t.Errorf("DDConfig.Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
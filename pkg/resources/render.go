@@ -0,0 +1,141 @@
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// RenderYAML renders the workload manifest with the debug sidecar added
+	RenderYAML = "yaml"
+	// RenderPatch renders a strategic merge patch adding the debug sidecar
+	RenderPatch = "patch"
+	// RenderKustomize renders a kustomize component with a JSON6902 patch adding the debug sidecar
+	RenderKustomize = "kustomize"
+)
+
+// ValidateRenderFormat returns an error if the format is not yaml, patch or kustomize
+func ValidateRenderFormat(format string) error {
+	switch format {
+	case RenderYAML, RenderPatch, RenderKustomize:
+		return nil
+	}
+	return fmt.Errorf("invalid output format %s, supported formats are %s, %s and %s", format, RenderYAML, RenderPatch, RenderKustomize)
+}
+
+// RenderedSidecar represents a workload manifest with the debug sidecar added offline, and the patches adding it
+type RenderedSidecar struct {
+	// Workload is the workload manifest with the debug sidecar added
+	Workload *unstructured.Unstructured
+	// StrategicMergePatch adds the debug sidecar to the workload, with the apiVersion, kind and name of the workload
+	StrategicMergePatch map[string]interface{}
+	// JSONPatch adds the debug sidecar to the workload
+	JSONPatch []JSONPatchOperation
+	// PodMonitor is the PodMonitor scraping the metrics endpoint, if requested
+	PodMonitor *unstructured.Unstructured
+}
+
+// ReadWorkloadManifest returns the workload of kind with name from a stream of YAML or JSON manifests
+func ReadWorkloadManifest(r io.Reader, kind, name string) (*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var obj map[string]interface{}
+		err := decoder.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s %s not found in manifests", kind, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifests: %v", err)
+		}
+		u := &unstructured.Unstructured{Object: obj}
+		if obj != nil && u.GetKind() == kind && u.GetName() == name {
+			return u, nil
+		}
+	}
+}
+
+// RenderDebugSidecar adds the debug sidecar to a workload manifest without a cluster.
+// The secret mounted into the sidecar and the PodMonitor are not part of the workload and must be committed with it
+func RenderDebugSidecar(workload *unstructured.Unstructured, namespace, containerToDebug, debugimage, secretname string, opts SidecarOptions) (RenderedSidecar, error) {
+	original, err := workload.MarshalJSON()
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+	var (
+		dataStruct interface{}
+		template   *corev1.PodTemplateSpec
+		selector   **metav1.LabelSelector
+	)
+	switch workload.GetKind() {
+	case "Deployment":
+		d := &appsv1.Deployment{}
+		dataStruct, template, selector = d, &d.Spec.Template, &d.Spec.Selector
+	case "DaemonSet":
+		d := &appsv1.DaemonSet{}
+		dataStruct, template, selector = d, &d.Spec.Template, &d.Spec.Selector
+	default:
+		return RenderedSidecar{}, fmt.Errorf("unsupported workload kind %s, supported kinds are Deployment and DaemonSet", workload.GetKind())
+	}
+	err = json.Unmarshal(original, dataStruct)
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+	before, err := json.Marshal(dataStruct)
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+	*template, err = AddDebugContainerPodTemplate(*template, namespace, containerToDebug, debugimage, secretname, opts)
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+	after, err := json.Marshal(dataStruct)
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+
+	// The patch is created from the typed workloads, and applied to the manifest to keep fields dmsctl does not know about
+	patch, err := strategicpatch.CreateTwoWayMergePatch(before, after, dataStruct)
+	if err != nil {
+		return RenderedSidecar{}, fmt.Errorf("failed to create strategic merge patch: %v", err)
+	}
+	rendered, err := strategicpatch.StrategicMergePatch(original, patch, dataStruct)
+	if err != nil {
+		return RenderedSidecar{}, fmt.Errorf("failed to apply strategic merge patch: %v", err)
+	}
+	result := RenderedSidecar{Workload: &unstructured.Unstructured{}}
+	err = result.Workload.UnmarshalJSON(rendered)
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+	err = json.Unmarshal(patch, &result.StrategicMergePatch)
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+	result.StrategicMergePatch["apiVersion"] = workload.GetAPIVersion()
+	result.StrategicMergePatch["kind"] = workload.GetKind()
+	result.StrategicMergePatch["metadata"] = map[string]interface{}{"name": workload.GetName()}
+	result.JSONPatch, err = JSONPatch(original, rendered)
+	if err != nil {
+		return RenderedSidecar{}, err
+	}
+
+	if opts.PodMonitorName != "" {
+		result.PodMonitor, err = GeneratePodMonitor(namespace, opts.PodMonitorName, workload.GetName(), *selector)
+		if err != nil {
+			return RenderedSidecar{}, err
+		}
+		if namespace == "" {
+			unstructured.RemoveNestedField(result.PodMonitor.Object, "metadata", "namespace")
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,172 @@
+package resources
+
+import (
+	"os"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestReadWorkloadManifest(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputfile string
+		kind      string
+		workload  string
+		wantErr   bool
+	}{
+		{
+			name:      "Finds deployment among other manifests",
+			inputfile: "testdata/render/render_test_deployment.yaml",
+			kind:      "Deployment",
+			workload:  "test",
+		},
+		{
+			name:      "Kind must match",
+			inputfile: "testdata/render/render_test_deployment.yaml",
+			kind:      "DaemonSet",
+			workload:  "test",
+			wantErr:   true,
+		},
+		{
+			name:      "Name must match",
+			inputfile: "testdata/render/render_test_deployment.yaml",
+			kind:      "Deployment",
+			workload:  "missing",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.inputfile)
+			if err != nil {
+				t.Errorf("failed to open inputfile %v", err)
+				return
+			}
+			defer f.Close()
+			got, err := ReadWorkloadManifest(f, tt.kind, tt.workload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadWorkloadManifest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.GetKind() != tt.kind || got.GetName() != tt.workload {
+				t.Errorf("ReadWorkloadManifest() = %s %s, want %s %s", got.GetKind(), got.GetName(), tt.kind, tt.workload)
+			}
+		})
+	}
+}
+
+func TestRenderDebugSidecar(t *testing.T) {
+	tests := []struct {
+		name             string
+		inputfile        string
+		kind             string
+		containerToDebug string
+		opts             SidecarOptions
+		goldenfile       string
+		patchgoldenfile  string
+		jsonpatchgolden  string
+		wantPodMonitor   bool
+		wantErr          bool
+	}{
+		{
+			name:            "Render deployment",
+			inputfile:       "testdata/render/render_test_deployment.yaml",
+			kind:            "Deployment",
+			goldenfile:      "testdata/render/render_test_deployment.golden",
+			patchgoldenfile: "testdata/render/render_test_deployment_patch.golden",
+			jsonpatchgolden: "testdata/render/render_test_deployment_jsonpatch.golden",
+		},
+		{
+			name:             "Render daemonset with pod monitor",
+			inputfile:        "testdata/render/render_test_daemonset.yaml",
+			kind:             "DaemonSet",
+			containerToDebug: "test",
+			opts:             SidecarOptions{Metrics: true, PodMonitorName: PodMonitorName("test")},
+			goldenfile:       "testdata/render/render_test_daemonset.golden",
+			patchgoldenfile:  "testdata/render/render_test_daemonset_patch.golden",
+			jsonpatchgolden:  "testdata/render/render_test_daemonset_jsonpatch.golden",
+			wantPodMonitor:   true,
+		},
+		{
+			name:      "Render daemonset without container to debug",
+			inputfile: "testdata/render/render_test_daemonset.yaml",
+			kind:      "DaemonSet",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.inputfile)
+			if err != nil {
+				t.Errorf("failed to open inputfile %v", err)
+				return
+			}
+			defer f.Close()
+			workload, err := ReadWorkloadManifest(f, tt.kind, "test")
+			if err != nil {
+				t.Errorf("ReadWorkloadManifest() error = %v", err)
+				return
+			}
+			got, err := RenderDebugSidecar(workload, "test", tt.containerToDebug, "mcr.microsoft.com/dotnet/monitor:8", "secret", tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RenderDebugSidecar() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			var expected map[string]interface{}
+			err = readUpdateGoldenFile(tt.goldenfile, *update, &expected, got.Workload.Object)
+			if err != nil {
+				t.Errorf("readUpdateGoldenFile() error = %v", err)
+				return
+			}
+			assertYAMLEqual(t, "workload", expected, got.Workload.Object)
+			var expectedPatch map[string]interface{}
+			err = readUpdateGoldenFile(tt.patchgoldenfile, *update, &expectedPatch, got.StrategicMergePatch)
+			if err != nil {
+				t.Errorf("readUpdateGoldenFile() error = %v", err)
+				return
+			}
+			assertYAMLEqual(t, "strategic merge patch", expectedPatch, got.StrategicMergePatch)
+			var expectedJSONPatch []JSONPatchOperation
+			err = readUpdateGoldenFile(tt.jsonpatchgolden, *update, &expectedJSONPatch, got.JSONPatch)
+			if err != nil {
+				t.Errorf("readUpdateGoldenFile() error = %v", err)
+				return
+			}
+			assertYAMLEqual(t, "json patch", expectedJSONPatch, got.JSONPatch)
+			if (got.PodMonitor != nil) != tt.wantPodMonitor {
+				t.Errorf("RenderDebugSidecar() PodMonitor = %v, want PodMonitor %v", got.PodMonitor, tt.wantPodMonitor)
+			}
+			annotations, _, _ := unstructured.NestedStringMap(workload.Object, "spec", "template", "metadata", "annotations")
+			if HasDebugSidecar(annotations) {
+				t.Errorf("RenderDebugSidecar() changed the input workload")
+			}
+		})
+	}
+}
+
+func assertYAMLEqual(t *testing.T, what string, expected, actual interface{}) {
+	t.Helper()
+	e, a := objToYAML(expected), objToYAML(actual)
+	if e != a {
+		dmp := diffmatchpatch.New()
+		diffs := dmp.DiffMain(e, a, false)
+		t.Errorf("Rendered %s did not match. Difference:\n%s", what, dmp.DiffPrettyText(diffs))
+	}
+}
+
+func objToYAML(obj interface{}) string {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
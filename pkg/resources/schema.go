@@ -0,0 +1,79 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DDConfigSchemaVersion is the schema version of the DDConfig written by this version of the cli.
+// Bump it and add a migration when a change to DDConfig can not be read by the previous version
+const DDConfigSchemaVersion = 2
+
+// ddConfigMigrations migrates a raw DDConfig from the schema version of its key to the next schema version
+var ddConfigMigrations = map[int]func(raw map[string]interface{}) error{
+	// Version 1 is the unversioned format written by releases before the schemaVersion field was added.
+	// All fields added to it since were optional, so only the version is added
+	1: func(raw map[string]interface{}) error {
+		return nil
+	},
+}
+
+// ParseDDConfig parses the DDConfig annotation written by any released version of the cli, migrating it to the current schema
+func ParseDDConfig(annotation string) (DDConfig, error) {
+	raw := map[string]interface{}{}
+	err := json.Unmarshal([]byte(annotation), &raw)
+	if err != nil {
+		return DDConfig{}, fmt.Errorf("failed to parse debug sidecar config: %v", err)
+	}
+	version, err := ddConfigSchemaVersion(raw)
+	if err != nil {
+		return DDConfig{}, err
+	}
+	if version > DDConfigSchemaVersion {
+		return DDConfig{}, fmt.Errorf("debug sidecar config schema version %d is newer than the supported version %d, upgrade dmsctl", version, DDConfigSchemaVersion)
+	}
+	for ; version < DDConfigSchemaVersion; version++ {
+		migrate, ok := ddConfigMigrations[version]
+		if !ok {
+			return DDConfig{}, fmt.Errorf("no migration from debug sidecar config schema version %d", version)
+		}
+		err = migrate(raw)
+		if err != nil {
+			return DDConfig{}, fmt.Errorf("failed to migrate debug sidecar config from schema version %d: %v", version, err)
+		}
+		raw["schemaVersion"] = version + 1
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return DDConfig{}, err
+	}
+	appliedConfig := DDConfig{}
+	err = json.Unmarshal(b, &appliedConfig)
+	if err != nil {
+		return DDConfig{}, fmt.Errorf("failed to parse debug sidecar config: %v", err)
+	}
+	return appliedConfig, nil
+}
+
+// MarshalDDConfig returns the DDConfig annotation value in the current schema version
+func MarshalDDConfig(appliedConfig DDConfig) (string, error) {
+	appliedConfig.SchemaVersion = DDConfigSchemaVersion
+	b, err := json.Marshal(appliedConfig)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ddConfigSchemaVersion returns the schema version of a raw DDConfig, 1 if it is unversioned
+func ddConfigSchemaVersion(raw map[string]interface{}) (int, error) {
+	v, ok := raw["schemaVersion"]
+	if !ok {
+		return 1, nil
+	}
+	version, ok := v.(float64)
+	if !ok || version < 1 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid debug sidecar config schema version %v", v)
+	}
+	return int(version), nil
+}
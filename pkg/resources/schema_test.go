@@ -0,0 +1,78 @@
+package resources
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDDConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		want       DDConfig
+		wantErr    bool
+	}{
+		{
+			name:       "Parse unversioned schema 1",
+			annotation: `{"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":true,"secretMount":"secret"}`,
+			want: DDConfig{
+				SchemaVersion:      DDConfigSchemaVersion,
+				ContainerToDebug:   "test",
+				DebugContainerName: "debug",
+				TmpdirAdded:        true,
+				SecretName:         "secret",
+			},
+		},
+		{
+			name:       "Parse schema 2",
+			annotation: `{"schemaVersion":2,"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":true,"secretMount":"secret","monitorVersion":8}`,
+			want: DDConfig{
+				SchemaVersion:      DDConfigSchemaVersion,
+				ContainerToDebug:   "test",
+				DebugContainerName: "debug",
+				TmpdirAdded:        true,
+				SecretName:         "secret",
+				MonitorVersion:     8,
+			},
+		},
+		{
+			name:       "Newer schema is not supported",
+			annotation: `{"schemaVersion":3,"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":true,"secretMount":"secret"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "Invalid schema version",
+			annotation: `{"schemaVersion":"2","containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":true,"secretMount":"secret"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "Invalid json",
+			annotation: `{"containerToDebug":test}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDDConfig(tt.annotation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDDConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDDConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalDDConfig(t *testing.T) {
+	got, err := MarshalDDConfig(DDConfig{ContainerToDebug: "test", DebugContainerName: "debug", SecretName: "secret"})
+	if err != nil {
+		t.Errorf("MarshalDDConfig() error = %v", err)
+		return
+	}
+	want := `{"schemaVersion":2,"containerToDebug":"test","debugContainerName":"debug","tmpdirAdded":false,"secretMount":"secret"}`
+	if got != want {
+		t.Errorf("MarshalDDConfig() = %s, want %s", got, want)
+	}
+}
@@ -11,8 +11,6 @@ import (
 const (
 	// SecretBaseName is the base name of the secret generated by the cli
 	SecretBaseName = "dd-monitor-apikey-"
-	// SecretLabel is the label used to identify the owner of the secret
-	SecretLabel = "dev.local/dd-secret"
 	// SubjectKey is the key for the subject in the secret
 	SubjectKey = "Authentication__MonitorApiKey__Subject"
 	// publicKeyKey is the key for the public key in the secret
@@ -20,15 +18,16 @@ const (
 )
 
 // GenerateSecret generates a kubernetes secret with a JWK public-key and subject
-func GenerateSecret(namespace, subject, key, owner string) corev1.Secret {
+func GenerateSecret(namespace, subject, key, owner string, ownerRefs ...metav1.OwnerReference) corev1.Secret {
 	secretName := fmt.Sprintf("%s%s", SecretBaseName, utilrand.String(5))
 	s := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      secretName,
 			Namespace: namespace,
 			Labels: map[string]string{
-				SecretLabel: owner,
+				SecretLabel(): owner,
 			},
+			OwnerReferences: ownerRefs,
 		},
 		Type: corev1.SecretTypeOpaque,
 	}
@@ -38,3 +37,13 @@ func GenerateSecret(namespace, subject, key, owner string) corev1.Secret {
 	}
 	return s
 }
+
+// WorkloadOwnerReference returns an owner reference to an apps/v1 workload, letting kubernetes garbage collect the owned object with the workload
+func WorkloadOwnerReference(kind string, workload metav1.Object) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       workload.GetName(),
+		UID:        workload.GetUID(),
+	}
+}
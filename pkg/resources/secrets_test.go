@@ -5,6 +5,8 @@ import (
 	"regexp"
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
 )
 
@@ -31,7 +33,8 @@ func TestGenerateSecret(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			actual := GenerateSecret(tt.args.namespace, tt.args.subject, tt.args.key, tt.args.owner)
+			workload := &metav1.ObjectMeta{Name: tt.args.owner, UID: types.UID(utilrand.String(5))}
+			actual := GenerateSecret(tt.args.namespace, tt.args.subject, tt.args.key, tt.args.owner, WorkloadOwnerReference("Deployment", workload))
 			nameRegxp := regexp.MustCompile(fmt.Sprintf("%s[a-zA-Z0-9_.-]{5}", SecretBaseName))
 			if !nameRegxp.MatchString(actual.Name) {
 				t.Errorf("Secret name %s does not match expected pattern %s", actual.Name, nameRegxp)
@@ -42,8 +45,11 @@ func TestGenerateSecret(t *testing.T) {
 			if string(actual.Data[publicKeyKey]) != tt.args.key {
 				t.Errorf("Secret publickey %s does not match expected key %s", string(actual.Data[publicKeyKey]), tt.args.key)
 			}
-			if actual.Labels[SecretLabel] != tt.args.owner {
-				t.Errorf("Secret owner %s does not match expected owner %s", actual.Labels[SecretLabel], tt.args.owner)
+			if actual.Labels[SecretLabel()] != tt.args.owner {
+				t.Errorf("Secret owner %s does not match expected owner %s", actual.Labels[SecretLabel()], tt.args.owner)
+			}
+			if len(actual.OwnerReferences) != 1 || actual.OwnerReferences[0].Name != tt.args.owner || actual.OwnerReferences[0].UID != workload.UID {
+				t.Errorf("Secret owner references %v does not reference workload %s", actual.OwnerReferences, tt.args.owner)
 			}
 		})
 	}
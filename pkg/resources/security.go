@@ -0,0 +1,129 @@
+package resources
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// SecurityProfileLegacy runs the sidecar with SYS_PTRACE, which is only allowed by the privileged Pod Security Standard
+	SecurityProfileLegacy = "legacy"
+	// SecurityProfileBaseline runs the sidecar compliant with the baseline Pod Security Standard
+	SecurityProfileBaseline = "baseline"
+	// SecurityProfileRestricted runs the sidecar compliant with the restricted Pod Security Standard
+	SecurityProfileRestricted = "restricted"
+)
+
+// podSecurityLevels maps the Pod Security Standard levels to their strictness
+var podSecurityLevels = map[string]int{
+	"privileged": 0,
+	"baseline":   1,
+	"restricted": 2,
+}
+
+// ValidateSecurityProfile returns an error if profile is not a known security profile
+func ValidateSecurityProfile(profile string) error {
+	switch profile {
+	case "", SecurityProfileLegacy, SecurityProfileBaseline, SecurityProfileRestricted:
+		return nil
+	}
+	return fmt.Errorf("unknown security profile %s, valid profiles are %s, %s and %s", profile, SecurityProfileRestricted, SecurityProfileBaseline, SecurityProfileLegacy)
+}
+
+// SecurityProfileSatisfies returns true if the sidecar generated with profile is allowed by the Pod Security Standard level.
+// Unknown levels are treated as privileged, like the Pod Security admission does
+func SecurityProfileSatisfies(profile, level string) bool {
+	required := podSecurityLevels[level]
+	switch profile {
+	case SecurityProfileRestricted:
+		return required <= podSecurityLevels["restricted"]
+	case SecurityProfileBaseline:
+		return required <= podSecurityLevels["baseline"]
+	}
+	return required <= podSecurityLevels["privileged"]
+}
+
+// securityContext returns the security context of the debug sidecar for the version and security profile
+func (v MonitorVersion) securityContext(profile string) *corev1.SecurityContext {
+	switch profile {
+	case SecurityProfileRestricted:
+		runAsUser := nonRootUID
+		if v.RunAsUser != nil {
+			runAsUser = *v.RunAsUser
+		}
+		return &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+			RunAsUser:                int64Ptr(runAsUser),
+			RunAsNonRoot:             boolPtr(true),
+			AllowPrivilegeEscalation: boolPtr(false),
+			ReadOnlyRootFilesystem:   boolPtr(true),
+			SeccompProfile: &corev1.SeccompProfile{
+				Type: corev1.SeccompProfileTypeRuntimeDefault,
+			},
+		}
+	case SecurityProfileBaseline:
+		sc := &corev1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+		}
+		if v.RunAsUser != nil {
+			sc.RunAsUser = int64Ptr(*v.RunAsUser)
+			sc.RunAsNonRoot = boolPtr(true)
+		}
+		return sc
+	}
+	sc := &corev1.SecurityContext{
+		Capabilities: &corev1.Capabilities{
+			Add: []corev1.Capability{
+				corev1.Capability("SYS_PTRACE"),
+			},
+		},
+	}
+	if v.RunAsUser != nil {
+		sc.RunAsUser = int64Ptr(*v.RunAsUser)
+		sc.RunAsNonRoot = boolPtr(true)
+	}
+	return sc
+}
+
+// targetIdentity returns the effective user and group of a container, where the container security context takes precedence over the pod
+func targetIdentity(podSpec corev1.PodSpec, containerName string) (runAsUser, runAsGroup *int64) {
+	if podSpec.SecurityContext != nil {
+		runAsUser = podSpec.SecurityContext.RunAsUser
+		runAsGroup = podSpec.SecurityContext.RunAsGroup
+	}
+	for _, c := range podSpec.Containers {
+		if c.Name != containerName || c.SecurityContext == nil {
+			continue
+		}
+		if c.SecurityContext.RunAsUser != nil {
+			runAsUser = c.SecurityContext.RunAsUser
+		}
+		if c.SecurityContext.RunAsGroup != nil {
+			runAsGroup = c.SecurityContext.RunAsGroup
+		}
+	}
+	return runAsUser, runAsGroup
+}
+
+// shareIdentity sets the user and group of the sidecar to the ones of the target container, so both can access the diagnostic socket in /tmp.
+// Root is not mirrored when the restricted profile is used. The values set on the sidecar are returned.
+// The fsGroup and supplementalGroups of the pod are not copied, as they only exist in the pod security context and are
+// given to the processes of every container in the pod, the sidecar included, along with the group ownership of the /tmp volume
+func shareIdentity(sc *corev1.SecurityContext, runAsUser, runAsGroup *int64, profile string) (sharedUser, sharedGroup *int64) {
+	if runAsUser != nil && !(*runAsUser == 0 && profile == SecurityProfileRestricted) {
+		sc.RunAsUser = int64Ptr(*runAsUser)
+		sc.RunAsNonRoot = nil
+		if *runAsUser != 0 {
+			sc.RunAsNonRoot = boolPtr(true)
+		}
+		sharedUser = sc.RunAsUser
+	}
+	if runAsGroup != nil {
+		sc.RunAsGroup = int64Ptr(*runAsGroup)
+		sharedGroup = sc.RunAsGroup
+	}
+	return sharedUser, sharedGroup
+}
@@ -0,0 +1,142 @@
+package resources
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// processGroups returns the groups the kubelet runs the processes of a container with: the effective runAsGroup,
+// unless left to the image, followed by the fsGroup and supplementalGroups of the pod
+func processGroups(podSpec corev1.PodSpec, containerName string) []int64 {
+	var groups []int64
+	_, runAsGroup := targetIdentity(podSpec, containerName)
+	if runAsGroup != nil {
+		groups = append(groups, *runAsGroup)
+	}
+	if sc := podSpec.SecurityContext; sc != nil {
+		if sc.FSGroup != nil {
+			groups = append(groups, *sc.FSGroup)
+		}
+		groups = append(groups, sc.SupplementalGroups...)
+	}
+	return groups
+}
+
+func TestShareIdentityGroupAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		podContext *corev1.PodSecurityContext
+		runAsGroup *int64
+		profile    string
+		wantGroups []int64
+	}{
+		{
+			name:       "fsGroup gives access to /tmp without runAsGroup",
+			podContext: &corev1.PodSecurityContext{FSGroup: int64Ptr(2000)},
+			wantGroups: []int64{2000},
+		},
+		{
+			name:       "fsGroup and supplementalGroups with runAsGroup of the container",
+			podContext: &corev1.PodSecurityContext{FSGroup: int64Ptr(2000), SupplementalGroups: []int64{3000}},
+			runAsGroup: int64Ptr(1000),
+			wantGroups: []int64{1000, 2000, 3000},
+		},
+		{
+			name:       "fsGroup with restricted security profile",
+			podContext: &corev1.PodSecurityContext{FSGroup: int64Ptr(2000)},
+			profile:    SecurityProfileRestricted,
+			wantGroups: []int64{2000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					SecurityContext: tt.podContext.DeepCopy(),
+					Containers: []corev1.Container{{
+						Name:            "app",
+						SecurityContext: &corev1.SecurityContext{RunAsUser: int64Ptr(1000), RunAsGroup: tt.runAsGroup},
+					}},
+				},
+			}
+			actual, err := AddDebugContainerPodTemplate(template, "test", "app", "mcr.microsoft.com/dotnet/monitor:8", "secret", SidecarOptions{SecurityProfile: tt.profile})
+			if err != nil {
+				t.Fatalf("AddDebugContainerPodTemplate() error = %v", err)
+			}
+			config, err := DDConfigFromPodTemplate(actual)
+			if err != nil {
+				t.Fatalf("DDConfigFromPodTemplate() error = %v", err)
+			}
+			targetGroups := processGroups(actual.Spec, "app")
+			debugGroups := processGroups(actual.Spec, config.DebugContainerName)
+			if !slices.Equal(targetGroups, tt.wantGroups) {
+				t.Errorf("container app runs with groups %v, want %v", targetGroups, tt.wantGroups)
+			}
+			if !slices.Equal(debugGroups, targetGroups) {
+				t.Errorf("debug container runs with groups %v, want the groups %v of container app", debugGroups, targetGroups)
+			}
+			debugUser, _ := targetIdentity(actual.Spec, config.DebugContainerName)
+			if debugUser == nil || *debugUser != 1000 {
+				t.Errorf("debug container runs as %s, want uid 1000", userString(debugUser))
+			}
+		})
+	}
+}
+
+func TestShareIdentityTargetWithoutUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		debugimage string
+		opts       SidecarOptions
+		wantUser   *int64
+	}{
+		{
+			name:       "Non-root sidecar image runs as root like the image of the container",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:8",
+			wantUser:   int64Ptr(0),
+		},
+		{
+			name:       "Non-root sidecar image runs as root with baseline security profile",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:8",
+			opts:       SidecarOptions{SecurityProfile: SecurityProfileBaseline},
+			wantUser:   int64Ptr(0),
+		},
+		{
+			name:       "Restricted security profile does not run as root",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:8",
+			opts:       SidecarOptions{SecurityProfile: SecurityProfileRestricted},
+			wantUser:   int64Ptr(nonRootUID),
+		},
+		{
+			name:       "Root sidecar image runs as the user of its image",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:6",
+		},
+		{
+			name:       "Non-root sidecar image runs as the user of its image without sharing identity",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:8",
+			opts:       SidecarOptions{SkipIdentitySharing: true},
+			wantUser:   int64Ptr(nonRootUID),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			}
+			actual, err := AddDebugContainerPodTemplate(template, "test", "app", tt.debugimage, "secret", tt.opts)
+			if err != nil {
+				t.Fatalf("AddDebugContainerPodTemplate() error = %v", err)
+			}
+			config, err := DDConfigFromPodTemplate(actual)
+			if err != nil {
+				t.Fatalf("DDConfigFromPodTemplate() error = %v", err)
+			}
+			debugUser, _ := targetIdentity(actual.Spec, config.DebugContainerName)
+			if userString(debugUser) != userString(tt.wantUser) {
+				t.Errorf("debug container runs as %s, want %s", userString(debugUser), userString(tt.wantUser))
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package resources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SnapshotAnnotation returns the workload annotation holding the compressed pod template from before the sidecar was added
+func SnapshotAnnotation() string {
+	return keyPrefix + snapshotKey
+}
+
+// SnapshotPodTemplate stores a compressed snapshot of the pod template from before the sidecar was added in the workload annotations.
+// The annotations are on the workload and not the pod template, so they do not cause a rollout.
+// Must be called on the workload as read before the update adding the sidecar
+func SnapshotPodTemplate(meta *metav1.ObjectMeta, original corev1.PodTemplateSpec) error {
+	b, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err = zw.Write(b)
+	if err != nil {
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	removeSnapshotAnnotations(meta)
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[SnapshotAnnotation()] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	return nil
+}
+
+// AddedHashAnnotation returns the workload annotation holding the hash of the pod template with the sidecar added
+func AddedHashAnnotation() string {
+	return keyPrefix + addedHashKey
+}
+
+// RecordAddedPodTemplate stores the hash of the pod template as written by the api server with the sidecar added in the
+// workload annotations, so RestorePodTemplate can tell it has not changed since. Must be called with the template of the
+// workload returned by the update adding the sidecar, and does nothing if the workload has no snapshot
+func RecordAddedPodTemplate(meta *metav1.ObjectMeta, added corev1.PodTemplateSpec) error {
+	if _, ok := lookup(meta.Annotations, snapshotKey); !ok {
+		return nil
+	}
+	hash, err := podTemplateHash(added)
+	if err != nil {
+		return err
+	}
+	meta.Annotations[AddedHashAnnotation()] = hash
+	return nil
+}
+
+// podTemplateHash returns the hex encoded sha256 of the json of a pod template
+func podTemplateHash(template corev1.PodTemplateSpec) (string, error) {
+	b, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RestoredPodTemplate represents the pod template of a workload with the debug sidecar removed
+type RestoredPodTemplate struct {
+	Template corev1.PodTemplateSpec
+	// Exact is true if the template is the snapshot taken when the sidecar was added
+	Exact bool
+	// Diff is the difference from the snapshot to the surgically restored template, if the snapshot could not be used
+	Diff string
+	// Reason is why the snapshot could not be used
+	Reason string
+	// Config is the DDConfig of the removed debug sidecar
+	Config DDConfig
+}
+
+// RestorePodTemplate removes the debug sidecar from the pod template of a workload and the snapshot annotations from the workload.
+// With exact set the snapshot taken when the sidecar was added is restored if the pod template has not changed since, as
+// recorded by RecordAddedPodTemplate, or if removing the sidecar surgically gives the snapshot. This also reverts changes
+// surgical removal does not know about, like the ones made by the api server when the sidecar was added.
+// Otherwise it falls back to removing the sidecar surgically, returning the diff to the snapshot
+func RestorePodTemplate(meta *metav1.ObjectMeta, template corev1.PodTemplateSpec, namespace, containerToDebug string, exact bool) (RestoredPodTemplate, error) {
+	surgical, err := RemoveDebugContainerPodTemplate(template, namespace, containerToDebug)
+	if err != nil {
+		return RestoredPodTemplate{}, err
+	}
+	snapshot, snapshotFound, err := podTemplateSnapshot(*meta)
+	addedHash, _ := lookup(meta.Annotations, addedHashKey)
+	removeSnapshotAnnotations(meta)
+	if !exact {
+		return RestoredPodTemplate{Template: surgical}, nil
+	}
+	if err != nil {
+		return RestoredPodTemplate{}, err
+	}
+	if !snapshotFound {
+		return RestoredPodTemplate{Template: surgical, Reason: "no snapshot of the pod template was stored when the sidecar was added"}, nil
+	}
+	hash, err := podTemplateHash(template)
+	if err != nil {
+		return RestoredPodTemplate{}, err
+	}
+	if hash == addedHash || apiequality.Semantic.DeepEqual(surgical, snapshot) {
+		return RestoredPodTemplate{Template: snapshot, Exact: true}, nil
+	}
+	diff, err := PodTemplateDiff("snapshot", "restored", snapshot, surgical)
+	if err != nil {
+		return RestoredPodTemplate{}, err
+	}
+	return RestoredPodTemplate{
+		Template: surgical,
+		Diff:     diff,
+		Reason:   "the pod template changed after the sidecar was added",
+	}, nil
+}
+
+// podTemplateSnapshot returns the pod template snapshot stored in the workload annotations, and false if there is none
+func podTemplateSnapshot(meta metav1.ObjectMeta) (corev1.PodTemplateSpec, bool, error) {
+	v, ok := lookup(meta.Annotations, snapshotKey)
+	if !ok {
+		return corev1.PodTemplateSpec{}, false, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, true, fmt.Errorf("failed to decode pod template snapshot: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return corev1.PodTemplateSpec{}, true, fmt.Errorf("failed to decompress pod template snapshot: %v", err)
+	}
+	b, err = io.ReadAll(zr)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, true, fmt.Errorf("failed to decompress pod template snapshot: %v", err)
+	}
+	var template corev1.PodTemplateSpec
+	err = json.Unmarshal(b, &template)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, true, fmt.Errorf("failed to parse pod template snapshot: %v", err)
+	}
+	return template, true, nil
+}
+
+// removeSnapshotAnnotations removes the snapshot annotations under the current and legacy prefix from a workload
+func removeSnapshotAnnotations(meta *metav1.ObjectMeta) {
+	for _, k := range append(keys(snapshotKey), keys(addedHashKey)...) {
+		delete(meta.Annotations, k)
+	}
+}
@@ -0,0 +1,191 @@
+package resources
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRestorePodTemplate(t *testing.T) {
+	original := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "test"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "app:1"},
+				{Name: "proxy", Image: "proxy:1"},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		exact      bool
+		snapshot   bool
+		written    func(template *corev1.PodTemplateSpec)
+		record     bool
+		change     func(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec)
+		wantExact  bool
+		wantImage  string
+		wantDiff   string
+		wantReason bool
+	}{
+		{
+			name:      "Restores snapshot of unchanged workload",
+			exact:     true,
+			snapshot:  true,
+			wantExact: true,
+			wantImage: "app:1",
+		},
+		{
+			name:     "Falls back to surgical removal with diff when workload changed",
+			exact:    true,
+			snapshot: true,
+			change: func(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Image = "app:2"
+			},
+			wantImage:  "app:2",
+			wantDiff:   "+  - image: app:2",
+			wantReason: true,
+		},
+		{
+			name:     "Restores snapshot when only the workload spec outside the pod template changed",
+			exact:    true,
+			snapshot: true,
+			change: func(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) {
+				// Scaling or changing the strategy bumps the generation without changing the pod template
+				meta.Generation += 2
+			},
+			wantExact: true,
+			wantImage: "app:1",
+		},
+		{
+			name:     "Falls back to surgical removal when the pod template changed outside the sidecar",
+			exact:    true,
+			snapshot: true,
+			change: func(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) {
+				template.Annotations["kubectl.kubernetes.io/restartedAt"] = "2024-01-01T00:00:00Z"
+			},
+			wantImage:  "app:1",
+			wantDiff:   "kubectl.kubernetes.io/restartedAt",
+			wantReason: true,
+		},
+		{
+			name:     "Restores snapshot of template unchanged since it was written, reverting what surgical removal keeps",
+			exact:    true,
+			snapshot: true,
+			written:  apiServerMutation,
+			record:   true,
+			change: func(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) {
+				// Scaling or changing the strategy bumps the generation without changing the pod template
+				meta.Generation += 2
+			},
+			wantExact: true,
+			wantImage: "app:1",
+		},
+		{
+			name:       "Falls back to surgical removal keeping what the api server changed without the template recorded",
+			exact:      true,
+			snapshot:   true,
+			written:    apiServerMutation,
+			wantImage:  "app:1",
+			wantDiff:   "+    sidecar.example.com/mutated",
+			wantReason: true,
+		},
+		{
+			name:     "Falls back to surgical removal when the recorded template changed",
+			exact:    true,
+			snapshot: true,
+			written:  apiServerMutation,
+			record:   true,
+			change: func(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Image = "app:2"
+			},
+			wantImage:  "app:2",
+			wantDiff:   "+  - image: app:2",
+			wantReason: true,
+		},
+		{
+			name:       "Falls back to surgical removal without snapshot",
+			exact:      true,
+			wantImage:  "app:1",
+			wantReason: true,
+		},
+		{
+			name:      "Removes surgically when exact restore is not requested",
+			snapshot:  true,
+			wantImage: "app:1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := metav1.ObjectMeta{Name: "test", Generation: 3}
+			template, err := AddDebugContainerPodTemplate(*original.DeepCopy(), "test", "app", "test:latest", "secret", SidecarOptions{})
+			if err != nil {
+				t.Errorf("AddDebugContainerPodTemplate() error = %v", err)
+				return
+			}
+			if tt.snapshot {
+				err = SnapshotPodTemplate(&meta, original)
+				if err != nil {
+					t.Errorf("SnapshotPodTemplate() error = %v", err)
+					return
+				}
+			}
+			if tt.written != nil {
+				tt.written(&template)
+			}
+			if tt.record {
+				err = RecordAddedPodTemplate(&meta, template)
+				if err != nil {
+					t.Errorf("RecordAddedPodTemplate() error = %v", err)
+					return
+				}
+			}
+			// The api server bumps the generation on spec changes
+			meta.Generation++
+			if tt.change != nil {
+				tt.change(&meta, &template)
+			}
+			restored, err := RestorePodTemplate(&meta, template, "test", "app", tt.exact)
+			if err != nil {
+				t.Errorf("RestorePodTemplate() error = %v", err)
+				return
+			}
+			if restored.Exact != tt.wantExact {
+				t.Errorf("RestorePodTemplate() exact = %v, want %v", restored.Exact, tt.wantExact)
+			}
+			if restored.Exact && !apiequality.Semantic.DeepEqual(restored.Template, original) {
+				t.Errorf("RestorePodTemplate() did not restore the snapshot")
+			}
+			if len(restored.Template.Spec.Containers) != 2 || restored.Template.Spec.Containers[0].Image != tt.wantImage {
+				t.Errorf("RestorePodTemplate() containers = %v, want app image %s", restored.Template.Spec.Containers, tt.wantImage)
+			}
+			if !strings.Contains(restored.Diff, tt.wantDiff) {
+				t.Errorf("RestorePodTemplate() diff = %s, want it to contain %s", restored.Diff, tt.wantDiff)
+			}
+			if (restored.Reason != "") != tt.wantReason {
+				t.Errorf("RestorePodTemplate() reason = %s, wantReason %v", restored.Reason, tt.wantReason)
+			}
+			surgical, err := RemoveDebugContainerPodTemplate(template, "test", "app")
+			if err != nil {
+				t.Errorf("RemoveDebugContainerPodTemplate() error = %v", err)
+				return
+			}
+			if tt.written != nil && restored.Exact && apiequality.Semantic.DeepEqual(restored.Template, surgical) {
+				t.Errorf("RestorePodTemplate() restored the same template as surgical removal")
+			}
+			for _, k := range []string{SnapshotAnnotation(), AddedHashAnnotation()} {
+				if _, ok := meta.Annotations[k]; ok {
+					t.Errorf("RestorePodTemplate() did not remove the annotation %s", k)
+				}
+			}
+		})
+	}
+}
+
+// apiServerMutation changes the pod template like a mutating admission webhook does when the sidecar is added
+func apiServerMutation(template *corev1.PodTemplateSpec) {
+	template.Annotations["sidecar.example.com/mutated"] = "true"
+}
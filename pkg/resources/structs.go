@@ -1,7 +1,15 @@
 package resources
 
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 // DDConfig represents the configuration applied for the debug sidecar
 type DDConfig struct {
+	// SchemaVersion is the version of the DDConfig format, see DDConfigSchemaVersion
+	SchemaVersion int `json:"schemaVersion,omitempty"`
 	// The name of the container to debug
 	ContainerToDebug string `json:"containerToDebug"`
 	// The name of the sidecar.
@@ -10,4 +18,71 @@ type DDConfig struct {
 	TmpdirAdded bool `json:"tmpdirAdded"`
 	// SecretMount reflect name of created and mounted
 	SecretName string `json:"secretMount"`
+	// RulesConfigMap reflects name of the collection rules configmap mounted into the sidecar
+	RulesConfigMap string `json:"rulesConfigMap,omitempty"`
+	// Metrics reflects if the prometheus metrics endpoint was exposed
+	Metrics bool `json:"metrics,omitempty"`
+	// AddedAnnotations reflects the pod template annotations added by dd, other than its own
+	AddedAnnotations []string `json:"addedAnnotations,omitempty"`
+	// PodMonitor reflects name of the PodMonitor created for the metrics endpoint
+	PodMonitor string `json:"podMonitor,omitempty"`
+	// MonitorVersion reflects the major version of dotnet-monitor the sidecar was configured for
+	MonitorVersion int `json:"monitorVersion,omitempty"`
+	// SecurityProfile reflects the security profile the sidecar was generated with
+	SecurityProfile string `json:"securityProfile,omitempty"`
+	// RunAsUser reflects the user mirrored from the container to debug onto the sidecar
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+	// RunAsGroup reflects the group mirrored from the container to debug onto the sidecar
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+	// ExpiresAt reflects when the sidecar expires and may be removed by dmsctl reap
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// Audit reflects who added the sidecar, when and why
+	Audit *AuditInfo `json:"audit,omitempty"`
+}
+
+// AuditInfo represents who changed the debug sidecar of a workload, when and why
+type AuditInfo struct {
+	// User is the kubernetes user making the change
+	User string `json:"user,omitempty"`
+	// Time is when the change was made
+	Time metav1.Time `json:"time"`
+	// CLIVersion is the version of dmsctl making the change
+	CLIVersion string `json:"cliVersion,omitempty"`
+	// Reason is why the change was made
+	Reason string `json:"reason,omitempty"`
+}
+
+// Expired returns true if the sidecar has an expiry which is before now
+func (c DDConfig) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && c.ExpiresAt.Time.Before(now)
+}
+
+// RemoveOptions represents the optional behaviour when removing the debug sidecar
+type RemoveOptions struct {
+	// RestoreExact restores the pod template snapshot taken when the sidecar was added, if the pod template has not changed since
+	RestoreExact bool
+	// DryRun is the dry run strategy, see DryRunClient and DryRunServer
+	DryRun string
+}
+
+// SidecarOptions represents the optional features of the debug sidecar
+type SidecarOptions struct {
+	// Metrics exposes the dotnet-monitor prometheus metrics endpoint
+	Metrics bool
+	// MetricsAnnotations adds prometheus.io scrape annotations to the pod template
+	MetricsAnnotations bool
+	// PodMonitorName is the name of the PodMonitor created for the metrics endpoint, if any
+	PodMonitorName string
+	// MonitorVersion overrides the dotnet-monitor major version parsed from the debug image tag
+	MonitorVersion int
+	// SecurityProfile selects the security context of the sidecar, legacy if empty
+	SecurityProfile string
+	// SkipIdentitySharing keeps the user and group of the debug image instead of mirroring the container to debug
+	SkipIdentitySharing bool
+	// ExpiresAt is when the sidecar expires, never if nil
+	ExpiresAt *metav1.Time
+	// Audit is recorded in the DDConfig, not recorded if nil
+	Audit *AuditInfo
+	// DryRun is the dry run strategy, see DryRunClient and DryRunServer. No resources are created in dry run
+	DryRun string
 }
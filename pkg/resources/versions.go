@@ -0,0 +1,139 @@
+package resources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// LatestMonitorVersion is the newest major version of dotnet-monitor known by the cli
+	LatestMonitorVersion = 8
+	// oldestMonitorVersion is the oldest major version of dotnet-monitor supported by the cli
+	oldestMonitorVersion = 6
+	// nonRootUID is the uid of the app user in the non-root dotnet images
+	nonRootUID int64 = 1654
+)
+
+// MonitorImage is the repository of the dotnet-monitor images
+const MonitorImage = "mcr.microsoft.com/dotnet/monitor"
+
+// monitorImageTags are the tags of the dotnet-monitor images of the supported major versions, newest first
+var monitorImageTags = []string{"8", "8.1", "8.0", "7", "7.3", "6", "6.3"}
+
+// MonitorImages returns the known dotnet-monitor images of the supported major versions, newest first
+func MonitorImages() []string {
+	images := make([]string, 0, len(monitorImageTags))
+	for _, tag := range monitorImageTags {
+		images = append(images, MonitorImage+":"+tag)
+	}
+	return images
+}
+
+// MonitorVersion describes how the debug sidecar is configured for a major version of dotnet-monitor
+type MonitorVersion struct {
+	// Major is the major version of dotnet-monitor
+	Major int
+	// Verb is the command passed to dotnet-monitor before the options, if any
+	Verb string
+	// Env is the environment set on the debug sidecar
+	Env []corev1.EnvVar
+	// RunAsUser is the user the image runs as, nil if the image runs as root
+	RunAsUser *int64
+}
+
+// monitorVersions is the registry of supported major versions. Versions newer than LatestMonitorVersion use the latest entry
+var monitorVersions = map[int]MonitorVersion{
+	6: {
+		Major: 6,
+	},
+	7: {
+		Major: 7,
+		Verb:  "collect",
+		Env: []corev1.EnvVar{
+			{Name: "DOTNETMONITOR_DiagnosticPort__ConnectionMode", Value: "Connect"},
+			{Name: "DOTNETMONITOR_Storage__DefaultSharedPath", Value: "/tmp"},
+		},
+	},
+	8: {
+		Major: 8,
+		Verb:  "collect",
+		Env: []corev1.EnvVar{
+			{Name: "DOTNETMONITOR_DiagnosticPort__ConnectionMode", Value: "Connect"},
+			{Name: "DOTNETMONITOR_Storage__DefaultSharedPath", Value: "/tmp"},
+		},
+		RunAsUser: int64Ptr(nonRootUID),
+	},
+}
+
+// ResolveMonitorVersion returns the dotnet-monitor version to configure the sidecar for.
+// The version is parsed from the debugimage tag unless override is set. Images without a version tag use the latest version
+func ResolveMonitorVersion(debugimage string, override int) (MonitorVersion, error) {
+	major := override
+	if major == 0 {
+		parsed, ok := monitorVersionFromImage(debugimage)
+		if !ok {
+			parsed = LatestMonitorVersion
+		}
+		major = parsed
+	}
+	if major < oldestMonitorVersion {
+		return MonitorVersion{}, fmt.Errorf("dotnet-monitor version %d is not supported, use version %d or newer", major, oldestMonitorVersion)
+	}
+	v, ok := monitorVersions[major]
+	if !ok {
+		v = monitorVersions[LatestMonitorVersion]
+		v.Major = major
+	}
+	return v, nil
+}
+
+// monitorVersionFromImage parses the major version from the tag of an image reference
+func monitorVersionFromImage(image string) (int, bool) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || i < strings.LastIndex(image, "/") {
+		return 0, false
+	}
+	tag := image[i+1:]
+	end := strings.IndexFunc(tag, func(r rune) bool { return r < '0' || r > '9' })
+	if end >= 0 {
+		tag = tag[:end]
+	}
+	major, err := strconv.Atoi(tag)
+	if err != nil {
+		return 0, false
+	}
+	return major, true
+}
+
+// args returns the arguments passed to dotnet-monitor
+func (v MonitorVersion) args(metrics bool) []string {
+	var args []string
+	if v.Verb != "" {
+		args = append(args, v.Verb)
+	}
+	args = append(args, "--urls", "http://*:52323")
+	if metrics {
+		args = append(args, "--metricUrls", fmt.Sprintf("http://*:%d", MetricsPort))
+	}
+	return args
+}
+
+// env returns the environment of the debug sidecar. The api key is not set in the environment, every version reads it
+// from the secret mounted at /etc/dotnet-monitor, so a rotated key reaches running sidecars
+func (v MonitorVersion) env() []corev1.EnvVar {
+	return append([]corev1.EnvVar{}, v.Env...)
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,78 @@
+package resources
+
+import "testing"
+
+func TestResolveMonitorVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		debugimage string
+		override   int
+		wantMajor  int
+		wantVerb   string
+		wantErr    bool
+	}{
+		{
+			name:       "Parses major version from tag",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:6.0",
+			wantMajor:  6,
+			wantVerb:   "",
+		},
+		{
+			name:       "Parses major version from tag with suffix",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:7.3.2-alpine",
+			wantMajor:  7,
+			wantVerb:   "collect",
+		},
+		{
+			name:       "Parses major version from tag with digest",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:8.0@sha256:0123456789abcdef",
+			wantMajor:  8,
+			wantVerb:   "collect",
+		},
+		{
+			name:       "Uses latest profile for newer versions",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:9.0",
+			wantMajor:  9,
+			wantVerb:   "collect",
+		},
+		{
+			name:       "Uses latest version when tag has no version",
+			debugimage: "localhost:5000/dotnet/monitor:latest",
+			wantMajor:  LatestMonitorVersion,
+			wantVerb:   "collect",
+		},
+		{
+			name:       "Uses latest version when image has no tag",
+			debugimage: "localhost:5000/dotnet/monitor",
+			wantMajor:  LatestMonitorVersion,
+			wantVerb:   "collect",
+		},
+		{
+			name:       "Override takes precedence over tag",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:8",
+			override:   6,
+			wantMajor:  6,
+			wantVerb:   "",
+		},
+		{
+			name:       "Unsupported version",
+			debugimage: "mcr.microsoft.com/dotnet/monitor:5.0",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveMonitorVersion(tt.debugimage, tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ResolveMonitorVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.Major != tt.wantMajor {
+				t.Errorf("ResolveMonitorVersion() major = %d, want %d", got.Major, tt.wantMajor)
+			}
+			if got.Verb != tt.wantVerb {
+				t.Errorf("ResolveMonitorVersion() verb = %s, want %s", got.Verb, tt.wantVerb)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package resources
 import (
 	"fmt"
 
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
 )
@@ -10,7 +11,7 @@ import (
 func getTmpVolume(podSpec corev1.PodSpec, containerToDebug string) (bool, corev1.Volume, error) {
 	containerCount := len(podSpec.Containers)
 	if containerCount > 1 && containerToDebug == "" {
-		return false, corev1.Volume{}, fmt.Errorf("multiple containers present, pleas supply the one you want to debug")
+		return false, corev1.Volume{}, &dmserrors.ContainerError{Containers: containerNames(podSpec.Containers), Err: dmserrors.ErrMultipleContainers}
 	}
 	var volumeMounts []corev1.VolumeMount
 	if containerCount > 1 {
@@ -22,7 +23,7 @@ func getTmpVolume(podSpec corev1.PodSpec, containerToDebug string) (bool, corev1
 			}
 		}
 		if !found {
-			return false, corev1.Volume{}, fmt.Errorf("could not find container with name %s", containerToDebug)
+			return false, corev1.Volume{}, &dmserrors.ContainerError{Container: containerToDebug, Containers: containerNames(podSpec.Containers), Err: dmserrors.ErrContainerNotFound}
 		}
 	} else {
 		volumeMounts = podSpec.Containers[0].VolumeMounts
@@ -44,16 +45,24 @@ func getTmpVolume(podSpec corev1.PodSpec, containerToDebug string) (bool, corev1
 	}, nil
 }
 
+func containerNames(containers []corev1.Container) []string {
+	names := make([]string, 0, len(containers))
+	for _, c := range containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
 func removeTmpVolumeMount(containers []corev1.Container, containerToDebug, tmpVolumeName string) []corev1.Container {
+	containers = append([]corev1.Container{}, containers...)
 	for i, c := range containers {
 		if containerToDebug == "" || c.Name == containerToDebug {
 			for j, v := range c.VolumeMounts {
 				if v.Name == tmpVolumeName {
-					c.VolumeMounts = append(c.VolumeMounts[:j], c.VolumeMounts[j+1:]...)
+					containers[i].VolumeMounts = append(append([]corev1.VolumeMount{}, c.VolumeMounts[:j]...), c.VolumeMounts[j+1:]...)
 					break
 				}
 			}
-			containers[i].VolumeMounts = c.VolumeMounts
 		}
 	}
 	return containers
@@ -62,7 +71,7 @@ func removeTmpVolumeMount(containers []corev1.Container, containerToDebug, tmpVo
 func removeVolume(volumes []corev1.Volume, tmpVolumeName string) []corev1.Volume {
 	for i, v := range volumes {
 		if v.Name == tmpVolumeName {
-			volumes = append(volumes[:i], volumes[i+1:]...)
+			volumes = append(append([]corev1.Volume{}, volumes[:i]...), volumes[i+1:]...)
 			break
 		}
 	}
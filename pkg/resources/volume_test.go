@@ -1,11 +1,13 @@
 package resources
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
 	"testing"
 
+	dmserrors "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	utilrand "k8s.io/apimachinery/pkg/util/rand"
 )
@@ -21,6 +23,7 @@ func Test_getTmpVolume(t *testing.T) {
 		expExisting bool
 		expVolume   corev1.Volume
 		wantErr     bool
+		wantErrIs   error
 	}{
 		// TODO: Add test cases.
 		{
@@ -162,7 +165,8 @@ func Test_getTmpVolume(t *testing.T) {
 					EmptyDir: &corev1.EmptyDirVolumeSource{},
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantErrIs: dmserrors.ErrMultipleContainers,
 		},
 		{
 			name: "Returns error if container not found",
@@ -186,7 +190,8 @@ func Test_getTmpVolume(t *testing.T) {
 					EmptyDir: &corev1.EmptyDirVolumeSource{},
 				},
 			},
-			wantErr: true,
+			wantErr:   true,
+			wantErrIs: dmserrors.ErrContainerNotFound,
 		},
 	}
 	for _, tt := range tests {
@@ -196,6 +201,9 @@ func Test_getTmpVolume(t *testing.T) {
 				t.Error("Expected error but non was returned")
 				return
 			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("getTmpVolume() error = %v, want %v", err, tt.wantErrIs)
+			}
 			if !tt.wantErr {
 				if existing != tt.expExisting {
 					t.Errorf("getTmpVolume() expected = %v\n got %v", tt.expExisting, existing)
@@ -1,104 +1,142 @@
 package utils
 
 import (
-	"path/filepath"
 	"strings"
 
 	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
 	"github.com/spf13/cobra"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // AutoCompleteDaemonSets implements autocompletion for the daemonset commands
-func AutoCompleteDaemonSets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	kubeconfig, namespace, err := getFlags(cmd)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
-	}
-	h, namespace, err := getKubernetesHelper(kubeconfig, namespace)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
-	}
-	daemonsets, err := h.ListDaemonsetsInNamespace(cmd.Context(), namespace)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
+func AutoCompleteDaemonSets(f dmskube.Factory) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		h, namespace, err := getKubernetesHelper(f)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		daemonsets, err := h.ListDaemonsetsInNamespace(cmd.Context(), namespace)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names := getFilteredDaemonSetNames(daemonsets.Items, toComplete)
+		return names, cobra.ShellCompDirectiveDefault
 	}
-	names := getFilteredDaemonSetNames(daemonsets.Items, toComplete)
-	return names, cobra.ShellCompDirectiveDefault
 }
 
 // AutoCompleteDeployments implements autocompletion for the deployment commands
-func AutoCompleteDeployments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	kubeconfig, namespace, err := getFlags(cmd)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
-	}
-	h, namespace, err := getKubernetesHelper(kubeconfig, namespace)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
-	}
-	deployments, err := h.ListDeploymentsInNamespace(cmd.Context(), namespace)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
+func AutoCompleteDeployments(f dmskube.Factory) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		h, namespace, err := getKubernetesHelper(f)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		deployments, err := h.ListDeploymentsInNamespace(cmd.Context(), namespace)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names := getFilteredDeploymentNames(deployments.Items, toComplete)
+		return names, cobra.ShellCompDirectiveDefault
 	}
-	names := getFilteredDeploymentNames(deployments.Items, toComplete)
-	return names, cobra.ShellCompDirectiveDefault
 }
 
 // AutoCompletePodsWithDebugContainer implements autocompletion for the pod commands where debug contianer is present
-func AutoCompletePodsWithDebugContainer(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	kubeconfig, namespace, err := getFlags(cmd)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
-	}
-	h, namespace, err := getKubernetesHelper(kubeconfig, namespace)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
-	}
-	pods, err := h.ListPodsInNamespace(cmd.Context(), namespace)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
+func AutoCompletePodsWithDebugContainer(f dmskube.Factory) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		h, namespace, err := getKubernetesHelper(f)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		pods, err := h.ListPodsInNamespace(cmd.Context(), namespace)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names := getFilteredPodNamesWithDebugContainer(pods.Items, toComplete)
+		return names, cobra.ShellCompDirectiveDefault
 	}
-	names := getFilteredPodNamesWithDebugContainer(pods.Items, toComplete)
-	return names, cobra.ShellCompDirectiveDefault
 }
 
-// getKubernetesHelper returns a kubernetes helper
-func getKubernetesHelper(kubeconfig, namespace string) (h dmskube.Helper, ns string, err error) {
-	if home := homedir.HomeDir(); home != "" && kubeconfig == "" {
-		kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		return dmskube.Helper{}, "", err
+// AutoCompleteNamespaces implements autocompletion for the --namespace flag
+func AutoCompleteNamespaces(f dmskube.Factory) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		h, err := f.Helper()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		namespaces, err := h.ListNamespaces(cmd.Context())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return getFilteredNamespaceNames(namespaces.Items, toComplete), cobra.ShellCompDirectiveNoFileComp
 	}
-	if namespace == "" {
-		namespace, err = GetNamespaceFromCurrentContext()
+}
+
+// AutoCompleteContainers implements autocompletion for the --container flag of the commands taking the name of a
+// deployment or daemonset as the first argument. The debug container is not completed
+func AutoCompleteContainers(f dmskube.Factory, kind string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		h, namespace, err := getKubernetesHelper(f)
 		if err != nil {
-			namespace = "default"
+			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
+		var template corev1.PodTemplateSpec
+		switch kind {
+		case "daemonset":
+			d, err := h.Client.AppsV1().DaemonSets(namespace).Get(cmd.Context(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			template = d.Spec.Template
+		default:
+			d, err := h.Client.AppsV1().Deployments(namespace).Get(cmd.Context(), args[0], metav1.GetOptions{})
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			template = d.Spec.Template
+		}
+		return getFilteredContainerNames(template, toComplete), cobra.ShellCompDirectiveNoFileComp
 	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err.Error())
+}
+
+// AutoCompleteContexts implements autocompletion for the --context flag with the contexts of the kubeconfig
+func AutoCompleteContexts(f dmskube.Factory) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		contexts, err := f.Contexts()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for _, c := range contexts {
+			if strings.HasPrefix(c, toComplete) {
+				names = append(names, c)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
 	}
-	return dmskube.Helper{
-		Client: clientset,
-	}, namespace, nil
 }
 
-// getFlags returns kubeconfig and namespace flags
-func getFlags(cmd *cobra.Command) (namespace, kubeconfig string, err error) {
-	kubeconfig, err = cmd.Flags().GetString("kubeconfig")
+// AutoCompleteDebugImages implements autocompletion for the --debugimage flag with the known dotnet-monitor images
+func AutoCompleteDebugImages(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return getFilteredDebugImages(resources.MonitorImages(), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// getKubernetesHelper returns a kubernetes helper and the namespace to complete in, default if the current context has none
+func getKubernetesHelper(f dmskube.Factory) (h dmskube.Helper, namespace string, err error) {
+	h, err = f.Helper()
+	if err != nil {
+		return dmskube.Helper{}, "", err
+	}
+	namespace, _, err = f.Namespace()
 	if err != nil {
-		return
+		namespace = "default"
 	}
-	namespace, err = cmd.Flags().GetString("namespace")
-	return
+	return h, namespace, nil
 }
 
 func getFilteredDeploymentNames(deployments []appsv1.Deployment, filter string) (names []string) {
@@ -121,9 +159,44 @@ func getFilteredDaemonSetNames(daemonsets []appsv1.DaemonSet, filter string) (na
 
 func getFilteredPodNamesWithDebugContainer(pods []corev1.Pod, filter string) (names []string) {
 	for _, p := range pods {
-		if strings.HasPrefix(p.Name, filter) && p.Annotations["dev.local/dd-added"] == "true" {
+		if strings.HasPrefix(p.Name, filter) && resources.HasDebugSidecar(p.Annotations) {
 			names = append(names, p.Name)
 		}
 	}
 	return
 }
+
+func getFilteredNamespaceNames(namespaces []corev1.Namespace, filter string) (names []string) {
+	for _, n := range namespaces {
+		if strings.HasPrefix(n.Name, filter) {
+			names = append(names, n.Name)
+		}
+	}
+	return
+}
+
+// getFilteredContainerNames returns the containers of a pod template, except the debug container if the sidecar is added
+func getFilteredContainerNames(template corev1.PodTemplateSpec, filter string) (names []string) {
+	debugContainer := ""
+	if resources.HasDebugSidecar(template.Annotations) {
+		config, err := resources.DDConfigFromPodTemplate(template)
+		if err == nil {
+			debugContainer = config.DebugContainerName
+		}
+	}
+	for _, c := range template.Spec.Containers {
+		if c.Name != debugContainer && strings.HasPrefix(c.Name, filter) {
+			names = append(names, c.Name)
+		}
+	}
+	return
+}
+
+func getFilteredDebugImages(images []string, filter string) (names []string) {
+	for _, image := range images {
+		if strings.HasPrefix(image, filter) {
+			names = append(names, image)
+		}
+	}
+	return
+}
@@ -1,12 +1,18 @@
 package utils
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
+	dmskube "github.com/altinn/dotnet-monitor-sidecar-cli/pkg/kubernetes"
+	"github.com/altinn/dotnet-monitor-sidecar-cli/pkg/resources"
+	"github.com/spf13/cobra"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 )
 
 func Test_getFilteredDeploymentNames(t *testing.T) {
@@ -279,3 +285,188 @@ func Test_getFilteredPodNamesWithDebugContainer(t *testing.T) {
 		})
 	}
 }
+
+func Test_getFilteredNamespaceNames(t *testing.T) {
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: v1.ObjectMeta{Name: "default"}},
+		{ObjectMeta: v1.ObjectMeta{Name: "dev"}},
+		{ObjectMeta: v1.ObjectMeta{Name: "kube-system"}},
+	}
+	tests := []struct {
+		filter    string
+		wantNames []string
+	}{
+		{filter: "", wantNames: []string{"default", "dev", "kube-system"}},
+		{filter: "de", wantNames: []string{"default", "dev"}},
+		{filter: "prod", wantNames: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			if gotNames := getFilteredNamespaceNames(namespaces, tt.filter); !reflect.DeepEqual(gotNames, tt.wantNames) {
+				t.Errorf("getFilteredNamespaceNames() = %v, want %v", gotNames, tt.wantNames)
+			}
+		})
+	}
+}
+
+func Test_getFilteredContainerNames(t *testing.T) {
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "proxy"}}},
+	}
+	withSidecar, err := resources.AddDebugContainerPodTemplate(template, "default", "app", "mcr.microsoft.com/dotnet/monitor:8", "dd-monitor-apikey-test", resources.SidecarOptions{})
+	if err != nil {
+		t.Fatalf("AddDebugContainerPodTemplate() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		template  corev1.PodTemplateSpec
+		filter    string
+		wantNames []string
+	}{
+		{
+			name:      "Filter is blank",
+			template:  template,
+			wantNames: []string{"app", "proxy"},
+		},
+		{
+			name:      "Filter matches one container",
+			template:  template,
+			filter:    "pr",
+			wantNames: []string{"proxy"},
+		},
+		{
+			name:      "Debug container is excluded",
+			template:  withSidecar,
+			wantNames: []string{"app", "proxy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotNames := getFilteredContainerNames(tt.template, tt.filter); !reflect.DeepEqual(gotNames, tt.wantNames) {
+				t.Errorf("getFilteredContainerNames() = %v, want %v", gotNames, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestAutoCompleteDebugImages(t *testing.T) {
+	tests := []struct {
+		toComplete string
+		wantNames  []string
+	}{
+		{toComplete: "mcr.microsoft.com/dotnet/monitor:7", wantNames: []string{"mcr.microsoft.com/dotnet/monitor:7", "mcr.microsoft.com/dotnet/monitor:7.3"}},
+		{toComplete: "mcr.microsoft.com/dotnet/monitor:8.", wantNames: []string{"mcr.microsoft.com/dotnet/monitor:8.1", "mcr.microsoft.com/dotnet/monitor:8.0"}},
+		{toComplete: "docker.io/", wantNames: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.toComplete, func(t *testing.T) {
+			gotNames, directive := AutoCompleteDebugImages(&cobra.Command{}, nil, tt.toComplete)
+			if !reflect.DeepEqual(gotNames, tt.wantNames) {
+				t.Errorf("AutoCompleteDebugImages() = %v, want %v", gotNames, tt.wantNames)
+			}
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("AutoCompleteDebugImages() directive = %v, want no file completion", directive)
+			}
+		})
+	}
+}
+
+// fakeFactory is a factory returning a helper with a fake clientset, in namespace test
+type fakeFactory struct {
+	client *testclient.Clientset
+}
+
+func (f *fakeFactory) Helper() (dmskube.Helper, error) {
+	return dmskube.Helper{Client: f.client}, nil
+}
+
+func (f *fakeFactory) ToRESTConfig() (*rest.Config, error) {
+	return &rest.Config{}, nil
+}
+
+func (f *fakeFactory) Namespace() (string, bool, error) {
+	return "test", false, nil
+}
+
+func (f *fakeFactory) User() (string, error) {
+	return "test-user", nil
+}
+
+func (f *fakeFactory) Contexts() ([]string, error) {
+	return []string{"dev", "prod", "prod-admin"}, nil
+}
+
+func TestAutoCompleteFlags(t *testing.T) {
+	f := &fakeFactory{client: testclient.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "test"}},
+		&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "other"}},
+		&appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "web", Namespace: "test"},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "proxy"}}},
+			}},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: v1.ObjectMeta{Name: "agent", Namespace: "test"},
+			Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "collector"}}},
+			}},
+		},
+	)}
+	tests := []struct {
+		name       string
+		complete   cobra.CompletionFunc
+		args       []string
+		toComplete string
+		wantNames  []string
+	}{
+		{
+			name:       "Namespaces",
+			complete:   AutoCompleteNamespaces(f),
+			toComplete: "o",
+			wantNames:  []string{"other"},
+		},
+		{
+			name:      "Containers of deployment",
+			complete:  AutoCompleteContainers(f, "deployment"),
+			args:      []string{"web"},
+			wantNames: []string{"app", "proxy"},
+		},
+		{
+			name:      "Containers of daemonset",
+			complete:  AutoCompleteContainers(f, "daemonset"),
+			args:      []string{"agent"},
+			wantNames: []string{"collector"},
+		},
+		{
+			name:      "Containers before the workload is supplied",
+			complete:  AutoCompleteContainers(f, "deployment"),
+			wantNames: nil,
+		},
+		{
+			name:      "Containers of missing workload",
+			complete:  AutoCompleteContainers(f, "deployment"),
+			args:      []string{"missing"},
+			wantNames: nil,
+		},
+		{
+			name:       "Contexts",
+			complete:   AutoCompleteContexts(f),
+			toComplete: "prod",
+			wantNames:  []string{"prod", "prod-admin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.SetContext(context.Background())
+			gotNames, directive := tt.complete(cmd, tt.args, tt.toComplete)
+			if !reflect.DeepEqual(gotNames, tt.wantNames) {
+				t.Errorf("completion = %v, want %v", gotNames, tt.wantNames)
+			}
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("completion directive = %v, want no file completion", directive)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrNotInteractive is returned when pickers are requested but stdin or stderr is not a terminal
+var ErrNotInteractive = errors.New("not running in a terminal")
+
+// maxPickerOptions is the number of options listed at a time, the rest are found by filtering
+const maxPickerOptions = 15
+
+// Picker lets the user pick one of a list of options in a terminal, by number or by filtering them with a fuzzy search.
+// The options and prompts are written to Out, which is stderr for the terminal, to keep stdout for the command output
+type Picker struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPicker returns a picker reading the answers from in and writing the options to out
+func NewPicker(in io.Reader, out io.Writer) *Picker {
+	return &Picker{in: bufio.NewReader(in), out: out}
+}
+
+// NewTerminalPicker returns a picker on stdin and stderr, or ErrNotInteractive if either is not a terminal
+func NewTerminalPicker() (*Picker, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil, ErrNotInteractive
+	}
+	return NewPicker(os.Stdin, os.Stderr), nil
+}
+
+// Pick returns the option picked. An option is picked without asking if it is the only one.
+// Typing text lists the options fuzzy matching it, and is picked if only one option matches
+func (p *Picker) Pick(prompt string, options []string) (string, error) {
+	switch len(options) {
+	case 0:
+		return "", fmt.Errorf("no %s to pick from", prompt)
+	case 1:
+		fmt.Fprintf(p.out, "%s: %s\n", prompt, options[0])
+		return options[0], nil
+	}
+	matches := options
+	for {
+		p.list(matches, len(options))
+		fmt.Fprintf(p.out, "%s (number or search): ", prompt)
+		answer, err := p.in.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if err != nil && (err != io.EOF || answer == "") {
+			return "", fmt.Errorf("no %s picked: %w", prompt, err)
+		}
+		if answer == "" {
+			matches = options
+			continue
+		}
+		if i, err := strconv.Atoi(answer); err == nil {
+			if i < 1 || i > len(matches) || i > maxPickerOptions {
+				fmt.Fprintf(p.out, "%d is not one of the numbers listed\n", i)
+				continue
+			}
+			return matches[i-1], nil
+		}
+		for _, o := range options {
+			if o == answer {
+				return o, nil
+			}
+		}
+		matches = FuzzyFilter(options, answer)
+		switch len(matches) {
+		case 0:
+			fmt.Fprintf(p.out, "No %s matches %q\n", prompt, answer)
+			matches = options
+		case 1:
+			fmt.Fprintf(p.out, "%s: %s\n", prompt, matches[0])
+			return matches[0], nil
+		}
+	}
+}
+
+func (p *Picker) list(options []string, total int) {
+	for i, o := range options {
+		if i == maxPickerOptions {
+			fmt.Fprintf(p.out, "  ... %d more, type to search\n", len(options)-maxPickerOptions)
+			break
+		}
+		fmt.Fprintf(p.out, "%3d) %s\n", i+1, o)
+	}
+	if len(options) < total {
+		fmt.Fprintf(p.out, "  (%d of %d, press enter to list all)\n", len(options), total)
+	}
+}
+
+// FuzzyFilter returns the options containing the characters of search in order, ignoring case.
+// Options containing search as is are returned first, and the order of the options is kept otherwise
+func FuzzyFilter(options []string, search string) []string {
+	search = strings.ToLower(search)
+	var matches []string
+	contains := map[string]bool{}
+	for _, o := range options {
+		lower := strings.ToLower(o)
+		if !isSubsequence(search, lower) {
+			continue
+		}
+		matches = append(matches, o)
+		contains[o] = strings.Contains(lower, search)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return contains[matches[i]] && !contains[matches[j]]
+	})
+	return matches
+}
+
+// isSubsequence returns true if the characters of s are in t in the same order
+func isSubsequence(s, t string) bool {
+	rs := []rune(s)
+	if len(rs) == 0 {
+		return true
+	}
+	i := 0
+	for _, r := range t {
+		if r == rs[i] {
+			i++
+			if i == len(rs) {
+				return true
+			}
+		}
+	}
+	return false
+}
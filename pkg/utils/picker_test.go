@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPicker_Pick(t *testing.T) {
+	options := []string{"frontend", "backend", "backend-worker"}
+	tests := []struct {
+		name    string
+		options []string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Picks by number",
+			input: "2\n",
+			want:  "backend",
+		},
+		{
+			name:  "Picks the only fuzzy match",
+			input: "frnt\n",
+			want:  "frontend",
+		},
+		{
+			name:  "Picks exact match of several",
+			input: "backend\n",
+			want:  "backend",
+		},
+		{
+			name:  "Picks by number of the matches",
+			input: "bck\n2\n",
+			want:  "backend-worker",
+		},
+		{
+			name:  "Asks again on numbers not listed and no matches",
+			input: "4\nxyz\n1\n",
+			want:  "frontend",
+		},
+		{
+			name:  "Picks without newline at end of input",
+			input: "3",
+			want:  "backend-worker",
+		},
+		{
+			name:    "Fails at end of input",
+			input:   "bck\n",
+			wantErr: true,
+		},
+		{
+			name:    "Picks the only option without asking",
+			options: []string{"app"},
+			want:    "app",
+		},
+		{
+			name:    "Fails without options",
+			options: []string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.options == nil {
+				tt.options = options
+			}
+			got, err := NewPicker(strings.NewReader(tt.input), io.Discard).Pick("deployment", tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Picker.Pick() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Picker.Pick() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	options := []string{"api-gateway", "payments-api", "Apps", "web"}
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{search: "", want: options},
+		{search: "api", want: []string{"api-gateway", "payments-api"}},
+		{search: "ap", want: []string{"api-gateway", "payments-api", "Apps"}},
+		{search: "pay", want: []string{"payments-api", "api-gateway"}},
+		{search: "gate", want: []string{"api-gateway"}},
+		{search: "aw", want: []string{"api-gateway"}},
+		{search: "xyz", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.search, func(t *testing.T) {
+			if got := FuzzyFilter(options, tt.search); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FuzzyFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}